@@ -20,10 +20,14 @@ import (
 
 // Reconciler reconciles a ToolchainCluster object
 type Reconciler struct {
-	Client      client.Client
-	Scheme      *runtime.Scheme
-	RequeAfter  time.Duration
-	checkHealth func(context.Context, *kubeclientset.Clientset) (bool, error)
+	Client     client.Client
+	Scheme     *runtime.Scheme
+	RequeAfter time.Duration
+	// AvailabilityWindow the window of the probe history used to compute the availability of the cluster connection
+	// (see status.ToolchainClusterAttributes), which determines the size of the probe history along with RequeAfter.
+	// The history keeps cluster.DefaultProbeHistorySize results if not set.
+	AvailabilityWindow time.Duration
+	checkHealth        func(context.Context, *kubeclientset.Clientset) (bool, error)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -73,7 +77,14 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 	}
 
 	// execute healthcheck
+	probeStart := time.Now()
 	healthCheckResult := r.getClusterHealthCondition(ctx, clientSet)
+	cluster.RecordProbeResult(toolchainCluster.Name, cluster.ProbeHistorySize(r.AvailabilityWindow, r.RequeAfter), cluster.ProbeResult{
+		Timestamp: probeStart,
+		Latency:   time.Since(probeStart),
+		Healthy:   healthCheckResult.Status == corev1.ConditionTrue,
+		Message:   healthCheckResult.Message,
+	})
 
 	// update the status of the individual cluster.
 	if err := r.updateStatus(ctx, toolchainCluster, cachedCluster, healthCheckResult); err != nil {
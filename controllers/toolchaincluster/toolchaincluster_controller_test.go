@@ -9,6 +9,7 @@ import (
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/h2non/gock.v1"
 	corev1 "k8s.io/api/core/v1"
@@ -97,6 +98,28 @@ func TestClusterControllerChecks(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, reconcile.Result{RequeueAfter: requeAfter}, recResult)
 		assertClusterStatus(t, cl, "stable", clusterReadyCondition())
+		assertProbeHistory(t, "stable", true)
+	})
+
+	t.Run("probe history sized for the availability window", func(t *testing.T) {
+		// given
+		stable, sec := newToolchainCluster(t, "stable", tcNs, "https://cluster.com")
+
+		cl := test.NewFakeClient(t, stable, sec)
+		reset := setupCachedClusters(t, cl, stable)
+
+		defer reset()
+		controller, req := prepareReconcile(stable, cl, 10*time.Second)
+		controller.AvailabilityWindow = time.Hour
+
+		// when
+		_, err := controller.Reconcile(context.TODO(), req)
+
+		// then
+		require.NoError(t, err)
+		history, found := cluster.GetProbeHistory("stable")
+		require.True(t, found)
+		assert.Equal(t, 361, history.Size())
 	})
 
 	t.Run("toolchain cluster cache not found", func(t *testing.T) {
@@ -201,9 +224,21 @@ func TestGetClusterHealth(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, reconcile.Result{RequeueAfter: requeAfter}, recResult)
 		assertClusterStatus(t, cl, "stable", clusterNotReadyCondition())
+		assertProbeHistory(t, "stable", false)
 	})
 }
 
+func assertProbeHistory(t *testing.T, clusterName string, healthy ...bool) {
+	history, found := cluster.GetProbeHistory(clusterName)
+	require.True(t, found)
+	results := history.Results()
+	require.Len(t, results, len(healthy))
+	for i, h := range healthy {
+		assert.Equal(t, h, results[i].Healthy)
+		assert.WithinDuration(t, time.Now(), results[i].Timestamp, time.Minute)
+	}
+}
+
 func setupCachedClusters(t *testing.T, cl *test.FakeClient, clusters ...*toolchainv1alpha1.ToolchainCluster) func() {
 	service := cluster.NewToolchainClusterServiceWithClient(cl, logf.Log, test.MemberOperatorNs, 0, func(config *rest.Config, options runtimeclient.Options) (runtimeclient.Client, error) {
 		// make sure that insecure is false to make Gock mocking working properly
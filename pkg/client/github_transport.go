@@ -0,0 +1,177 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	headerETag               = "ETag"
+	headerIfNoneMatch        = "If-None-Match"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// GitHubRateLimitError returned when the rate limit of the GitHub API is exhausted and no cached response is available
+type GitHubRateLimitError struct {
+	Reset time.Time
+}
+
+func (e *GitHubRateLimitError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit exhausted until %s", e.Reset.Format(time.RFC3339))
+}
+
+// GitHubCachingTransport an http.RoundTripper for the GitHub API which:
+//   - caches the responses of the GET requests along with their ETag, and issues conditional requests (If-None-Match).
+//     The responses with the `304 Not Modified` status do not count against the rate limit, and are replaced by the cached responses.
+//   - honors the `X-RateLimit-Remaining` and `X-RateLimit-Reset` headers: when the rate limit is exhausted, the cached
+//     responses are returned without calling the API until the reset, or a GitHubRateLimitError if there is none.
+//
+// The responses are cached per URL, ie, per repository and branch for the commits API. It is safe for concurrent use.
+type GitHubCachingTransport struct {
+	base http.RoundTripper
+	now  func() time.Time
+
+	mutex              sync.Mutex
+	responses          map[string]cachedResponse
+	rateLimitRemaining int
+	rateLimitReset     time.Time
+}
+
+type cachedResponse struct {
+	etag       string
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// NewGitHubCachingTransport returns a new GitHubCachingTransport on top of the given transport (http.DefaultTransport if nil)
+func NewGitHubCachingTransport(base http.RoundTripper) *GitHubCachingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &GitHubCachingTransport{
+		base:               base,
+		now:                time.Now,
+		responses:          map[string]cachedResponse{},
+		rateLimitRemaining: -1,
+	}
+}
+
+func (t *GitHubCachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	key := req.URL.String()
+	t.mutex.Lock()
+	cached, isCached := t.responses[key]
+	exhausted := t.rateLimitRemaining == 0 && t.now().Before(t.rateLimitReset)
+	reset := t.rateLimitReset
+	t.mutex.Unlock()
+
+	if exhausted {
+		if isCached {
+			return cached.toResponse(req), nil
+		}
+		return nil, &GitHubRateLimitError{Reset: reset}
+	}
+
+	if isCached {
+		req = req.Clone(req.Context())
+		req.Header.Set(headerIfNoneMatch, cached.etag)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.recordRateLimit(resp)
+
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return cached.toResponse(req), nil
+	}
+	etag := resp.Header.Get(headerETag)
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	t.mutex.Lock()
+	t.responses[key] = cachedResponse{
+		etag:       etag,
+		statusCode: resp.StatusCode,
+		header:     resp.Header.Clone(),
+		body:       body,
+	}
+	t.mutex.Unlock()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (t *GitHubCachingTransport) recordRateLimit(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64)
+	if err != nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.rateLimitRemaining = remaining
+	t.rateLimitReset = time.Unix(reset, 0)
+}
+
+func (c cachedResponse) toResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.statusCode, http.StatusText(c.statusCode)),
+		StatusCode:    c.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		Request:       req,
+	}
+}
+
+// NewCachingGitHubClientFunc returns a GetGitHubClientFunc which returns the same client for a given access token,
+// whose requests go through a GitHubCachingTransport. Contrary to NewGitHubClient, the cached responses and the
+// rate limit are thus shared by all the checks using the same token. It is safe for concurrent use.
+// Since the clients outlive the calls, their transports do not depend on the context of the call which created them:
+// the context of each request is given to the methods of the client instead.
+func NewCachingGitHubClientFunc() GetGitHubClientFunc {
+	return newCachingGitHubClientFunc(func(accessToken string) http.RoundTripper {
+		return oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})).Transport
+	})
+}
+
+func newCachingGitHubClientFunc(newTransport func(accessToken string) http.RoundTripper) GetGitHubClientFunc {
+	var mutex sync.Mutex
+	clients := map[string]*github.Client{}
+	return func(_ context.Context, accessToken string) *github.Client {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if cl, found := clients[accessToken]; found {
+			return cl
+		}
+		cl := github.NewClient(&http.Client{Transport: NewGitHubCachingTransport(newTransport(accessToken))})
+		clients[accessToken] = cl
+		return cl
+	}
+}
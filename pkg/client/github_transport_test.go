@@ -0,0 +1,246 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub a GitHub commits API which supports the conditional requests and returns the rate limit headers
+type fakeGitHub struct {
+	calls       int32
+	notModified int32
+	remaining   int32
+	reset       time.Time
+	sha         string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&f.calls, 1)
+	etag := fmt.Sprintf(`"%s%s"`, r.URL.Path, f.sha)
+	w.Header().Set(headerRateLimitReset, strconv.FormatInt(f.reset.Unix(), 10))
+	if r.Header.Get(headerIfNoneMatch) == etag {
+		atomic.AddInt32(&f.notModified, 1)
+		w.Header().Set(headerRateLimitRemaining, strconv.Itoa(int(atomic.LoadInt32(&f.remaining))))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set(headerRateLimitRemaining, strconv.Itoa(int(atomic.AddInt32(&f.remaining, -1))))
+	w.Header().Set(headerETag, etag)
+	_, _ = fmt.Fprintf(w, `{"sha": "%s"}`, f.sha)
+}
+
+func TestGitHubCachingTransport(t *testing.T) {
+	get := func(t *testing.T, cl *http.Client, url string) (int, string) {
+		resp, err := cl.Get(url) // nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("conditional requests", func(t *testing.T) {
+		// given
+		gh := &fakeGitHub{remaining: 10, reset: time.Now().Add(time.Hour), sha: "1234abcd"}
+		server := httptest.NewServer(gh)
+		defer server.Close()
+		cl := &http.Client{Transport: NewGitHubCachingTransport(server.Client().Transport)}
+
+		// when
+		status1, body1 := get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+		status2, body2 := get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+
+		// then
+		assert.Equal(t, http.StatusOK, status1)
+		assert.Equal(t, http.StatusOK, status2)
+		assert.Equal(t, `{"sha": "1234abcd"}`, body1)
+		assert.Equal(t, body1, body2)
+		assert.Equal(t, int32(2), gh.calls)
+		assert.Equal(t, int32(1), gh.notModified)
+		assert.Equal(t, int32(9), gh.remaining)
+
+		t.Run("cached per repo and branch", func(t *testing.T) {
+			// when
+			status, body := get(t, cl, server.URL+"/repos/org/host-operator/commits/other")
+
+			// then
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, `{"sha": "1234abcd"}`, body)
+			assert.Equal(t, int32(1), gh.notModified)
+			assert.Equal(t, int32(8), gh.remaining)
+		})
+
+		t.Run("new content", func(t *testing.T) {
+			// given
+			gh.sha = "5678efgh"
+
+			// when
+			_, body := get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+
+			// then
+			assert.Equal(t, `{"sha": "5678efgh"}`, body)
+		})
+	})
+
+	t.Run("rate limit exhausted", func(t *testing.T) {
+		// given
+		gh := &fakeGitHub{remaining: 1, reset: time.Now().Add(time.Hour), sha: "1234abcd"}
+		server := httptest.NewServer(gh)
+		defer server.Close()
+		cl := &http.Client{Transport: NewGitHubCachingTransport(server.Client().Transport)}
+		_, _ = get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+		require.Equal(t, int32(0), gh.remaining)
+
+		t.Run("cached response is returned without calling the API", func(t *testing.T) {
+			// when
+			status, body := get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+
+			// then
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, `{"sha": "1234abcd"}`, body)
+			assert.Equal(t, int32(1), gh.calls)
+		})
+
+		t.Run("error without cached response", func(t *testing.T) {
+			// when
+			_, err := cl.Get(server.URL + "/repos/org/member-operator/commits/master") // nolint:noctx
+
+			// then
+			rateLimitErr := &GitHubRateLimitError{}
+			require.ErrorAs(t, err, &rateLimitErr)
+			assert.Equal(t, gh.reset.Unix(), rateLimitErr.Reset.Unix())
+			assert.Equal(t, int32(1), gh.calls)
+		})
+
+		t.Run("API is called again after the reset", func(t *testing.T) {
+			// given
+			transport := cl.Transport.(*GitHubCachingTransport)
+			transport.now = func() time.Time {
+				return time.Now().Add(2 * time.Hour)
+			}
+
+			// when
+			status, _ := get(t, cl, server.URL+"/repos/org/member-operator/commits/master")
+
+			// then
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, int32(2), gh.calls)
+		})
+	})
+
+	t.Run("responses without ETag are not cached", func(t *testing.T) {
+		// given
+		calls := int32(0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			assert.Empty(t, r.Header.Get(headerIfNoneMatch))
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+		cl := &http.Client{Transport: NewGitHubCachingTransport(server.Client().Transport)}
+
+		// when
+		_, _ = get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+		_, _ = get(t, cl, server.URL+"/repos/org/host-operator/commits/master")
+
+		// then
+		assert.Equal(t, int32(2), calls)
+	})
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		// given
+		gh := &fakeGitHub{remaining: 1000, reset: time.Now().Add(time.Hour), sha: "1234abcd"}
+		server := httptest.NewServer(gh)
+		defer server.Close()
+		cl := &http.Client{Transport: NewGitHubCachingTransport(server.Client().Transport)}
+		var wg sync.WaitGroup
+
+		// when
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				resp, err := cl.Get(fmt.Sprintf("%s/repos/org/repo-%d/commits/master", server.URL, i%4)) // nolint:noctx
+				if assert.NoError(t, err) {
+					_ = resp.Body.Close()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		// then
+		assert.Equal(t, int32(20), gh.calls)
+	})
+}
+
+func TestNewCachingGitHubClientFunc(t *testing.T) {
+	// given
+	gh := &fakeGitHub{remaining: 10, reset: time.Now().Add(time.Hour), sha: "1234abcd"}
+	server := httptest.NewServer(gh)
+	defer server.Close()
+	newClient := newCachingGitHubClientFunc(func(string) http.RoundTripper {
+		return server.Client().Transport
+	})
+
+	// when
+	cl1 := newClient(context.TODO(), "token")
+	cl2 := newClient(context.TODO(), "token")
+	other := newClient(context.TODO(), "other-token")
+
+	// then
+	assert.Same(t, cl1, cl2)
+	assert.NotSame(t, cl1, other)
+
+	t.Run("conditional requests with go-github", func(t *testing.T) {
+		// given
+		for _, cl := range []*github.Client{cl1, cl2} {
+			var err error
+			cl.BaseURL, err = cl.BaseURL.Parse(server.URL + "/")
+			require.NoError(t, err)
+		}
+
+		// when
+		commit1, _, err1 := cl1.Repositories.GetCommit(context.TODO(), "org", "host-operator", "master", nil)
+		commit2, _, err2 := cl2.Repositories.GetCommit(context.TODO(), "org", "host-operator", "master", nil)
+
+		// then
+		require.NoError(t, errors.Join(err1, err2))
+		assert.Equal(t, "1234abcd", commit1.GetSHA())
+		assert.Equal(t, "1234abcd", commit2.GetSHA())
+		assert.Equal(t, int32(1), gh.notModified)
+	})
+}
+
+func TestNewCachingGitHubClientFuncIgnoresTheContextOfTheFirstCall(t *testing.T) {
+	// given
+	gh := &fakeGitHub{remaining: 10, reset: time.Now().Add(time.Hour), sha: "1234abcd"}
+	server := httptest.NewServer(gh)
+	defer server.Close()
+	newClient := NewCachingGitHubClientFunc()
+	ctx, cancel := context.WithCancel(context.Background())
+	cl := newClient(ctx, "token")
+	cancel() // the first call is over, eg, the reconcile loop which created the client returned
+	var err error
+	cl.BaseURL, err = cl.BaseURL.Parse(server.URL + "/")
+	require.NoError(t, err)
+
+	// when
+	commit, _, err := newClient(context.TODO(), "token").Repositories.GetCommit(context.TODO(), "org", "host-operator", "master", nil)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "1234abcd", commit.GetSHA())
+}
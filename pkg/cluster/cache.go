@@ -14,6 +14,9 @@ type toolchainClusterClients struct {
 	sync.RWMutex
 	clusters     map[string]*CachedToolchainCluster
 	refreshCache func()
+	// probeHistories the probe histories per cluster name, kept apart from the clusters so that they are not lost
+	// when the clusters are updated
+	probeHistories map[string]*ProbeHistory
 }
 
 type Config struct {
@@ -56,6 +59,37 @@ func (c *toolchainClusterClients) deleteCachedToolchainCluster(name string) {
 	c.Lock()
 	defer c.Unlock()
 	delete(c.clusters, name)
+	delete(c.probeHistories, name)
+}
+
+// getOrCreateProbeHistory returns the probe history of the cluster with the given name, which is created or resized
+// if it does not have the given size (DefaultProbeHistorySize if not positive)
+func (c *toolchainClusterClients) getOrCreateProbeHistory(name string, size int) *ProbeHistory {
+	if size <= 0 {
+		size = DefaultProbeHistorySize
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.probeHistories == nil {
+		c.probeHistories = map[string]*ProbeHistory{}
+	}
+	history, found := c.probeHistories[name]
+	switch {
+	case !found:
+		history = NewProbeHistory(size)
+		c.probeHistories[name] = history
+	case history.Size() != size:
+		history = history.resized(size)
+		c.probeHistories[name] = history
+	}
+	return history
+}
+
+func (c *toolchainClusterClients) getProbeHistory(name string) (*ProbeHistory, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	history, found := c.probeHistories[name]
+	return history, found
 }
 
 func (c *toolchainClusterClients) getCachedToolchainCluster(name string, canRefreshCache bool) (*CachedToolchainCluster, bool) {
@@ -103,6 +137,24 @@ func GetCachedToolchainCluster(name string) (*CachedToolchainCluster, bool) {
 	return clusterCache.getCachedToolchainCluster(name, true)
 }
 
+// RecordProbeResult records the given result of a health check probe in the history of the cluster with the given name,
+// which keeps the given number of results (DefaultProbeHistorySize if not positive, see ProbeHistorySize)
+func RecordProbeResult(name string, historySize int, result ProbeResult) {
+	clusterCache.getOrCreateProbeHistory(name, historySize).Add(result)
+}
+
+// GetProbeHistory returns the history of the health check probes of the cluster with the given name, and false if no
+// probe result was recorded for this cluster
+func GetProbeHistory(name string) (*ProbeHistory, bool) {
+	return clusterCache.getProbeHistory(name)
+}
+
+// ProbeHistory returns the history of the health check probes of this cluster, or nil if no probe result was recorded
+func (c *CachedToolchainCluster) ProbeHistory() *ProbeHistory {
+	history, _ := GetProbeHistory(c.Name)
+	return history
+}
+
 // GetHostClusterFunc a func that returns the Host cluster from the cache,
 // along with a bool to indicate if there was a match or not
 type GetHostClusterFunc func() (*CachedToolchainCluster, bool)
@@ -3,6 +3,7 @@ package cluster
 import (
 	"sync"
 	"testing"
+	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
@@ -270,6 +271,57 @@ func TestDeleteCluster(t *testing.T) {
 	assert.Equal(t, cachedCluster, clusterCache.clusters["testCluster"])
 }
 
+func TestClusterProbeHistory(t *testing.T) {
+	// given
+	defer resetClusterCache()
+	cachedCluster := newTestCachedToolchainCluster(t, "testCluster", ready)
+	clusterCache.addCachedToolchainCluster(cachedCluster)
+	assert.Nil(t, cachedCluster.ProbeHistory())
+
+	// when
+	RecordProbeResult("testCluster", 0, ProbeResult{Timestamp: time.Now(), Healthy: true})
+	RecordProbeResult("testCluster", 0, ProbeResult{Timestamp: time.Now(), Message: "connection refused"})
+
+	// then
+	history, found := GetProbeHistory("testCluster")
+	require.True(t, found)
+	require.Len(t, history.Results(), 2)
+	assert.Equal(t, "connection refused", history.Results()[1].Message)
+	_, found = GetProbeHistory("cluster")
+	assert.False(t, found)
+
+	t.Run("history is kept when the cluster is updated", func(t *testing.T) {
+		// when
+		updatedCluster := newTestCachedToolchainCluster(t, "testCluster", notReady)
+		clusterCache.addCachedToolchainCluster(updatedCluster)
+
+		// then
+		assert.Same(t, history, updatedCluster.ProbeHistory())
+	})
+
+	t.Run("history is resized with the most recent results", func(t *testing.T) {
+		// when
+		RecordProbeResult("testCluster", 2, ProbeResult{Timestamp: time.Now(), Healthy: true})
+
+		// then
+		resized, found := GetProbeHistory("testCluster")
+		require.True(t, found)
+		assert.Equal(t, 2, resized.Size())
+		require.Len(t, resized.Results(), 2)
+		assert.Equal(t, "connection refused", resized.Results()[0].Message)
+		assert.True(t, resized.Results()[1].Healthy)
+	})
+
+	t.Run("history is deleted along with the cluster", func(t *testing.T) {
+		// when
+		clusterCache.deleteCachedToolchainCluster("testCluster")
+
+		// then
+		_, found := GetProbeHistory("testCluster")
+		assert.False(t, found)
+	})
+}
+
 func TestRefreshCache(t *testing.T) {
 	// given
 	testCluster := newTestCachedToolchainCluster(t, "testCluster", ready)
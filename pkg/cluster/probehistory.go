@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultProbeHistorySize the default number of probe results kept per cluster, ie, 20 minutes with the default 10s
+// health check period. See ProbeHistorySize to keep the probe results of a longer window.
+const DefaultProbeHistorySize = 120
+
+// ProbeHistorySize returns the number of probe results to keep in order to cover the given window with the given
+// health check period, or DefaultProbeHistorySize if the window or the period is not set
+func ProbeHistorySize(window, period time.Duration) int {
+	if window <= 0 || period <= 0 {
+		return DefaultProbeHistorySize
+	}
+	// one more result, since the first probe of the window may be older than the window by less than a period
+	return int((window+period-1)/period) + 1
+}
+
+// ProbeResult the result of a health check probe of a cluster
+type ProbeResult struct {
+	Timestamp time.Time
+	Latency   time.Duration
+	Healthy   bool
+	Message   string
+}
+
+// ProbeHistory a bounded history of the probe results of a cluster: when it is full, the oldest result is replaced
+// by the new one. It is safe for concurrent use.
+type ProbeHistory struct {
+	mutex   sync.RWMutex
+	results []ProbeResult
+	next    int
+	full    bool
+}
+
+// NewProbeHistory returns a new ProbeHistory which keeps the given number of results (DefaultProbeHistorySize if not positive)
+func NewProbeHistory(size int) *ProbeHistory {
+	if size <= 0 {
+		size = DefaultProbeHistorySize
+	}
+	return &ProbeHistory{results: make([]ProbeResult, size)}
+}
+
+// Size returns the maximum number of results kept in the history
+func (h *ProbeHistory) Size() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.results)
+}
+
+// resized returns a new history of the given size, with the most recent results of this history
+func (h *ProbeHistory) resized(size int) *ProbeHistory {
+	resized := NewProbeHistory(size)
+	results := h.Results()
+	if len(results) > len(resized.results) {
+		results = results[len(results)-len(resized.results):]
+	}
+	for _, r := range results {
+		resized.Add(r)
+	}
+	return resized
+}
+
+// Add adds the given result to the history
+func (h *ProbeHistory) Add(result ProbeResult) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.results[h.next] = result
+	h.next = (h.next + 1) % len(h.results)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Results returns the results of the history, from the oldest to the most recent
+func (h *ProbeHistory) Results() []ProbeResult {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if !h.full {
+		return append([]ProbeResult{}, h.results[:h.next]...)
+	}
+	return append(append([]ProbeResult{}, h.results[h.next:]...), h.results[:h.next]...)
+}
+
+// Since returns the results of the history which happened at or after the given time, from the oldest to the most recent
+func (h *ProbeHistory) Since(since time.Time) []ProbeResult {
+	var results []ProbeResult
+	for _, r := range h.Results() {
+		if !r.Timestamp.Before(since) {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// Covers returns true if the history contains all the results since the given time, ie, if the history is not full
+// yet or if its oldest result is not after the given time. Otherwise, the older results were replaced by newer ones.
+func (h *ProbeHistory) Covers(since time.Time) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return !h.full || !h.results[h.next].Timestamp.After(since)
+}
+
+// Availability returns the ratio (between 0 and 1) of healthy probes since the given time, along with the number of probes.
+// The ratio is 0 if there was no probe.
+func (h *ProbeHistory) Availability(since time.Time) (float64, int) {
+	results := h.Since(since)
+	if len(results) == 0 {
+		return 0, 0
+	}
+	healthy := 0
+	for _, r := range results {
+		if r.Healthy {
+			healthy++
+		}
+	}
+	return float64(healthy) / float64(len(results)), len(results)
+}
@@ -0,0 +1,143 @@
+package cluster_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeHistorySize(t *testing.T) {
+	assert.Equal(t, 361, cluster.ProbeHistorySize(time.Hour, 10*time.Second))
+	assert.Equal(t, 5, cluster.ProbeHistorySize(35*time.Second, 10*time.Second))
+	assert.Equal(t, cluster.DefaultProbeHistorySize, cluster.ProbeHistorySize(0, 10*time.Second))
+	assert.Equal(t, cluster.DefaultProbeHistorySize, cluster.ProbeHistorySize(time.Hour, 0))
+}
+
+func TestProbeHistory(t *testing.T) {
+	now := time.Now()
+
+	t.Run("empty history", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(3)
+
+		// when
+		availability, probes := history.Availability(now.Add(-time.Hour))
+
+		// then
+		assert.Empty(t, history.Results())
+		assert.Zero(t, availability)
+		assert.Zero(t, probes)
+	})
+
+	t.Run("results from the oldest to the most recent", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(3)
+
+		// when
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-2 * time.Minute), Healthy: true})
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-time.Minute), Message: "timeout"})
+
+		// then
+		results := history.Results()
+		require.Len(t, results, 2)
+		assert.True(t, results[0].Healthy)
+		assert.Equal(t, "timeout", results[1].Message)
+	})
+
+	t.Run("oldest results are replaced when the history is full", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(3)
+
+		// when
+		for i := 5; i > 0; i-- {
+			history.Add(cluster.ProbeResult{Timestamp: now.Add(-time.Duration(i) * time.Minute), Latency: time.Duration(i)})
+		}
+
+		// then
+		results := history.Results()
+		require.Len(t, results, 3)
+		assert.Equal(t, time.Duration(3), results[0].Latency)
+		assert.Equal(t, time.Duration(2), results[1].Latency)
+		assert.Equal(t, time.Duration(1), results[2].Latency)
+	})
+
+	t.Run("default size", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(0)
+
+		// when
+		for i := 0; i < cluster.DefaultProbeHistorySize+10; i++ {
+			history.Add(cluster.ProbeResult{Timestamp: now})
+		}
+
+		// then
+		assert.Len(t, history.Results(), cluster.DefaultProbeHistorySize)
+	})
+
+	t.Run("covers a window", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(3)
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-30 * time.Minute)})
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-20 * time.Minute)})
+
+		// then
+		assert.True(t, history.Covers(now.Add(-time.Hour))) // not full yet
+
+		// when
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-10 * time.Minute)})
+		history.Add(cluster.ProbeResult{Timestamp: now})
+
+		// then
+		assert.Equal(t, 3, history.Size())
+		assert.True(t, history.Covers(now.Add(-20*time.Minute)))
+		assert.False(t, history.Covers(now.Add(-25*time.Minute)))
+	})
+
+	t.Run("availability within a window", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(10)
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-2 * time.Hour)}) // out of the window
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-40 * time.Minute), Healthy: true})
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-30 * time.Minute), Healthy: true})
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-20 * time.Minute), Healthy: true})
+		history.Add(cluster.ProbeResult{Timestamp: now.Add(-10 * time.Minute)})
+
+		// when
+		availability, probes := history.Availability(now.Add(-time.Hour))
+
+		// then
+		assert.InDelta(t, 0.75, availability, 0.001)
+		assert.Equal(t, 4, probes)
+		assert.Len(t, history.Since(now.Add(-25*time.Minute)), 2)
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		// given
+		history := cluster.NewProbeHistory(10)
+		var wg sync.WaitGroup
+
+		// when
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				history.Add(cluster.ProbeResult{Timestamp: time.Now(), Healthy: true})
+			}()
+			go func() {
+				defer wg.Done()
+				_, _ = history.Availability(now.Add(-time.Minute))
+			}()
+		}
+		wg.Wait()
+
+		// then
+		availability, probes := history.Availability(now.Add(-time.Minute))
+		assert.Equal(t, 10, probes)
+		assert.InDelta(t, 1.0, availability, 0.001)
+	})
+}
@@ -0,0 +1,99 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConditionedObject a resource with status conditions
+type ConditionedObject interface {
+	client.Object
+	GetConditions() []toolchainv1alpha1.Condition
+	SetConditions(conditions []toolchainv1alpha1.Condition)
+}
+
+// StatusConditions returns a ConditionedObject for the given resource, whose conditions are in the
+// `Status.Conditions` field (as for all the toolchain resources, eg: UserSignup, Space, MasterUserRecord, etc.).
+// The returned object reads and writes the conditions of the given resource.
+func StatusConditions(obj client.Object) (ConditionedObject, error) {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("the resource of type %T is not a pointer to a struct", obj)
+	}
+	status := value.Elem().FieldByName("Status")
+	if !status.IsValid() || status.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("the resource of type %T has no status", obj)
+	}
+	conditions := status.FieldByName("Conditions")
+	if !conditions.IsValid() || conditions.Type() != reflect.TypeOf([]toolchainv1alpha1.Condition{}) {
+		return nil, fmt.Errorf("the status of the resource of type %T has no conditions", obj)
+	}
+	return &statusConditionsObject{Object: obj, conditions: conditions}, nil
+}
+
+// statusConditionsObject a resource whose conditions are in the `Status.Conditions` field
+type statusConditionsObject struct {
+	client.Object
+	conditions reflect.Value
+}
+
+func (o *statusConditionsObject) GetConditions() []toolchainv1alpha1.Condition {
+	return o.conditions.Interface().([]toolchainv1alpha1.Condition)
+}
+
+func (o *statusConditionsObject) SetConditions(conditions []toolchainv1alpha1.Condition) {
+	o.conditions.Set(reflect.ValueOf(conditions))
+}
+
+func (o *statusConditionsObject) unwrap() client.Object {
+	return o.Object
+}
+
+// unwrap returns the actual resource, ie, the resource given to StatusConditions
+func unwrap(obj ConditionedObject) client.Object {
+	if wrapper, ok := obj.(interface{ unwrap() client.Object }); ok {
+		return wrapper.unwrap()
+	}
+	return obj
+}
+
+// SetConditions adds or updates the given conditions of the given object (see AddOrUpdateStatusConditions) and patches
+// its status. The status is not patched if the conditions did not change. In case of conflict, the object is
+// retrieved again and the conditions are applied on its latest version.
+// Returns true if the status was patched.
+func SetConditions(ctx context.Context, cl client.Client, obj ConditionedObject, newConditions ...toolchainv1alpha1.Condition) (bool, error) {
+	resource := unwrap(obj)
+	updated := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		conditions, changed := AddOrUpdateStatusConditions(obj.GetConditions(), newConditions...)
+		if !changed {
+			updated = false
+			return nil
+		}
+		patch := client.MergeFromWithOptions(resource.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+		obj.SetConditions(conditions)
+		if err := cl.Status().Patch(ctx, resource, patch); err != nil {
+			if apierrors.IsConflict(err) {
+				// retrieve the latest version before retrying
+				if getErr := cl.Get(ctx, client.ObjectKeyFromObject(resource), resource); getErr != nil {
+					return getErr
+				}
+			}
+			return err
+		}
+		updated = true
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to set the conditions of the '%s' resource", resource.GetName())
+	}
+	return updated, nil
+}
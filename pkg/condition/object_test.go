@@ -0,0 +1,187 @@
+package condition_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestStatusConditions(t *testing.T) {
+	t.Run("reads and writes the conditions", func(t *testing.T) {
+		// given
+		space := newSpace(readyCondition("Provisioning"))
+
+		// when
+		obj, err := condition.StatusConditions(space)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, space.Status.Conditions, obj.GetConditions())
+		provisioned := readyCondition("Provisioned")
+		obj.SetConditions([]toolchainv1alpha1.Condition{provisioned})
+		assert.Equal(t, []toolchainv1alpha1.Condition{provisioned}, space.Status.Conditions)
+	})
+
+	t.Run("fails when the resource has no conditions", func(t *testing.T) {
+		// when
+		_, err := condition.StatusConditions(&corev1.ConfigMap{})
+
+		// then
+		require.EqualError(t, err, "the resource of type *v1.ConfigMap has no status")
+	})
+
+	t.Run("fails when the status has no conditions", func(t *testing.T) {
+		// when
+		_, err := condition.StatusConditions(&corev1.Namespace{})
+
+		// then
+		require.EqualError(t, err, "the status of the resource of type *v1.Namespace has no conditions")
+	})
+}
+
+func TestSetConditions(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("patches the status when the conditions changed", func(t *testing.T) {
+		// given
+		provisioning := readyCondition("Provisioning")
+		space := newSpace(provisioning)
+		cl := test.NewFakeClient(t, space)
+		obj, err := condition.StatusConditions(space)
+		require.NoError(t, err)
+
+		// when
+		updated, err := condition.SetConditions(ctx, cl, obj, readyCondition("Provisioned"))
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, updated)
+		actual := &toolchainv1alpha1.Space{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(space), actual))
+		assert.True(t, condition.IsFalseWithReason(actual.Status.Conditions, toolchainv1alpha1.ConditionReady, "Provisioned"))
+		// status did not change, so the transition time is kept
+		assert.True(t, provisioning.LastTransitionTime.Equal(&actual.Status.Conditions[0].LastTransitionTime))
+	})
+
+	t.Run("does not patch the status when nothing changed", func(t *testing.T) {
+		// given
+		space := newSpace(readyCondition("Provisioning"))
+		cl := test.NewFakeClient(t, space)
+		cl.MockStatusPatch = func(_ context.Context, _ client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+			return fmt.Errorf("should not be called")
+		}
+		obj, err := condition.StatusConditions(space)
+		require.NoError(t, err)
+
+		// when
+		updated, err := condition.SetConditions(ctx, cl, obj, readyCondition("Provisioning"))
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	t.Run("retries on conflict with the latest version", func(t *testing.T) {
+		// given
+		space := newSpace(readyCondition("Provisioning"))
+		cl := test.NewFakeClient(t, space)
+		obj, err := condition.StatusConditions(space)
+		require.NoError(t, err)
+		// someone else updated the space in the meantime
+		latest := &toolchainv1alpha1.Space{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(space), latest))
+		latest.Status.Conditions = append(latest.Status.Conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionType("Other"),
+			Status: corev1.ConditionTrue,
+		})
+		require.NoError(t, cl.Status().Update(ctx, latest))
+		patchCalls := 0
+		cl.MockStatusPatch = func(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			patchCalls++
+			return cl.Client.Status().Patch(ctx, obj, patch, opts...)
+		}
+
+		// when
+		updated, err := condition.SetConditions(ctx, cl, obj, readyCondition("Provisioned"))
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, 2, patchCalls)
+		actual := &toolchainv1alpha1.Space{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(space), actual))
+		assert.True(t, condition.IsFalseWithReason(actual.Status.Conditions, toolchainv1alpha1.ConditionReady, "Provisioned"))
+		assert.True(t, condition.IsTrue(actual.Status.Conditions, toolchainv1alpha1.ConditionType("Other")))
+	})
+
+	t.Run("fails when the patch fails", func(t *testing.T) {
+		// given
+		space := newSpace(readyCondition("Provisioning"))
+		cl := test.NewFakeClient(t, space)
+		cl.MockStatusPatch = func(_ context.Context, _ client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+			return fmt.Errorf("mock error")
+		}
+		obj, err := condition.StatusConditions(space)
+		require.NoError(t, err)
+
+		// when
+		updated, err := condition.SetConditions(ctx, cl, obj, readyCondition("Provisioned"))
+
+		// then
+		require.EqualError(t, err, "unable to set the conditions of the 'john' resource: mock error")
+		assert.False(t, updated)
+	})
+
+	t.Run("fails when the conflict persists", func(t *testing.T) {
+		// given
+		space := newSpace(readyCondition("Provisioning"))
+		cl := test.NewFakeClient(t, space)
+		cl.MockStatusPatch = func(_ context.Context, _ client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+			return apierrors.NewConflict(schema.GroupResource{Resource: "spaces"}, "john", fmt.Errorf("mock conflict"))
+		}
+		obj, err := condition.StatusConditions(space)
+		require.NoError(t, err)
+
+		// when
+		updated, err := condition.SetConditions(ctx, cl, obj, readyCondition("Provisioned"))
+
+		// then
+		require.Error(t, err)
+		assert.True(t, apierrors.IsConflict(err))
+		assert.False(t, updated)
+	})
+}
+
+func newSpace(conditions ...toolchainv1alpha1.Condition) *toolchainv1alpha1.Space {
+	return &toolchainv1alpha1.Space{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "john",
+			Namespace: test.HostOperatorNs,
+		},
+		Status: toolchainv1alpha1.SpaceStatus{
+			Conditions: conditions,
+		},
+	}
+}
+
+func readyCondition(reason string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:               toolchainv1alpha1.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		Reason:             reason,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)).Rfc3339Copy(),
+	}
+}
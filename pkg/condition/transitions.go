@@ -0,0 +1,149 @@
+package condition
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// TransitionEventReason the reason of the Events emitted when a condition transitions to another status or reason
+const TransitionEventReason = "ConditionTransition"
+
+// stateDurations the time spent by the resources in a given status/reason of a condition, by resource kind
+var stateDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "sandbox_condition_state_duration_seconds",
+	Help: "Time spent by the resources in a given status/reason of a condition before transitioning, by resource kind",
+	// from 1s to ~18h
+	Buckets: prometheus.ExponentialBuckets(1, 2, 17),
+}, []string{"kind", "type", "status", "reason"})
+
+// RegisterMetrics registers the metrics of the condition transitions in the given registry
+// (eg: `metrics.Registry` of controller-runtime)
+func RegisterMetrics(registry prometheus.Registerer) error {
+	return registry.Register(stateDurations)
+}
+
+// TransitionRecorder updates the conditions of the resources of a given kind (see AddOrUpdateStatusConditions), and on
+// every transition of a condition to another status or reason, records the time spent in the previous status/reason
+// and optionally emits an Event on the resource.
+type TransitionRecorder struct {
+	kind          string
+	eventRecorder record.EventRecorder
+	now           func() time.Time
+	mu            sync.Mutex
+	// entered the status/reason of the conditions of the resources, and the time at which it was entered, by resource and condition type
+	entered map[stateKey]state
+}
+
+type stateKey struct {
+	namespace     string
+	name          string
+	conditionType toolchainv1alpha1.ConditionType
+}
+
+type state struct {
+	status    corev1.ConditionStatus
+	reason    string
+	enteredAt time.Time
+}
+
+// TransitionRecorderOption an option to configure the TransitionRecorder
+type TransitionRecorderOption func(*TransitionRecorder)
+
+// WithEventRecorder emits an Event on the resource on every transition of a condition
+func WithEventRecorder(eventRecorder record.EventRecorder) TransitionRecorderOption {
+	return func(r *TransitionRecorder) {
+		r.eventRecorder = eventRecorder
+	}
+}
+
+// NewTransitionRecorder returns a new TransitionRecorder for the resources of the given kind (eg: `UserSignup`)
+func NewTransitionRecorder(kind string, options ...TransitionRecorderOption) *TransitionRecorder {
+	r := &TransitionRecorder{
+		kind:    kind,
+		now:     time.Now,
+		entered: map[stateKey]state{},
+	}
+	for _, apply := range options {
+		apply(r)
+	}
+	return r
+}
+
+// AddOrUpdateStatusConditions has the same behavior as the AddOrUpdateStatusConditions func, and records the transitions
+// of the conditions of the given object. Since the `LastTransitionTime` of a condition only changes along with its
+// status, the recorder keeps the time at which the current status/reason of each condition was entered. The time spent
+// in the previous status/reason is computed from this time, or from the `LastTransitionTime` of the condition if the
+// recorder did not see the transition to the previous status/reason (eg, after a restart of the operator).
+func (r *TransitionRecorder) AddOrUpdateStatusConditions(obj runtime.Object, conditions []toolchainv1alpha1.Condition, newConditions ...toolchainv1alpha1.Condition) ([]toolchainv1alpha1.Condition, bool) {
+	now := r.now()
+	key, tracked := objectKey(obj)
+	r.mu.Lock()
+	for _, newCondition := range newConditions {
+		previous, found := FindConditionByType(conditions, newCondition.Type)
+		if found && previous.Status == newCondition.Status && previous.Reason == newCondition.Reason {
+			continue
+		}
+		key.conditionType = newCondition.Type
+		if found {
+			enteredAt := previous.LastTransitionTime.Time
+			if entered, ok := r.entered[key]; ok && entered.status == previous.Status && entered.reason == previous.Reason {
+				enteredAt = entered.enteredAt
+			}
+			r.recordTransition(obj, previous, newCondition, enteredAt, now)
+		}
+		if tracked {
+			r.entered[key] = state{status: newCondition.Status, reason: newCondition.Reason, enteredAt: now}
+		}
+	}
+	r.mu.Unlock()
+	return AddOrUpdateStatusConditions(conditions, newConditions...)
+}
+
+// Forget removes the times at which the conditions of the given object entered their current status/reason, and should
+// be called when the object is deleted
+func (r *TransitionRecorder) Forget(obj runtime.Object) {
+	key, tracked := objectKey(obj)
+	if !tracked {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k := range r.entered {
+		if k.namespace == key.namespace && k.name == key.name {
+			delete(r.entered, k)
+		}
+	}
+}
+
+// objectKey returns the key of the given object, and false if the object has no name
+func objectKey(obj runtime.Object) (stateKey, bool) {
+	if object, ok := obj.(metav1.Object); ok && object.GetName() != "" {
+		return stateKey{namespace: object.GetNamespace(), name: object.GetName()}, true
+	}
+	return stateKey{}, false
+}
+
+func (r *TransitionRecorder) recordTransition(obj runtime.Object, previous, current toolchainv1alpha1.Condition, enteredAt, now time.Time) {
+	if enteredAt.IsZero() {
+		return
+	}
+	duration := now.Sub(enteredAt)
+	if duration < 0 {
+		duration = 0
+	}
+	stateDurations.WithLabelValues(r.kind, string(previous.Type), string(previous.Status), previous.Reason).Observe(duration.Seconds())
+	if r.eventRecorder != nil && obj != nil {
+		r.eventRecorder.Event(obj, corev1.EventTypeNormal, TransitionEventReason,
+			fmt.Sprintf("condition %s changed from %s/%s to %s/%s after %s",
+				previous.Type, previous.Status, previous.Reason, current.Status, current.Reason, duration.Round(time.Second)))
+	}
+}
@@ -0,0 +1,244 @@
+package condition
+
+import (
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestTransitionRecorder(t *testing.T) {
+	now := time.Now()
+	userSignup := &toolchainv1alpha1.UserSignup{ObjectMeta: metav1.ObjectMeta{Name: "john", Namespace: "toolchain-host-operator"}}
+	provisioning := toolchainv1alpha1.Condition{
+		Type:               toolchainv1alpha1.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		Reason:             "Provisioning",
+		LastTransitionTime: metav1.NewTime(now.Add(-90 * time.Second)),
+	}
+
+	t.Run("records the time spent in the previous status and reason", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		eventRecorder := record.NewFakeRecorder(10)
+		r := NewTransitionRecorder("UserSignup", WithEventRecorder(eventRecorder))
+		r.now = func() time.Time { return now }
+
+		// when
+		result, updated := r.AddOrUpdateStatusConditions(userSignup, []toolchainv1alpha1.Condition{provisioning}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: "Provisioned",
+		})
+
+		// then
+		assert.True(t, updated)
+		assert.True(t, IsTrueWithReason(result, toolchainv1alpha1.ConditionReady, "Provisioned"))
+		histogram := stateDurations.WithLabelValues("UserSignup", "Ready", "False", "Provisioning").(prometheus.Histogram)
+		metrics.AssertHistogramSampleCountEquals(t, 1, histogram)
+		metrics.AssertHistogramBucketEquals(t, 0, 64, histogram)
+		metrics.AssertHistogramBucketEquals(t, 1, 128, histogram)
+		require.Len(t, eventRecorder.Events, 1)
+		assert.Equal(t, "Normal ConditionTransition condition Ready changed from False/Provisioning to True/Provisioned after 1m30s", <-eventRecorder.Events)
+	})
+
+	t.Run("records the reason change without status change", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		r := NewTransitionRecorder("UserSignup")
+		r.now = func() time.Time { return now }
+
+		// when
+		_, updated := r.AddOrUpdateStatusConditions(userSignup, []toolchainv1alpha1.Condition{provisioning}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "ProvisioningFailed",
+		})
+
+		// then
+		assert.True(t, updated)
+		metrics.AssertHistogramSampleCountEquals(t, 1, stateDurations.WithLabelValues("UserSignup", "Ready", "False", "Provisioning").(prometheus.Histogram))
+	})
+
+	t.Run("records the time spent in the previous reason with the same status", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		eventRecorder := record.NewFakeRecorder(10)
+		r := NewTransitionRecorder("UserSignup", WithEventRecorder(eventRecorder))
+		r.now = func() time.Time { return now }
+		// the status changed 90s ago, but the reason changed 20s ago
+		r.now = func() time.Time { return now.Add(-20 * time.Second) }
+		conditions, _ := r.AddOrUpdateStatusConditions(userSignup, []toolchainv1alpha1.Condition{provisioning}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "ProvisioningFailed",
+		})
+		<-eventRecorder.Events
+		r.now = func() time.Time { return now }
+
+		// when
+		result, updated := r.AddOrUpdateStatusConditions(userSignup, conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "Provisioning",
+		})
+
+		// then
+		assert.True(t, updated)
+		histogram := stateDurations.WithLabelValues("UserSignup", "Ready", "False", "ProvisioningFailed").(prometheus.Histogram)
+		metrics.AssertHistogramSampleCountEquals(t, 1, histogram)
+		metrics.AssertHistogramBucketEquals(t, 0, 16, histogram)
+		metrics.AssertHistogramBucketEquals(t, 1, 32, histogram)
+		assert.Equal(t, "Normal ConditionTransition condition Ready changed from False/ProvisioningFailed to False/Provisioning after 20s", <-eventRecorder.Events)
+		ready, found := FindConditionByType(result, toolchainv1alpha1.ConditionReady)
+		require.True(t, found)
+		assert.Equal(t, provisioning.LastTransitionTime, ready.LastTransitionTime)
+		assert.Nil(t, ready.LastUpdatedTime) // not used by the recorder
+	})
+
+	t.Run("falls back to the last transition time once the object is forgotten", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		eventRecorder := record.NewFakeRecorder(10)
+		r := NewTransitionRecorder("UserSignup", WithEventRecorder(eventRecorder))
+		r.now = func() time.Time { return now.Add(-20 * time.Second) }
+		conditions, _ := r.AddOrUpdateStatusConditions(userSignup, []toolchainv1alpha1.Condition{provisioning}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "ProvisioningFailed",
+		})
+		<-eventRecorder.Events
+		r.now = func() time.Time { return now }
+
+		// when
+		r.Forget(userSignup)
+		_, updated := r.AddOrUpdateStatusConditions(userSignup, conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "Provisioning",
+		})
+
+		// then
+		assert.True(t, updated)
+		assert.Equal(t, "Normal ConditionTransition condition Ready changed from False/ProvisioningFailed to False/Provisioning after 1m30s", <-eventRecorder.Events)
+	})
+
+	t.Run("records the time spent in each reason", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		start := now.Add(-time.Hour)
+		r := NewTransitionRecorder("UserSignup")
+		r.now = func() time.Time { return start }
+		conditions, _ := r.AddOrUpdateStatusConditions(userSignup, nil, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "Provisioning",
+		})
+		r.now = func() time.Time { return start.Add(10 * time.Minute) }
+		conditions, _ = r.AddOrUpdateStatusConditions(userSignup, conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "ProvisioningFailed",
+		})
+		r.now = func() time.Time { return start.Add(11 * time.Minute) }
+		conditions, _ = r.AddOrUpdateStatusConditions(userSignup, conditions, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "ProvisioningFailed",
+			Message: "retrying",
+		})
+		r.now = func() time.Time { return start.Add(12 * time.Minute) }
+
+		// when
+		_, updated := r.AddOrUpdateStatusConditions(userSignup, conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: "Provisioned",
+		})
+
+		// then
+		assert.True(t, updated)
+		provisioningHistogram := stateDurations.WithLabelValues("UserSignup", "Ready", "False", "Provisioning").(prometheus.Histogram)
+		metrics.AssertHistogramSampleCountEquals(t, 1, provisioningHistogram)
+		metrics.AssertHistogramBucketEquals(t, 0, 512, provisioningHistogram)
+		metrics.AssertHistogramBucketEquals(t, 1, 1024, provisioningHistogram) // 10min
+		failedHistogram := stateDurations.WithLabelValues("UserSignup", "Ready", "False", "ProvisioningFailed").(prometheus.Histogram)
+		metrics.AssertHistogramSampleCountEquals(t, 1, failedHistogram)
+		metrics.AssertHistogramBucketEquals(t, 0, 64, failedHistogram)
+		metrics.AssertHistogramBucketEquals(t, 1, 128, failedHistogram) // 2min, the message change is ignored
+	})
+
+	t.Run("does not record when only the message changed", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		eventRecorder := record.NewFakeRecorder(10)
+		r := NewTransitionRecorder("UserSignup", WithEventRecorder(eventRecorder))
+
+		// when
+		_, updated := r.AddOrUpdateStatusConditions(userSignup, []toolchainv1alpha1.Condition{provisioning}, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Provisioning",
+			Message: "still provisioning",
+		})
+
+		// then
+		assert.True(t, updated)
+		metrics.AssertAllHistogramBucketsAreEmpty(t, stateDurations.WithLabelValues("UserSignup", "Ready", "False", "Provisioning").(prometheus.Histogram))
+		assert.Empty(t, eventRecorder.Events)
+	})
+
+	t.Run("does not record when the condition is new", func(t *testing.T) {
+		// given
+		stateDurations.Reset()
+		eventRecorder := record.NewFakeRecorder(10)
+		r := NewTransitionRecorder("Space", WithEventRecorder(eventRecorder))
+
+		// when
+		result, updated := r.AddOrUpdateStatusConditions(userSignup, nil, provisioning)
+
+		// then
+		assert.True(t, updated)
+		assert.Len(t, result, 1)
+		assert.Empty(t, eventRecorder.Events)
+		families, err := gather()
+		require.NoError(t, err)
+		assert.Empty(t, families)
+	})
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	// given
+	registry := prometheus.NewRegistry()
+
+	// when
+	err := RegisterMetrics(registry)
+
+	// then
+	require.NoError(t, err)
+	assert.Error(t, RegisterMetrics(registry))
+}
+
+func gather() ([]string, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(stateDurations); err != nil {
+		return nil, err
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(families))
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	return names, nil
+}
@@ -14,12 +14,31 @@ import (
 
 var configCache = &cache{}
 
+// configName the name of the configuration object (ToolchainConfig or MemberOperatorConfig)
+const configName = "config"
+
 var cacheLog = logf.Log.WithName("cache_toolchainconfig")
 
 type cache struct {
 	sync.RWMutex
 	configObj runtime.Object
 	secrets   map[string]map[string]string // map of secret key-value pairs indexed by secret name
+	configMap mapLayer                     // the layer of the ConfigMap loaded with LoadConfigMap
+}
+
+func (c *cache) setConfigMap(layer mapLayer) {
+	c.Lock()
+	defer c.Unlock()
+	c.configMap = layer
+}
+
+func (c *cache) getConfigMap() Layer {
+	c.RLock()
+	defer c.RUnlock()
+	if c.configMap.source == "" {
+		return mapLayer{source: SourceConfigMap}
+	}
+	return c.configMap
 }
 
 func (c *cache) set(config runtime.Object, secrets map[string]map[string]string) {
@@ -29,6 +48,13 @@ func (c *cache) set(config runtime.Object, secrets map[string]map[string]string)
 	c.secrets = CopyOf(secrets)
 }
 
+func (c *cache) reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.configObj = nil
+	c.secrets = nil
+}
+
 func (c *cache) get() (runtime.Object, map[string]map[string]string) {
 	c.RLock()
 	defer c.RUnlock()
@@ -42,16 +68,19 @@ func UpdateConfig(config runtime.Object, secrets map[string]map[string]string) {
 	configCache.set(config, secrets)
 }
 
-// loadLatest retrieves the latest configuration object and secrets using the provided client and updates the cache.
+// loadLatest retrieves the latest configuration object and secrets using the provided reader and updates the cache.
+// The reader must not be backed by the cache of the manager (use the manager's APIReader): reading a secret through
+// the cache would start an informer on all the secrets of the cluster, which requires the RBAC to list and watch them
+// all, and keeps them all in memory.
 // If the resource is not found, then returns nil for the configuration and secret.
 // If any failure happens while getting the configuration object or secrets, then returns an error.
-func LoadLatest(cl client.Client, configObj client.Object) (runtime.Object, map[string]map[string]string, error) {
+func LoadLatest(cl client.Reader, configObj client.Object) (runtime.Object, map[string]map[string]string, error) {
 	namespace, err := GetWatchNamespace()
 	if err != nil {
 		return nil, nil, errs.Wrap(err, "failed to get watch namespace")
 	}
 
-	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "config"}, configObj); err != nil {
+	if err := getConfig(context.TODO(), cl, types.NamespacedName{Namespace: namespace, Name: configName}, configObj); err != nil {
 		if apierrors.IsNotFound(err) {
 			cacheLog.Info("ToolchainConfig resource with the name 'config' wasn't found, default configuration will be used", "namespace", namespace)
 			return nil, nil, nil
@@ -59,7 +88,7 @@ func LoadLatest(cl client.Client, configObj client.Object) (runtime.Object, map[
 		return nil, nil, err
 	}
 
-	allSecrets, err := LoadSecrets(cl, namespace)
+	allSecrets, err := LoadSecrets(cl, namespace, WithSecretNames(ReferencedSecrets(configObj)...))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -70,11 +99,11 @@ func LoadLatest(cl client.Client, configObj client.Object) (runtime.Object, map[
 }
 
 // getConfig returns a cached configuration object
-// If no config is stored in the cache, then it retrieves it from the cluster using the provided LoadConfiguration func
-// and stores in the cache.
+// If no config is stored in the cache, then it retrieves it from the cluster using the provided reader (which must
+// not be backed by the cache of the manager, see LoadLatest) and stores in the cache.
 // If the resource is not found, then returns nil for the configuration and secret.
 // If any failure happens while getting the configuration object or secrets, then returns an error.
-func GetConfig(cl client.Client, configObj client.Object) (runtime.Object, map[string]map[string]string, error) {
+func GetConfig(cl client.Reader, configObj client.Object) (runtime.Object, map[string]map[string]string, error) {
 	config, secrets := configCache.get()
 	if config == nil {
 		return LoadLatest(cl, configObj)
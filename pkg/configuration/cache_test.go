@@ -145,18 +145,16 @@ func TestGetConfigFailed(t *testing.T) {
 	})
 
 	t.Run("load secrets error", func(t *testing.T) {
-		config := NewToolchainConfigObjWithReset(t)
+		config := NewToolchainConfigObjWithReset(t, testconfig.Notifications().Secret().Ref("notification-secret"))
 		// given
 		cl := test.NewFakeClient(t, config)
-		cl.MockList = func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
-			return fmt.Errorf("list error")
-		}
+		cl.MockGet = mockGetSecretError(cl)
 
 		// when
 		actual, secrets, err := LoadLatest(cl, &toolchainv1alpha1.ToolchainConfig{})
 
 		// then
-		require.EqualError(t, err, "list error")
+		require.EqualError(t, err, "secret error")
 		assert.Nil(t, actual)
 		assert.Empty(t, secrets)
 	})
@@ -199,7 +197,9 @@ func TestLoadLatest(t *testing.T) {
 	restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.HostOperatorNs)
 	defer restore()
 	t.Run("config found", func(t *testing.T) {
-		initConfig := NewToolchainConfigObjWithReset(t, testconfig.AutomaticApproval().Enabled(true))
+		initConfig := NewToolchainConfigObjWithReset(t,
+			testconfig.AutomaticApproval().Enabled(true),
+			testconfig.Notifications().Secret().Ref("notification-secret"))
 		initSecret := &v1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "notification-secret",
@@ -212,9 +212,10 @@ func TestLoadLatest(t *testing.T) {
 		}
 		// given
 		cl := test.NewFakeClient(t, initConfig, initSecret)
+		apiReader := struct{ client.Reader }{cl} // eg, the APIReader of the manager, which is not a client.Client
 
 		// when
-		actual, secrets, err := LoadLatest(cl, &toolchainv1alpha1.ToolchainConfig{})
+		actual, secrets, err := LoadLatest(apiReader, &toolchainv1alpha1.ToolchainConfig{})
 
 		// then
 		require.NoError(t, err)
@@ -239,7 +240,7 @@ func TestLoadLatest(t *testing.T) {
 
 		t.Run("returns the new value when the config has been updated", func(t *testing.T) {
 			// get
-			changedConfig := UpdateToolchainConfigObjWithReset(t, cl, testconfig.AutomaticApproval().Enabled(false))
+			changedConfig := UpdateToolchainConfigObjWithReset(t, cl, testconfig.AutomaticApproval().Enabled(false), testconfig.Notifications().Secret().Ref("notification-secret"))
 			err := cl.Update(context.TODO(), changedConfig)
 			require.NoError(t, err)
 
@@ -293,18 +294,16 @@ func TestLoadLatest(t *testing.T) {
 	})
 
 	t.Run("load secrets error", func(t *testing.T) {
-		initconfig := NewToolchainConfigObjWithReset(t)
+		initconfig := NewToolchainConfigObjWithReset(t, testconfig.Notifications().Secret().Ref("notification-secret"))
 		// given
 		cl := test.NewFakeClient(t, initconfig)
-		cl.MockList = func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
-			return fmt.Errorf("list error")
-		}
+		cl.MockGet = mockGetSecretError(cl)
 
 		// when
 		actual, secrets, err := LoadLatest(cl, &toolchainv1alpha1.ToolchainConfig{})
 
 		// then
-		require.EqualError(t, err, "list error")
+		require.EqualError(t, err, "secret error")
 		assert.Nil(t, actual)
 		assert.Empty(t, secrets)
 	})
@@ -319,7 +318,7 @@ func TestMultipleExecutionsInParallel(t *testing.T) {
 	var waitForFinished sync.WaitGroup
 	initconfig := NewToolchainConfigObjWithReset(t, testconfig.Members().SpecificPerMemberCluster("member", toolchainv1alpha1.MemberOperatorConfigSpec{
 		Environment: ptr.To("env"),
-	}))
+	}), testconfig.Notifications().Secret().Ref("notification-secret"))
 
 	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -377,3 +376,12 @@ func TestMultipleExecutionsInParallel(t *testing.T) {
 	assert.NotEmpty(t, toolchaincfg.Spec)
 	require.NotEmpty(t, secrets)
 }
+
+func mockGetSecretError(cl *test.FakeClient) func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+		if _, ok := obj.(*v1.Secret); ok {
+			return fmt.Errorf("secret error")
+		}
+		return cl.Client.Get(ctx, key, obj, opts...)
+	}
+}
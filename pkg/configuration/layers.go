@@ -0,0 +1,302 @@
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SourceConfigMap the value is read from a key of a ConfigMap
+const SourceConfigMap Source = "configMap"
+
+// Layer a source of configuration values, indexed by key (eg: `toolchainCluster.healthCheckPeriod`)
+type Layer interface {
+	// Source the provenance of the values of this layer
+	Source() Source
+	// Origin details about the provenance of the value of the given key, eg, the name of the ConfigMap
+	Origin(key string) string
+	// Lookup returns the value of the given key, and false if the layer has no value for this key
+	Lookup(key string) (string, bool)
+}
+
+// Layers configuration layers, in increasing order of precedence: the value of a key is looked up in the last layer
+// first, then in the previous one, etc. The usual order is: defaults < ConfigMap < configuration resource < env vars.
+// Contrary to LoadFromConfigMap, the layers do not mutate the env vars of the process.
+type Layers []Layer
+
+// NewLayers returns the given layers, in increasing order of precedence
+func NewLayers(layers ...Layer) Layers {
+	return layers
+}
+
+// Lookup returns the value of the given key, along with its provenance. The returned bool is false if none
+// of the layers has a value for this key.
+func (l Layers) Lookup(key string) (EffectiveValue, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if value, found := l[i].Lookup(key); found {
+			return EffectiveValue{Path: key, Value: value, Source: l[i].Source(), Origin: l[i].Origin(key)}, true
+		}
+	}
+	return EffectiveValue{Path: key}, false
+}
+
+// GetString returns the value of the given key, or the given default value if none of the layers has a value for this key
+func (l Layers) GetString(key, defaultValue string) string {
+	if value, found := l.Lookup(key); found {
+		return value.Value
+	}
+	return defaultValue
+}
+
+// GetBool returns the value of the given key, or the given default value if none of the layers has a valid value
+// for this key. A value which cannot be parsed as a bool is ignored, ie, the value is looked up in the previous layers.
+func (l Layers) GetBool(key string, defaultValue bool) bool {
+	if value := l.BoolValue(key); value.Value != "" {
+		b, _ := strconv.ParseBool(value.Value)
+		return b
+	}
+	return defaultValue
+}
+
+// GetInt returns the value of the given key, or the given default value if none of the layers has a valid value
+// for this key. A value which cannot be parsed as an int is ignored, ie, the value is looked up in the previous layers.
+func (l Layers) GetInt(key string, defaultValue int) int {
+	if value := l.IntValue(key); value.Value != "" {
+		i, _ := strconv.Atoi(value.Value)
+		return i
+	}
+	return defaultValue
+}
+
+// GetDuration returns the value of the given key, or the given default value if none of the layers has a valid value
+// for this key. A value which cannot be parsed as a duration is ignored, ie, the value is looked up in the previous layers.
+func (l Layers) GetDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := l.DurationValue(key); value.Value != "" {
+		d, _ := time.ParseDuration(value.Value)
+		return d
+	}
+	return defaultValue
+}
+
+// StringValue returns the effective value of the given key. The default value is the one of the defaults layer
+// (see NewDefaultsLayer), the value is empty if none of the layers has a value for this key.
+func (l Layers) StringValue(key string) EffectiveValue {
+	return l.value(key, func(value string) (string, error) {
+		return value, nil
+	})
+}
+
+// BoolValue returns the effective value of the given key (see StringValue). The values which cannot be parsed
+// as a bool are ignored (see GetBool).
+func (l Layers) BoolValue(key string) EffectiveValue {
+	return l.value(key, func(value string) (string, error) {
+		b, err := strconv.ParseBool(value)
+		return strconv.FormatBool(b), err
+	})
+}
+
+// IntValue returns the effective value of the given key (see StringValue). The values which cannot be parsed
+// as an int are ignored (see GetInt).
+func (l Layers) IntValue(key string) EffectiveValue {
+	return l.value(key, func(value string) (string, error) {
+		i, err := strconv.Atoi(value)
+		return strconv.Itoa(i), err
+	})
+}
+
+// DurationValue returns the effective value of the given key (see StringValue). The values which cannot be parsed
+// as a duration are ignored (see GetDuration).
+func (l Layers) DurationValue(key string) EffectiveValue {
+	return l.value(key, func(value string) (string, error) {
+		d, err := time.ParseDuration(value)
+		return d.String(), err
+	})
+}
+
+// SecretValue returns the redacted effective value of the key of the secret whose name and key are the values
+// of the given refKey and keyKey keys (see SecretValue)
+func (l Layers) SecretValue(path string, secrets map[string]map[string]string, refKey, keyKey string) EffectiveValue {
+	ref, key := l.GetString(refKey, ""), l.GetString(keyKey, "")
+	return SecretValue(path, secrets, &ref, &key)
+}
+
+// value returns the first value of the given key which can be formatted with the given func, starting from the last layer.
+// The invalid values which were ignored are mentioned in the origin of the returned value.
+func (l Layers) value(key string, format func(string) (string, error)) EffectiveValue {
+	var details []string
+	for i := len(l) - 1; i >= 0; i-- {
+		value, found := l[i].Lookup(key)
+		if !found {
+			continue
+		}
+		formatted, err := format(value)
+		if err != nil {
+			details = append(details, fmt.Sprintf("invalid value '%s' ignored", value))
+			continue
+		}
+		if origin := l[i].Origin(key); origin != "" {
+			details = append([]string{origin}, details...)
+		}
+		return EffectiveValue{Path: key, Value: formatted, Source: l[i].Source(), Origin: strings.Join(details, ", ")}
+	}
+	return EffectiveValue{Path: key, Source: SourceDefault, Origin: strings.Join(details, ", ")}
+}
+
+// mapLayer a layer whose values are stored in a map
+type mapLayer struct {
+	source Source
+	origin string
+	values map[string]string
+}
+
+func (l mapLayer) Source() Source {
+	return l.source
+}
+
+func (l mapLayer) Origin(_ string) string {
+	return l.origin
+}
+
+func (l mapLayer) Lookup(key string) (string, bool) {
+	value, found := l.values[key]
+	return value, found
+}
+
+// NewDefaultsLayer returns a layer with the given default values
+func NewDefaultsLayer(values map[string]string) Layer {
+	return mapLayer{source: SourceDefault, values: values}
+}
+
+// LoadConfigMap loads the data of the ConfigMap whose name is set in the given env var, in the watch namespace, and
+// stores it in the configuration cache, so that it is used as the ConfigMap layer of the Layers returned by
+// NewOperatorLayers. The layer is empty if the env var is not set or if the ConfigMap does not exist.
+// This is the replacement of LoadFromConfigMap, which does not mutate the env vars of the process.
+func LoadConfigMap(resourceKey string, cl client.Reader) error {
+	layer, err := loadConfigMapLayer(resourceKey, cl)
+	if err != nil {
+		return err
+	}
+	configCache.setConfigMap(layer)
+	return nil
+}
+
+// NewOperatorLayers returns the configuration layers of an operator, in increasing order of precedence: the given
+// default values, the ConfigMap loaded with LoadConfigMap, the given configuration resource (ToolchainConfig or
+// MemberOperatorConfig, which may be nil) and the env vars with the given prefix (eg: `MEMBER_OPERATOR`).
+// The layer of the configuration resource is omitted if its spec cannot be read, in which case an error is returned
+// along with the other layers.
+func NewOperatorLayers(prefix string, defaults map[string]string, config runtime.Object) (Layers, error) {
+	layers := NewLayers(NewDefaultsLayer(defaults), configCache.getConfigMap())
+	resource, err := NewResourceLayer(config)
+	if err == nil {
+		layers = append(layers, resource)
+	}
+	return append(layers, NewEnvLayer(prefix)), err
+}
+
+func loadConfigMapLayer(resourceKey string, cl client.Reader) (mapLayer, error) {
+	configMapName := getResourceName(resourceKey)
+	if configMapName == "" {
+		return mapLayer{source: SourceConfigMap}, nil
+	}
+	namespace, err := GetWatchNamespace()
+	if err != nil {
+		return mapLayer{}, err
+	}
+	return newConfigMapLayer(context.TODO(), cl, namespace, configMapName)
+}
+
+func newConfigMapLayer(ctx context.Context, cl client.Reader, namespace, name string) (mapLayer, error) {
+	configMap := &v1.ConfigMap{}
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return mapLayer{}, err
+		}
+		logf.Log.Info("configmap is not found")
+	}
+	values := make(map[string]string, len(configMap.Data))
+	for key, value := range configMap.Data {
+		values[key] = value
+	}
+	return mapLayer{source: SourceConfigMap, origin: name, values: values}, nil
+}
+
+// NewResourceLayer returns a layer with the values of the spec of the given configuration resource (ToolchainConfig
+// or MemberOperatorConfig), indexed by their JSON path in the spec (eg: `toolchainCluster.healthCheckPeriod`).
+// Values which are not set in the resource are not in the layer.
+func NewResourceLayer(obj runtime.Object) (Layer, error) {
+	values := map[string]string{}
+	if s := spec(obj); s != nil {
+		raw, err := json.Marshal(s)
+		if err != nil {
+			return nil, errs.Wrap(err, "unable to read the spec of the configuration resource")
+		}
+		content := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &content); err != nil {
+			return nil, errs.Wrap(err, "unable to read the spec of the configuration resource")
+		}
+		flatten("", content, values)
+	}
+	return mapLayer{source: SourceResource, values: values}, nil
+}
+
+func flatten(prefix string, content map[string]interface{}, values map[string]string) {
+	for key, value := range content {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, values)
+		case string:
+			values[key] = v
+		case nil:
+		default:
+			// numbers, bools and lists
+			raw, _ := json.Marshal(v)
+			values[key] = string(raw)
+		}
+	}
+}
+
+// envLayer a layer whose values are read from the env vars
+type envLayer struct {
+	prefix string
+}
+
+// NewEnvLayer returns a layer whose values are read from the env vars with the given prefix, eg: the value of the
+// `toolchainCluster.healthCheckPeriod` key is read from the `MEMBER_OPERATOR_TOOLCHAINCLUSTER_HEALTHCHECKPERIOD`
+// env var when the prefix is `MEMBER_OPERATOR`.
+// The env vars set by the deprecated LoadFromConfigMap are ignored, since their values are in the ConfigMap layer,
+// whose precedence is lower than the one of the configuration resource.
+func NewEnvLayer(prefix string) Layer {
+	return envLayer{prefix: prefix}
+}
+
+func (l envLayer) Source() Source {
+	return SourceEnvVar
+}
+
+func (l envLayer) Origin(key string) string {
+	return createOperatorEnvVarKey(l.prefix, key)
+}
+
+func (l envLayer) Lookup(key string) (string, bool) {
+	envVar := createOperatorEnvVarKey(l.prefix, key)
+	if isEnvVarFromConfigMap(envVar) {
+		return "", false
+	}
+	return os.LookupEnv(envVar)
+}
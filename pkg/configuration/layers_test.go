@@ -0,0 +1,286 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestLayers(t *testing.T) {
+	// given
+	cl := test.NewFakeClient(t, createConfigMap("member-config", test.MemberOperatorNs, map[string]string{
+		"environment":                         "configmap-env",
+		"toolchainCluster.healthCheckPeriod":  "20s",
+		"toolchainCluster.healthCheckTimeout": "4s",
+		"webhook.deploy":                      "false",
+	}))
+	configMap, err := newConfigMapLayer(context.TODO(), cl, test.MemberOperatorNs, "member-config")
+	require.NoError(t, err)
+	resource, err := NewResourceLayer(testconfig.NewMemberOperatorConfigObj(
+		testconfig.MemberEnvironment("resource-env"),
+		testconfig.ToolchainCluster().HealthCheckPeriod("30s"),
+		testconfig.Autoscaler().BufferReplicas(3)))
+	require.NoError(t, err)
+	restore := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_ENVIRONMENT", "env-var-env")
+	defer restore()
+	layers := NewLayers(
+		NewDefaultsLayer(map[string]string{
+			"environment":                         "prod",
+			"toolchainCluster.healthCheckPeriod":  "10s",
+			"toolchainCluster.healthCheckTimeout": "3s",
+			"console.namespace":                   "openshift-console",
+		}),
+		configMap,
+		resource,
+		NewEnvLayer("MEMBER_OPERATOR"),
+	)
+
+	t.Run("lookup", func(t *testing.T) {
+		for key, expected := range map[string]EffectiveValue{
+			"environment":                         {Path: "environment", Value: "env-var-env", Source: SourceEnvVar, Origin: "MEMBER_OPERATOR_ENVIRONMENT"},
+			"toolchainCluster.healthCheckPeriod":  {Path: "toolchainCluster.healthCheckPeriod", Value: "30s", Source: SourceResource},
+			"toolchainCluster.healthCheckTimeout": {Path: "toolchainCluster.healthCheckTimeout", Value: "4s", Source: SourceConfigMap, Origin: "member-config"},
+			"console.namespace":                   {Path: "console.namespace", Value: "openshift-console", Source: SourceDefault},
+			"autoscaler.bufferReplicas":           {Path: "autoscaler.bufferReplicas", Value: "3", Source: SourceResource},
+		} {
+			t.Run(key, func(t *testing.T) {
+				// when
+				actual, found := layers.Lookup(key)
+
+				// then
+				require.True(t, found)
+				assert.Equal(t, expected, actual)
+			})
+		}
+
+		t.Run("unknown key", func(t *testing.T) {
+			// when
+			actual, found := layers.Lookup("unknown")
+
+			// then
+			require.False(t, found)
+			assert.Equal(t, EffectiveValue{Path: "unknown"}, actual)
+		})
+	})
+
+	t.Run("typed values", func(t *testing.T) {
+		assert.Equal(t, "env-var-env", layers.GetString("environment", "default"))
+		assert.Equal(t, "default", layers.GetString("unknown", "default"))
+		assert.False(t, layers.GetBool("webhook.deploy", true))
+		assert.True(t, layers.GetBool("environment", true)) // not a bool
+		assert.Equal(t, 3, layers.GetInt("autoscaler.bufferReplicas", 1))
+		assert.Equal(t, 1, layers.GetInt("environment", 1)) // not an int
+		assert.Equal(t, 30*time.Second, layers.GetDuration("toolchainCluster.healthCheckPeriod", time.Second))
+		assert.Equal(t, time.Second, layers.GetDuration("environment", time.Second)) // not a duration
+	})
+
+	t.Run("env vars are not mutated", func(t *testing.T) {
+		_, found := os.LookupEnv("MEMBER_OPERATOR_TOOLCHAINCLUSTER_HEALTHCHECKTIMEOUT")
+		assert.False(t, found)
+	})
+}
+
+func TestConfigMapLayer(t *testing.T) {
+	restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.MemberOperatorNs)
+	defer restore()
+
+	t.Run("configmap not found", func(t *testing.T) {
+		// when
+		layer, err := newConfigMapLayer(context.TODO(), test.NewFakeClient(t), test.MemberOperatorNs, "member-config")
+
+		// then
+		require.NoError(t, err)
+		_, found := layer.Lookup("environment")
+		assert.False(t, found)
+	})
+
+	t.Run("load with name from env var", func(t *testing.T) {
+		// given
+		ResetCache()
+		t.Cleanup(ResetCache)
+		restore := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_CONFIG_MAP_NAME", "member-config")
+		defer restore()
+		cl := test.NewFakeClient(t, createConfigMap("member-config", test.MemberOperatorNs, map[string]string{
+			"environment": "configmap-env",
+		}))
+
+		// when
+		err := LoadConfigMap("MEMBER_OPERATOR_CONFIG_MAP_NAME", cl)
+
+		// then
+		require.NoError(t, err)
+		layer := configCache.getConfigMap()
+		value, found := layer.Lookup("environment")
+		assert.True(t, found)
+		assert.Equal(t, "configmap-env", value)
+		assert.Equal(t, SourceConfigMap, layer.Source())
+		assert.Equal(t, "member-config", layer.Origin("environment"))
+	})
+
+	t.Run("name not set", func(t *testing.T) {
+		// given
+		ResetCache()
+		t.Cleanup(ResetCache)
+
+		// when
+		err := LoadConfigMap("MEMBER_OPERATOR_CONFIG_MAP_NAME", test.NewFakeClient(t))
+
+		// then
+		require.NoError(t, err)
+		_, found := configCache.getConfigMap().Lookup("environment")
+		assert.False(t, found)
+	})
+
+	t.Run("get error", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return errors.New("get error")
+		}
+
+		// when
+		_, err := newConfigMapLayer(context.TODO(), cl, test.MemberOperatorNs, "member-config")
+
+		// then
+		require.EqualError(t, err, "get error")
+	})
+}
+
+func TestOperatorLayers(t *testing.T) {
+	// given
+	ResetCache()
+	t.Cleanup(ResetCache)
+	restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.MemberOperatorNs)
+	defer restore()
+	restoreName := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_CONFIG_MAP_NAME", "member-config")
+	defer restoreName()
+	restoreEnv := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_ENVIRONMENT", "env-var-env")
+	defer restoreEnv()
+	cl := test.NewFakeClient(t, createConfigMap("member-config", test.MemberOperatorNs, map[string]string{
+		"environment":                         "configmap-env",
+		"toolchainCluster.healthCheckPeriod":  "20s",
+		"toolchainCluster.healthCheckTimeout": "4s",
+	}))
+	require.NoError(t, LoadConfigMap("MEMBER_OPERATOR_CONFIG_MAP_NAME", cl))
+	defaults := map[string]string{
+		"toolchainCluster.healthCheckPeriod":  "10s",
+		"toolchainCluster.healthCheckTimeout": "3s",
+		"console.namespace":                   "openshift-console",
+	}
+
+	t.Run("with resource", func(t *testing.T) {
+		// when
+		layers, err := NewOperatorLayers("MEMBER_OPERATOR", defaults, testconfig.NewMemberOperatorConfigObj(
+			testconfig.ToolchainCluster().HealthCheckPeriod("30s").HealthCheckTimeout("1 minute")))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, EffectiveValue{Path: "environment", Value: "env-var-env", Source: SourceEnvVar, Origin: "MEMBER_OPERATOR_ENVIRONMENT"}, layers.StringValue("environment"))
+		assert.Equal(t, EffectiveValue{Path: "toolchainCluster.healthCheckPeriod", Value: "30s", Source: SourceResource}, layers.DurationValue("toolchainCluster.healthCheckPeriod"))
+		assert.Equal(t, EffectiveValue{Path: "toolchainCluster.healthCheckTimeout", Value: "4s", Source: SourceConfigMap, Origin: "member-config, invalid value '1 minute' ignored"}, layers.DurationValue("toolchainCluster.healthCheckTimeout"))
+		assert.Equal(t, 4*time.Second, layers.GetDuration("toolchainCluster.healthCheckTimeout", 3*time.Second))
+		assert.Equal(t, EffectiveValue{Path: "console.namespace", Value: "openshift-console", Source: SourceDefault}, layers.StringValue("console.namespace"))
+		assert.Equal(t, EffectiveValue{Path: "autoscaler.bufferReplicas", Source: SourceDefault}, layers.IntValue("autoscaler.bufferReplicas"))
+		assert.Equal(t, 1, layers.GetInt("autoscaler.bufferReplicas", 1))
+	})
+
+	t.Run("without resource", func(t *testing.T) {
+		// when
+		layers, err := NewOperatorLayers("MEMBER_OPERATOR", defaults, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, EffectiveValue{Path: "toolchainCluster.healthCheckPeriod", Value: "20s", Source: SourceConfigMap, Origin: "member-config"}, layers.DurationValue("toolchainCluster.healthCheckPeriod"))
+	})
+
+	t.Run("secret value", func(t *testing.T) {
+		// given
+		layers, err := NewOperatorLayers("MEMBER_OPERATOR", defaults, testconfig.NewMemberOperatorConfigObj(
+			testconfig.MemberStatus().GitHubSecretRef("github").GitHubSecretAccessTokenKey("accessToken")))
+		require.NoError(t, err)
+
+		// when
+		value := layers.SecretValue("memberStatus.gitHubSecret.accessTokenKey", map[string]map[string]string{"github": {"accessToken": "abc123"}},
+			"memberStatus.gitHubSecret.ref", "memberStatus.gitHubSecret.accessTokenKey")
+
+		// then
+		assert.Equal(t, EffectiveValue{Path: "memberStatus.gitHubSecret.accessTokenKey", Value: RedactedValue, Source: SourceSecret, Origin: "github/accessToken"}, value)
+	})
+}
+
+func TestOperatorLayersWithLoadFromConfigMap(t *testing.T) {
+	// given
+	ResetCache()
+	t.Cleanup(ResetCache)
+	restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.MemberOperatorNs)
+	defer restore()
+	restoreName := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_CONFIG_MAP_NAME", "legacy-config")
+	defer restoreName()
+	restoreEnv := test.UnsetEnvVarAndRestore(t, "MEMBER_OPERATOR_CONSOLE_ROUTENAME")
+	defer restoreEnv()
+	cl := test.NewFakeClient(t, createConfigMap("legacy-config", test.MemberOperatorNs, map[string]string{
+		"console.routeName": "configmap-route",
+	}))
+	require.NoError(t, LoadFromConfigMap("MEMBER_OPERATOR", "MEMBER_OPERATOR_CONFIG_MAP_NAME", cl))
+
+	t.Run("the resource takes precedence over the configmap", func(t *testing.T) {
+		// when
+		layers, err := NewOperatorLayers("MEMBER_OPERATOR", nil, testconfig.NewMemberOperatorConfigObj(testconfig.Console().RouteName("resource-route")))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, EffectiveValue{Path: "console.routeName", Value: "resource-route", Source: SourceResource}, layers.StringValue("console.routeName"))
+	})
+
+	t.Run("the value of the configmap is in the ConfigMap layer", func(t *testing.T) {
+		// when
+		layers, err := NewOperatorLayers("MEMBER_OPERATOR", nil, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, EffectiveValue{Path: "console.routeName", Value: "configmap-route", Source: SourceConfigMap, Origin: "legacy-config"}, layers.StringValue("console.routeName"))
+	})
+}
+
+func TestResourceLayer(t *testing.T) {
+	t.Run("ToolchainConfig", func(t *testing.T) {
+		// given
+		cfg := testconfig.NewToolchainConfigObj(t,
+			testconfig.AutomaticApproval().Enabled(true),
+			testconfig.Notifications().Secret().Ref("notifications"))
+
+		// when
+		layer, err := NewResourceLayer(cfg)
+
+		// then
+		require.NoError(t, err)
+		value, found := layer.Lookup("host.automaticApproval.enabled")
+		assert.True(t, found)
+		assert.Equal(t, "true", value)
+		value, found = layer.Lookup("host.notifications.secret.ref")
+		assert.True(t, found)
+		assert.Equal(t, "notifications", value)
+		_, found = layer.Lookup("host.notifications.adminEmail")
+		assert.False(t, found)
+	})
+
+	t.Run("no resource", func(t *testing.T) {
+		// when
+		layer, err := NewResourceLayer((*toolchainv1alpha1.MemberOperatorConfig)(nil))
+
+		// then
+		require.NoError(t, err)
+		_, found := layer.Lookup("environment")
+		assert.False(t, found)
+	})
+}
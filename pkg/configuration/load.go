@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	errs "k8s.io/apimachinery/pkg/api/errors"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -73,36 +78,27 @@ func LoadFromSecret(resourceKey string, cl client.Client) (map[string]string, er
 // prefix: represents the operator prefix (HOST_OPERATOR/MEMBER_OPERATOR)
 // resourceKey: is the env var which contains the configmap resource name.
 // cl: is the client that should be used to retrieve the configmap.
+//
+// The data of the configmap is also loaded as the ConfigMap layer of the Layers returned by NewOperatorLayers (see
+// LoadConfigMap), and the env vars set by this function are ignored by their env layer, so that the configmap does not
+// take precedence over the configuration resource.
+//
+// Deprecated: use LoadConfigMap along with NewOperatorLayers, which do not mutate the env vars of the process
 func LoadFromConfigMap(prefix, resourceKey string, cl client.Client) error {
-	// get the configMap name
-	configMapName := getResourceName(resourceKey)
-	if configMapName == "" {
-		return nil
-	}
-
-	// get the configMap
-	namespace, err := GetWatchNamespace()
+	layer, err := loadConfigMapLayer(resourceKey, cl)
 	if err != nil {
 		return err
 	}
-
-	configMap := &v1.ConfigMap{}
-	namespacedName := types.NamespacedName{Namespace: namespace, Name: configMapName}
-	err = cl.Get(context.TODO(), namespacedName, configMap)
-	if err != nil {
-		if !errs.IsNotFound(err) {
-			return err
-		}
-		logf.Log.Info("configmap is not found")
-	}
+	configCache.setConfigMap(layer)
 
 	// get configMap data and set environment variables
-	for key, value := range configMap.Data {
+	for key, value := range layer.values {
 		configKey := createOperatorEnvVarKey(prefix, key)
 		err := os.Setenv(configKey, value)
 		if err != nil {
 			return err
 		}
+		recordEnvVarFromConfigMap(configKey, layer.origin, key)
 	}
 
 	return nil
@@ -129,16 +125,69 @@ func createOperatorEnvVarKey(prefix, key string) string {
 	return prefix + "_" + (strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(key, ".", "_"), "-", "_")))
 }
 
-// LoadSecrets lists all secrets in the provided namespace and indexes them into a map by name along with its secret data.
-// Service account secrets are skipped.
-func LoadSecrets(cl client.Client, namespace string) (map[string]map[string]string, error) {
+// SecretsOption an option to select the secrets to load with LoadSecrets
+type SecretsOption func(*secretsOptions)
+
+type secretsOptions struct {
+	names    []string
+	byName   bool
+	selector labels.Selector
+}
+
+// WithSecretNames loads only the secrets with the given names. Secrets which do not exist are ignored.
+// If no name is given, then no secret is loaded by name.
+func WithSecretNames(names ...string) SecretsOption {
+	return func(o *secretsOptions) {
+		o.names = append(o.names, names...)
+		o.byName = true
+	}
+}
+
+// WithSecretSelector loads only the secrets matching the given label selector
+func WithSecretSelector(selector labels.Selector) SecretsOption {
+	return func(o *secretsOptions) {
+		o.selector = selector
+	}
+}
+
+// LoadSecrets loads the secrets in the provided namespace and indexes them into a map by name along with its secret data.
+// By default, all the secrets in the namespace are listed, except the service account secrets. When the WithSecretNames
+// and/or WithSecretSelector options are provided, then only the secrets with the given names and/or matching the given
+// selector are loaded.
+// The reader should not be backed by the cache of the manager (use the manager's APIReader): reading a secret through
+// the cache starts an informer on all the secrets of the cluster, regardless of the given options.
+func LoadSecrets(cl client.Reader, namespace string, options ...SecretsOption) (map[string]map[string]string, error) {
 	var allSecrets = make(map[string]map[string]string)
-	secretList := &v1.SecretList{}
-	err := cl.List(context.TODO(), secretList, client.InNamespace(namespace))
-	if err != nil {
-		return allSecrets, err
+	opts := &secretsOptions{}
+	for _, apply := range options {
+		apply(opts)
+	}
+	var secrets []v1.Secret
+	if opts.byName {
+		for _, name := range opts.names {
+			secret := v1.Secret{}
+			if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, &secret); err != nil {
+				if errs.IsNotFound(err) {
+					logf.Log.Info("referenced secret not found", "namespace", namespace, "name", name)
+					continue
+				}
+				return allSecrets, err
+			}
+			secrets = append(secrets, secret)
+		}
+	}
+	if opts.selector != nil || !opts.byName {
+		listOpts := []client.ListOption{client.InNamespace(namespace)}
+		if opts.selector != nil {
+			listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: opts.selector})
+		}
+		secretList := &v1.SecretList{}
+		if err := cl.List(context.TODO(), secretList, listOpts...); err != nil {
+			return allSecrets, err
+		}
+		secrets = append(secrets, secretList.Items...)
 	}
-	for _, secret := range secretList.Items {
+	for _, secret := range secrets {
 		if _, ok := secret.Annotations["kubernetes.io/service-account.name"]; ok {
 			// skip service account secrets
 			continue
@@ -149,7 +198,50 @@ func LoadSecrets(cl client.Client, namespace string) (map[string]map[string]stri
 		}
 		allSecrets[secret.Name] = secretData
 	}
-	return allSecrets, err
+	return allSecrets, nil
+}
+
+// ReferencedSecrets returns the sorted names of all the secrets referenced by the given configuration object,
+// ie, the values of all the `ToolchainSecret` fields in the object (including in maps and slices)
+func ReferencedSecrets(obj runtime.Object) []string {
+	if obj == nil {
+		return []string{}
+	}
+	refs := sets.New[string]()
+	collectSecretRefs(reflect.ValueOf(obj), refs)
+	return sets.List(refs)
+}
+
+var toolchainSecretType = reflect.TypeOf(toolchainv1alpha1.ToolchainSecret{})
+
+func collectSecretRefs(v reflect.Value, refs sets.Set[string]) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectSecretRefs(v.Elem(), refs)
+		}
+	case reflect.Struct:
+		if v.Type() == toolchainSecretType {
+			if ref := v.Interface().(toolchainv1alpha1.ToolchainSecret).Ref; ref != nil && *ref != "" {
+				refs.Insert(*ref)
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				collectSecretRefs(v.Field(i), refs)
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectSecretRefs(iter.Value(), refs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSecretRefs(v.Index(i), refs)
+		}
+	}
 }
 
 // GetWatchNamespace returns the namespace the operator should be watching for changes
@@ -7,12 +7,16 @@ import (
 	"os"
 	"testing"
 
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -334,6 +338,129 @@ func TestLoadSecrets(t *testing.T) {
 	})
 }
 
+func TestLoadSecretsWithOptions(t *testing.T) {
+	// given
+	secret := test.CreateSecret("secret", test.MemberOperatorNs, map[string][]byte{"key-1": []byte("value-1")})
+	secret2 := test.CreateSecret("secret2", test.MemberOperatorNs, map[string][]byte{"key-2": []byte("value-2")})
+	secret2.Labels = map[string]string{"toolchain.dev.openshift.com/config": "true"}
+	tlsSecret := test.CreateSecret("tls", test.MemberOperatorNs, map[string][]byte{"tls.crt": []byte("cert")})
+	newClient := func(t *testing.T) *test.FakeClient {
+		cl := test.NewFakeClient(t, secret, secret2, tlsSecret)
+		cl.MockList = func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+			listOpts := &client.ListOptions{}
+			listOpts.ApplyOptions(opts)
+			if listOpts.LabelSelector == nil {
+				return fmt.Errorf("listing all secrets is forbidden")
+			}
+			return cl.Client.List(ctx, list, opts...)
+		}
+		return cl
+	}
+
+	t.Run("with names", func(t *testing.T) {
+		// when
+		secrets, err := LoadSecrets(newClient(t), test.MemberOperatorNs, WithSecretNames("secret", "unknown"))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"secret": {"key-1": "value-1"},
+		}, secrets)
+	})
+
+	t.Run("without names", func(t *testing.T) {
+		// when
+		secrets, err := LoadSecrets(newClient(t), test.MemberOperatorNs, WithSecretNames())
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, secrets)
+	})
+
+	t.Run("with selector", func(t *testing.T) {
+		// when
+		secrets, err := LoadSecrets(newClient(t), test.MemberOperatorNs, WithSecretSelector(labels.SelectorFromSet(labels.Set{"toolchain.dev.openshift.com/config": "true"})))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"secret2": {"key-2": "value-2"},
+		}, secrets)
+	})
+
+	t.Run("with names and selector", func(t *testing.T) {
+		// when
+		secrets, err := LoadSecrets(newClient(t), test.MemberOperatorNs,
+			WithSecretNames("secret"),
+			WithSecretSelector(labels.SelectorFromSet(labels.Set{"toolchain.dev.openshift.com/config": "true"})))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"secret":  {"key-1": "value-1"},
+			"secret2": {"key-2": "value-2"},
+		}, secrets)
+	})
+
+	t.Run("get secret error", func(t *testing.T) {
+		// given
+		cl := newClient(t)
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return fmt.Errorf("get error")
+		}
+
+		// when
+		secrets, err := LoadSecrets(cl, test.MemberOperatorNs, WithSecretNames("secret"))
+
+		// then
+		require.EqualError(t, err, "get error")
+		require.Empty(t, secrets)
+	})
+}
+
+func TestReferencedSecrets(t *testing.T) {
+	t.Run("ToolchainConfig", func(t *testing.T) {
+		// given
+		cfg := testconfig.NewToolchainConfigObj(t,
+			testconfig.Notifications().Secret().Ref("notifications"),
+			testconfig.RegistrationService().Verification().Secret().Ref("verification"),
+			testconfig.Members().Default(toolchainv1alpha1.MemberOperatorConfigSpec{
+				Webhook: toolchainv1alpha1.WebhookConfig{
+					Secret: &toolchainv1alpha1.WebhookSecret{ToolchainSecret: toolchainv1alpha1.ToolchainSecret{Ref: ptr.To("webhook")}},
+				},
+			}),
+			testconfig.Members().SpecificPerMemberCluster("member1", toolchainv1alpha1.MemberOperatorConfigSpec{
+				MemberStatus: toolchainv1alpha1.MemberStatusConfig{
+					GitHubSecret: toolchainv1alpha1.GitHubSecret{ToolchainSecret: toolchainv1alpha1.ToolchainSecret{Ref: ptr.To("github")}},
+				},
+			}))
+
+		// when
+		refs := ReferencedSecrets(cfg)
+
+		// then
+		assert.Equal(t, []string{"github", "notifications", "verification", "webhook"}, refs)
+	})
+
+	t.Run("MemberOperatorConfig", func(t *testing.T) {
+		// given
+		cfg := testconfig.NewMemberOperatorConfigObj(
+			testconfig.MemberStatus().GitHubSecretRef("github"),
+			testconfig.Webhook().WebhookSecretRef("github")) // same secret
+
+		// when
+		refs := ReferencedSecrets(cfg)
+
+		// then
+		assert.Equal(t, []string{"github"}, refs)
+	})
+
+	t.Run("no reference", func(t *testing.T) {
+		assert.Empty(t, ReferencedSecrets(testconfig.NewMemberOperatorConfigObj()))
+		assert.Empty(t, ReferencedSecrets(nil))
+	})
+}
+
 func createConfigMap(name, namespace string, data map[string]string) *v1.ConfigMap { //nolint: unparam
 	return &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -2,6 +2,7 @@ package memberoperatorconfig
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
@@ -14,14 +15,56 @@ import (
 
 var logger = logf.Log.WithName("configuration")
 
+// default values of the configuration
+const (
+	defaultIdp                       = "rhd"
+	defaultAutoscalerDeploy          = true // TODO it is temporarily changed to true but should be changed back to false after autoscaler handling is moved to memberoperatorconfig controller
+	defaultAutoscalerBufferMemory    = "50Mi"
+	defaultAutoscalerBufferCPU       = "50m"
+	defaultAutoscalerBufferReplicas  = 2 // TODO temporarily changed to e2e value, should be changed back to 1 after autoscaler handling is moved to memberoperatorconfig controller
+	defaultConsoleNamespace          = "openshift-console"
+	defaultConsoleRouteName          = "console"
+	defaultEnvironment               = "prod"
+	defaultSkipUserCreation          = false
+	defaultRefreshPeriod             = 5 * time.Second
+	defaultClusterHealthCheckPeriod  = 10 * time.Second
+	defaultClusterHealthCheckTimeout = 3 * time.Second
+	defaultWebhookDeploy             = true
+)
+
+// envVarPrefix the prefix of the env vars which override the configuration values, eg: `MEMBER_OPERATOR_ENVIRONMENT`
+const envVarPrefix = "MEMBER_OPERATOR"
+
+// defaults the default values of the configuration, indexed by their JSON path in the MemberOperatorConfigSpec
+var defaults = map[string]string{
+	"auth.idp":                            defaultIdp,
+	"autoscaler.deploy":                   strconv.FormatBool(defaultAutoscalerDeploy),
+	"autoscaler.bufferMemory":             defaultAutoscalerBufferMemory,
+	"autoscaler.bufferCPU":                defaultAutoscalerBufferCPU,
+	"autoscaler.bufferReplicas":           strconv.Itoa(defaultAutoscalerBufferReplicas),
+	"console.namespace":                   defaultConsoleNamespace,
+	"console.routeName":                   defaultConsoleRouteName,
+	"environment":                         defaultEnvironment,
+	"skipUserCreation":                    strconv.FormatBool(defaultSkipUserCreation),
+	"memberStatus.refreshPeriod":          defaultRefreshPeriod.String(),
+	"toolchainCluster.healthCheckPeriod":  defaultClusterHealthCheckPeriod.String(),
+	"toolchainCluster.healthCheckTimeout": defaultClusterHealthCheckTimeout.String(),
+	"webhook.deploy":                      strconv.FormatBool(defaultWebhookDeploy),
+}
+
+// Configuration the configuration of the member operator. The values are read from the layers, in increasing order
+// of precedence: the default values, the ConfigMap loaded with commonconfig.LoadConfigMap, the MemberOperatorConfig
+// and the `MEMBER_OPERATOR_*` env vars.
 type Configuration struct {
 	cfg     *toolchainv1alpha1.MemberOperatorConfigSpec
 	secrets map[string]map[string]string
+	l       commonconfig.Layers
 }
 
 // GetConfiguration returns a Configuration using the cache, or if the cache was not initialized
-// then retrieves the latest config using the provided client and updates the cache
-func GetConfiguration(cl client.Client) (Configuration, error) {
+// then retrieves the latest config using the provided reader (usually the manager's APIReader, see commonconfig.LoadLatest)
+// and updates the cache
+func GetConfiguration(cl client.Reader) (Configuration, error) {
 	config, secrets, err := commonconfig.GetConfig(cl, &toolchainv1alpha1.MemberOperatorConfig{})
 	if err != nil {
 		// return default config
@@ -37,15 +80,20 @@ func GetCachedConfiguration() Configuration {
 	return newConfiguration(config, secrets)
 }
 
-// ForceLoadConfiguration updates the cache using the provided client and returns the latest Configuration
-func ForceLoadConfiguration(cl client.Client) (Configuration, error) {
+// ForceLoadConfiguration updates the cache using the provided reader (usually the manager's APIReader, see
+// commonconfig.LoadLatest) and returns the latest Configuration
+func ForceLoadConfiguration(cl client.Reader) (Configuration, error) {
 	config, secrets, err := commonconfig.LoadLatest(cl, &toolchainv1alpha1.MemberOperatorConfig{})
 	if err != nil {
 		// return default config
 		logger.Error(err, "failed to force load Configuration")
 		return Configuration{cfg: &toolchainv1alpha1.MemberOperatorConfigSpec{}}, err
 	}
-	return newConfiguration(config, secrets), nil
+	cfg := newConfiguration(config, secrets)
+	if errs := cfg.Validate(); len(errs) > 0 {
+		logger.Error(errs.ToAggregate(), "invalid Configuration, default values are used for the invalid fields")
+	}
+	return cfg, nil
 }
 
 func newConfiguration(config runtime.Object, secrets map[string]map[string]string) Configuration {
@@ -60,169 +108,202 @@ func newConfiguration(config runtime.Object, secrets map[string]map[string]strin
 		logger.Error(fmt.Errorf("cache does not contain Configuration resource type"), "failed to get Configuration from resource, using default configuration")
 		return Configuration{cfg: &toolchainv1alpha1.MemberOperatorConfigSpec{}}
 	}
-	return Configuration{cfg: &membercfg.Spec, secrets: secrets}
+	cfg := Configuration{cfg: &membercfg.Spec, secrets: secrets}
+	cfg.l = cfg.layers()
+	return cfg
+}
+
+// layers returns the layers of the configuration, which are computed if the Configuration was not created
+// with newConfiguration
+func (c *Configuration) layers() commonconfig.Layers {
+	if c.l != nil {
+		return c.l
+	}
+	layers, err := commonconfig.NewOperatorLayers(envVarPrefix, defaults, &toolchainv1alpha1.MemberOperatorConfig{Spec: *c.cfg})
+	if err != nil {
+		logger.Error(err, "failed to read the MemberOperatorConfig, ignoring its values")
+	}
+	return layers
+}
+
+// Subscribe registers the given handler on the store, so that it is called with the previous and the current
+// Configuration every time the MemberOperatorConfig or the secrets change
+func Subscribe(store *commonconfig.Store, handler func(previous, current Configuration)) {
+	store.Subscribe(func(previous, current commonconfig.Snapshot) {
+		handler(newConfiguration(previous.Config, previous.Secrets), newConfiguration(current.Config, current.Secrets))
+	})
+}
+
+// NewStore returns a new Store for the MemberOperatorConfig in the given namespace. The given reader (usually the
+// manager's cache) is used to retrieve the MemberOperatorConfig, and the given API reader (usually the manager's
+// APIReader) is used to retrieve the secrets.
+func NewStore(cl, apiReader client.Reader, namespace string) *commonconfig.Store {
+	return commonconfig.NewStore(cl, apiReader, namespace, func() client.Object {
+		return &toolchainv1alpha1.MemberOperatorConfig{}
+	})
 }
 
 func (c *Configuration) Print() {
 	logger.Info("Member operator configuration variables", "MemberOperatorConfigSpec", c.cfg)
 }
 
+// Effective returns all the configuration values in effect, along with their provenance. The secret values are redacted.
+func (c *Configuration) Effective() []commonconfig.EffectiveValue {
+	layers := c.layers()
+	return []commonconfig.EffectiveValue{
+		layers.StringValue("auth.idp"),
+		layers.BoolValue("autoscaler.deploy"),
+		layers.StringValue("autoscaler.bufferMemory"),
+		layers.StringValue("autoscaler.bufferCPU"),
+		layers.IntValue("autoscaler.bufferReplicas"),
+		layers.StringValue("console.namespace"),
+		layers.StringValue("console.routeName"),
+		layers.StringValue("environment"),
+		layers.BoolValue("skipUserCreation"),
+		layers.DurationValue("memberStatus.refreshPeriod"),
+		layers.SecretValue("memberStatus.gitHubSecret.accessTokenKey", c.secrets, "memberStatus.gitHubSecret.ref", "memberStatus.gitHubSecret.accessTokenKey"),
+		layers.DurationValue("toolchainCluster.healthCheckPeriod"),
+		layers.DurationValue("toolchainCluster.healthCheckTimeout"),
+		layers.BoolValue("webhook.deploy"),
+		layers.SecretValue("webhook.secret.virtualMachineAccessKey", c.secrets, "webhook.secret.ref", "webhook.secret.virtualMachineAccessKey"),
+	}
+}
+
 func (c *Configuration) Auth() AuthConfig {
-	return AuthConfig{auth: c.cfg.Auth}
+	return AuthConfig{layers: c.layers()}
 }
 
 func (c *Configuration) Autoscaler() AutoscalerConfig {
-	return AutoscalerConfig{autoscaler: c.cfg.Autoscaler}
+	return AutoscalerConfig{layers: c.layers()}
 }
 
 func (c *Configuration) Console() ConsoleConfig {
-	return ConsoleConfig{console: c.cfg.Console}
+	return ConsoleConfig{layers: c.layers()}
 }
 
 func (c *Configuration) Environment() string {
-	return commonconfig.GetString(c.cfg.Environment, "prod")
+	return c.layers().GetString("environment", defaultEnvironment)
 }
 
 func (c *Configuration) GitHubSecret() GitHubSecret {
 	return GitHubSecret{
-		s:       c.cfg.MemberStatus.GitHubSecret,
+		layers:  c.layers(),
 		secrets: c.secrets,
 	}
 }
 
 func (c *Configuration) MemberStatus() MemberStatusConfig {
-	return MemberStatusConfig{c.cfg.MemberStatus}
+	return MemberStatusConfig{layers: c.layers()}
 }
 
 func (c *Configuration) SkipUserCreation() bool {
-	return commonconfig.GetBool(c.cfg.SkipUserCreation, false)
+	return c.layers().GetBool("skipUserCreation", defaultSkipUserCreation)
 }
 
 func (c *Configuration) ToolchainCluster() ToolchainClusterConfig {
-	return ToolchainClusterConfig{c.cfg.ToolchainCluster}
+	return ToolchainClusterConfig{layers: c.layers()}
 }
 
 func (c *Configuration) Webhook() WebhookConfig {
 	return WebhookConfig{
-		w:       c.cfg.Webhook,
+		layers:  c.layers(),
 		secrets: c.secrets,
 	}
 }
 
 type AuthConfig struct {
-	auth toolchainv1alpha1.AuthConfig
+	layers commonconfig.Layers
 }
 
 func (a AuthConfig) Idp() string {
-	return commonconfig.GetString(a.auth.Idp, "rhd")
+	return a.layers.GetString("auth.idp", defaultIdp)
 }
 
 type AutoscalerConfig struct {
-	autoscaler toolchainv1alpha1.AutoscalerConfig
+	layers commonconfig.Layers
 }
 
 func (a AutoscalerConfig) Deploy() bool {
-	return commonconfig.GetBool(a.autoscaler.Deploy, true) // TODO it is temporarily changed to true but should be changed back to false after autoscaler handling is moved to memberoperatorconfig controller
+	return a.layers.GetBool("autoscaler.deploy", defaultAutoscalerDeploy)
 }
 
 func (a AutoscalerConfig) BufferMemory() string {
-	return commonconfig.GetString(a.autoscaler.BufferMemory, "50Mi")
+	return a.layers.GetString("autoscaler.bufferMemory", defaultAutoscalerBufferMemory)
 }
 
 func (a AutoscalerConfig) BufferCPU() string {
-	return commonconfig.GetString(a.autoscaler.BufferCPU, "50m")
+	return a.layers.GetString("autoscaler.bufferCPU", defaultAutoscalerBufferCPU)
 }
 
 func (a AutoscalerConfig) BufferReplicas() int {
-	return commonconfig.GetInt(a.autoscaler.BufferReplicas, 2) // TODO temporarily changed to e2e value, should be changed back to 1 after autoscaler handling is moved to memberoperatorconfig controller
+	return a.layers.GetInt("autoscaler.bufferReplicas", defaultAutoscalerBufferReplicas)
 }
 
 type GitHubSecret struct {
-	s       toolchainv1alpha1.GitHubSecret
+	layers  commonconfig.Layers
 	secrets map[string]map[string]string
 }
 
 func (gh GitHubSecret) githubSecret(secretKey string) string {
-	secret := commonconfig.GetString(gh.s.Ref, "")
+	secret := gh.layers.GetString("memberStatus.gitHubSecret.ref", "")
 	return gh.secrets[secret][secretKey]
 }
 
 func (gh GitHubSecret) AccessTokenKey() string {
-	key := commonconfig.GetString(gh.s.AccessTokenKey, "")
+	key := gh.layers.GetString("memberStatus.gitHubSecret.accessTokenKey", "")
 	return gh.githubSecret(key)
 }
 
 type ConsoleConfig struct {
-	console toolchainv1alpha1.ConsoleConfig
+	layers commonconfig.Layers
 }
 
 func (a ConsoleConfig) Namespace() string {
-	return commonconfig.GetString(a.console.Namespace, "openshift-console")
+	return a.layers.GetString("console.namespace", defaultConsoleNamespace)
 }
 
 func (a ConsoleConfig) RouteName() string {
-	return commonconfig.GetString(a.console.RouteName, "console")
+	return a.layers.GetString("console.routeName", defaultConsoleRouteName)
 }
 
 type MemberStatusConfig struct {
-	memberStatus toolchainv1alpha1.MemberStatusConfig
+	layers commonconfig.Layers
 }
 
 func (a MemberStatusConfig) RefreshPeriod() time.Duration {
-	defaultRefreshPeriod := "5s"
-	refreshPeriod := commonconfig.GetString(a.memberStatus.RefreshPeriod, defaultRefreshPeriod)
-	d, err := time.ParseDuration(refreshPeriod)
-	if err != nil {
-		d, _ = time.ParseDuration(defaultRefreshPeriod)
-	}
-	return d
+	return a.layers.GetDuration("memberStatus.refreshPeriod", defaultRefreshPeriod)
 }
 
 type ToolchainClusterConfig struct {
-	t toolchainv1alpha1.ToolchainClusterConfig
+	layers commonconfig.Layers
 }
 
 func (a ToolchainClusterConfig) HealthCheckPeriod() time.Duration {
-	defaultClusterHealthCheckPeriod := "10s"
-	healthCheckPeriod := commonconfig.GetString(a.t.HealthCheckPeriod, defaultClusterHealthCheckPeriod)
-	d, err := time.ParseDuration(healthCheckPeriod)
-	if err != nil {
-		d, _ = time.ParseDuration(defaultClusterHealthCheckPeriod)
-	}
-	return d
+	return a.layers.GetDuration("toolchainCluster.healthCheckPeriod", defaultClusterHealthCheckPeriod)
 }
 
 func (a ToolchainClusterConfig) HealthCheckTimeout() time.Duration {
-	defaultClusterHealthCheckTimeout := "3s"
-	healthCheckTimeout := commonconfig.GetString(a.t.HealthCheckTimeout, defaultClusterHealthCheckTimeout)
-	d, err := time.ParseDuration(healthCheckTimeout)
-	if err != nil {
-		d, _ = time.ParseDuration(defaultClusterHealthCheckTimeout)
-	}
-	return d
+	return a.layers.GetDuration("toolchainCluster.healthCheckTimeout", defaultClusterHealthCheckTimeout)
 }
 
 type WebhookConfig struct {
-	w       toolchainv1alpha1.WebhookConfig
+	layers  commonconfig.Layers
 	secrets map[string]map[string]string
 }
 
 func (a WebhookConfig) webhookSecret(webhookSecretKey string) string {
-	if a.w.Secret == nil {
-		return ""
-	}
-	webhookSecret := commonconfig.GetString(a.w.Secret.Ref, "")
+	webhookSecret := a.layers.GetString("webhook.secret.ref", "")
 	return a.secrets[webhookSecret][webhookSecretKey]
 }
 
 func (a WebhookConfig) Deploy() bool {
-	return commonconfig.GetBool(a.w.Deploy, true)
+	return a.layers.GetBool("webhook.deploy", defaultWebhookDeploy)
 }
 
 func (a WebhookConfig) VMSSHKey() string {
-	if a.w.Secret == nil {
+	vmAccessKey := a.layers.GetString("webhook.secret.virtualMachineAccessKey", "")
+	if vmAccessKey == "" {
 		return ""
 	}
-	vmAccessKey := commonconfig.GetString(a.w.Secret.VirtualMachineAccessKey, "")
 	return a.webhookSecret(vmAccessKey)
 }
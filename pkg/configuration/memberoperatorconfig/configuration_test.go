@@ -1,13 +1,21 @@
 package memberoperatorconfig
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 )
 
 func TestAuth(t *testing.T) {
@@ -242,3 +250,128 @@ func TestWebhook(t *testing.T) {
 		assert.Equal(t, "ssh-rsa abc-123", memberOperatorCfg.Webhook().VMSSHKey())
 	})
 }
+
+func TestSubscribe(t *testing.T) {
+	// given
+	commonconfig.ResetCache()
+	t.Cleanup(commonconfig.ResetCache)
+	ctx := context.TODO()
+	config := testconfig.NewMemberOperatorConfigObj(testconfig.ToolchainCluster().HealthCheckPeriod("10s"))
+	cl := test.NewFakeClient(t, config)
+	informers := &informertest.FakeInformers{Scheme: cl.Scheme()}
+	store := NewStore(cl, cl, test.MemberOperatorNs)
+	require.NoError(t, store.Start(ctx, informers))
+	configInformer, err := informers.FakeInformerFor(ctx, &toolchainv1alpha1.MemberOperatorConfig{})
+	require.NoError(t, err)
+	var periods []time.Duration
+	Subscribe(store, func(previous, current Configuration) {
+		if previous.ToolchainCluster().HealthCheckPeriod() != current.ToolchainCluster().HealthCheckPeriod() {
+			periods = append(periods, current.ToolchainCluster().HealthCheckPeriod())
+		}
+	})
+	require.NoError(t, store.Reload(ctx))
+	require.Empty(t, periods) // same as the default value
+
+	// when
+	config.Spec.ToolchainCluster.HealthCheckPeriod = ptr.To("1m")
+	require.NoError(t, cl.Update(ctx, config))
+	configInformer.Update(config, config)
+
+	// then
+	assert.Equal(t, []time.Duration{time.Minute}, periods)
+	cached := GetCachedConfiguration()
+	assert.Equal(t, time.Minute, cached.ToolchainCluster().HealthCheckPeriod())
+}
+
+func TestEffective(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewMemberOperatorConfigWithReset(t)
+		memberOperatorCfg := Configuration{cfg: &cfg.Spec}
+
+		// when
+		values := memberOperatorCfg.Effective()
+
+		// then
+		for _, value := range values {
+			if value.Source != commonconfig.SourceEnvVar {
+				assert.Equal(t, commonconfig.SourceDefault, value.Source, value.Path)
+			}
+		}
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "autoscaler.bufferMemory", Value: "50Mi", Source: commonconfig.SourceDefault})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "toolchainCluster.healthCheckPeriod", Value: "10s", Source: commonconfig.SourceDefault})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "webhook.secret.virtualMachineAccessKey", Source: commonconfig.SourceDefault})
+	})
+
+	t.Run("non-default", func(t *testing.T) {
+		// given
+		cfg := commonconfig.NewMemberOperatorConfigWithReset(t,
+			testconfig.MemberEnvironment("e2e-tests"),
+			testconfig.MemberStatus().RefreshPeriod("1 minute").GitHubSecretRef("github").GitHubSecretAccessTokenKey("accessToken"),
+			testconfig.ToolchainCluster().HealthCheckPeriod("1m"),
+			testconfig.Webhook().WebhookSecretRef("webhook").VMSSHKey("vmKey"))
+		memberOperatorCfg := Configuration{cfg: &cfg.Spec, secrets: map[string]map[string]string{
+			"github": {
+				"accessToken": "abc123",
+			},
+		}}
+
+		// when
+		values := memberOperatorCfg.Effective()
+
+		// then
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "environment", Value: "e2e-tests", Source: commonconfig.SourceResource})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "memberStatus.refreshPeriod", Value: "5s", Source: commonconfig.SourceDefault, Origin: "invalid value '1 minute' ignored"})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "toolchainCluster.healthCheckPeriod", Value: "1m0s", Source: commonconfig.SourceResource})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "memberStatus.gitHubSecret.accessTokenKey", Value: commonconfig.RedactedValue, Source: commonconfig.SourceSecret, Origin: "github/accessToken"})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "webhook.secret.virtualMachineAccessKey", Source: commonconfig.SourceDefault, Origin: "secret key 'webhook/vmKey' not found"})
+		for _, value := range values {
+			assert.NotEqual(t, "abc123", value.Value, "secret value not redacted for '%s'", value.Path)
+		}
+	})
+}
+
+func TestLayers(t *testing.T) {
+	// given
+	commonconfig.ResetCache()
+	t.Cleanup(commonconfig.ResetCache)
+	restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.MemberOperatorNs)
+	defer restore()
+	restoreName := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_CONFIG_MAP_NAME", "member-config")
+	defer restoreName()
+	restoreEnv := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_ENVIRONMENT", "env-var-env")
+	defer restoreEnv()
+	cl := test.NewFakeClient(t, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "member-config", Namespace: test.MemberOperatorNs},
+		Data: map[string]string{
+			"environment":                         "configmap-env",
+			"toolchainCluster.healthCheckPeriod":  "20s",
+			"toolchainCluster.healthCheckTimeout": "4s",
+			"unrelated":                           "value",
+		},
+	})
+	require.NoError(t, commonconfig.LoadConfigMap("MEMBER_OPERATOR_CONFIG_MAP_NAME", cl))
+	config := testconfig.NewMemberOperatorConfigObj(testconfig.ToolchainCluster().HealthCheckPeriod("30s"))
+	memberOperatorCfg := newConfiguration(config, nil)
+
+	t.Run("accessors", func(t *testing.T) {
+		assert.Equal(t, "env-var-env", memberOperatorCfg.Environment())
+		assert.Equal(t, 30*time.Second, memberOperatorCfg.ToolchainCluster().HealthCheckPeriod())
+		assert.Equal(t, 4*time.Second, memberOperatorCfg.ToolchainCluster().HealthCheckTimeout())
+		assert.Equal(t, "openshift-console", memberOperatorCfg.Console().Namespace())
+	})
+
+	t.Run("effective", func(t *testing.T) {
+		// when
+		values := memberOperatorCfg.Effective()
+
+		// then
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "environment", Value: "env-var-env", Source: commonconfig.SourceEnvVar, Origin: "MEMBER_OPERATOR_ENVIRONMENT"})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "toolchainCluster.healthCheckPeriod", Value: "30s", Source: commonconfig.SourceResource})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "toolchainCluster.healthCheckTimeout", Value: "4s", Source: commonconfig.SourceConfigMap, Origin: "member-config"})
+		assert.Contains(t, values, commonconfig.EffectiveValue{Path: "console.namespace", Value: "openshift-console", Source: commonconfig.SourceDefault})
+		for _, value := range values {
+			assert.NotEqual(t, "unrelated", value.Path)
+		}
+	})
+}
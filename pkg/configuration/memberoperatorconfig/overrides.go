@@ -0,0 +1,97 @@
+package memberoperatorconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// LabelOverride overrides the MemberOperatorConfigSpec of the member clusters whose labels match the selector
+// (eg: the `cluster-role.toolchain.dev.openshift.com/gpu` label, see cluster.RoleLabel)
+type LabelOverride struct {
+	Selector labels.Selector
+	Spec     toolchainv1alpha1.MemberOperatorConfigSpec
+}
+
+// EffectiveSpec returns the MemberOperatorConfigSpec of the member cluster with the given name and labels, computed
+// from the given `members` configuration of the ToolchainConfig. The `Default` spec is overridden, field by field:
+//   - first by the specs of the given label overrides whose selector matches the labels of the cluster, in their order,
+//   - then by the spec in `SpecificPerMemberCluster` whose key is the name of the cluster.
+func EffectiveSpec(members toolchainv1alpha1.Members, clusterName string, clusterLabels map[string]string, labelOverrides ...LabelOverride) (toolchainv1alpha1.MemberOperatorConfigSpec, error) {
+	overrides := make([]toolchainv1alpha1.MemberOperatorConfigSpec, 0, len(labelOverrides)+1)
+	for _, override := range labelOverrides {
+		if override.Selector != nil && override.Selector.Matches(labels.Set(clusterLabels)) {
+			overrides = append(overrides, override.Spec)
+		}
+	}
+	if spec, found := members.SpecificPerMemberCluster[clusterName]; found {
+		overrides = append(overrides, spec)
+	}
+
+	effective, err := toMap(members.Default)
+	if err != nil {
+		return toolchainv1alpha1.MemberOperatorConfigSpec{}, err
+	}
+	for _, override := range overrides {
+		values, err := toMap(override)
+		if err != nil {
+			return toolchainv1alpha1.MemberOperatorConfigSpec{}, err
+		}
+		merge(effective, values)
+	}
+	raw, err := json.Marshal(effective)
+	if err != nil {
+		return toolchainv1alpha1.MemberOperatorConfigSpec{}, errors.Wrapf(err, "unable to compute the configuration of the '%s' member cluster", clusterName)
+	}
+	spec := toolchainv1alpha1.MemberOperatorConfigSpec{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return toolchainv1alpha1.MemberOperatorConfigSpec{}, errors.Wrapf(err, "unable to compute the configuration of the '%s' member cluster", clusterName)
+	}
+	return spec, nil
+}
+
+// EffectiveSpecForCluster returns the MemberOperatorConfigSpec of the given member cluster, computed from the `members`
+// configuration of the cached ToolchainConfig (see EffectiveSpec). Returns an empty spec if there is no cached ToolchainConfig.
+func EffectiveSpecForCluster(memberCluster *cluster.CachedToolchainCluster, labelOverrides ...LabelOverride) (toolchainv1alpha1.MemberOperatorConfigSpec, error) {
+	config, _ := commonconfig.GetCachedConfig()
+	if config == nil {
+		return toolchainv1alpha1.MemberOperatorConfigSpec{}, nil
+	}
+	toolchainConfig, ok := config.(*toolchainv1alpha1.ToolchainConfig)
+	if !ok {
+		return toolchainv1alpha1.MemberOperatorConfigSpec{}, fmt.Errorf("the cached configuration is not a ToolchainConfig but a %T", config)
+	}
+	return EffectiveSpec(toolchainConfig.Spec.Members, memberCluster.Name, memberCluster.Labels, labelOverrides...)
+}
+
+// toMap converts the given spec into a map, which does not contain the fields that are not set
+func toMap(spec toolchainv1alpha1.MemberOperatorConfigSpec) (map[string]interface{}, error) {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the member configuration")
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, errors.Wrap(err, "unable to read the member configuration")
+	}
+	return values, nil
+}
+
+// merge merges the given override values into the given values, recursively
+func merge(values, overrides map[string]interface{}) {
+	for key, override := range overrides {
+		overrideMap, isMap := override.(map[string]interface{})
+		existingMap, existingIsMap := values[key].(map[string]interface{})
+		if isMap && existingIsMap {
+			merge(existingMap, overrideMap)
+			continue
+		}
+		values[key] = override
+	}
+}
@@ -0,0 +1,198 @@
+package memberoperatorconfig
+
+import (
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+)
+
+func TestEffectiveSpec(t *testing.T) {
+	// given
+	gpuRole := cluster.RoleLabel("gpu")
+	members := toolchainv1alpha1.Members{
+		Default: toolchainv1alpha1.MemberOperatorConfigSpec{
+			Environment: ptr.To("prod"),
+			Autoscaler: toolchainv1alpha1.AutoscalerConfig{
+				BufferMemory: ptr.To("1Gi"),
+				BufferCPU:    ptr.To("500m"),
+			},
+			Webhook: toolchainv1alpha1.WebhookConfig{
+				Deploy: ptr.To(true),
+			},
+		},
+		SpecificPerMemberCluster: map[string]toolchainv1alpha1.MemberOperatorConfigSpec{
+			"member-gpu-1": {
+				Autoscaler: toolchainv1alpha1.AutoscalerConfig{
+					BufferMemory:   ptr.To("16Gi"),
+					BufferReplicas: ptr.To(5),
+				},
+			},
+		},
+	}
+	labelOverrides := []LabelOverride{
+		{
+			Selector: labels.SelectorFromSet(labels.Set{gpuRole: ""}),
+			Spec: toolchainv1alpha1.MemberOperatorConfigSpec{
+				Autoscaler: toolchainv1alpha1.AutoscalerConfig{
+					BufferMemory: ptr.To("8Gi"),
+				},
+				Webhook: toolchainv1alpha1.WebhookConfig{
+					Deploy: ptr.To(false),
+				},
+			},
+		},
+		{
+			Selector: labels.SelectorFromSet(labels.Set{"zone": "us-east"}),
+			Spec: toolchainv1alpha1.MemberOperatorConfigSpec{
+				Autoscaler: toolchainv1alpha1.AutoscalerConfig{
+					BufferMemory: ptr.To("4Gi"),
+					BufferCPU:    ptr.To("1"),
+				},
+			},
+		},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		// when
+		spec, err := EffectiveSpec(members, "member-1", map[string]string{"zone": "eu-west"}, labelOverrides...)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, members.Default, spec)
+	})
+
+	t.Run("overridden by cluster-role label", func(t *testing.T) {
+		// when
+		spec, err := EffectiveSpec(members, "member-gpu-2", map[string]string{gpuRole: ""}, labelOverrides...)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "prod", *spec.Environment)
+		assert.Equal(t, "8Gi", *spec.Autoscaler.BufferMemory)
+		assert.Equal(t, "500m", *spec.Autoscaler.BufferCPU)
+		assert.False(t, *spec.Webhook.Deploy)
+	})
+
+	t.Run("overridden by the matching labels in their order", func(t *testing.T) {
+		// when
+		spec, err := EffectiveSpec(members, "member-gpu-2", map[string]string{gpuRole: "", "zone": "us-east"}, labelOverrides...)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "4Gi", *spec.Autoscaler.BufferMemory)
+		assert.Equal(t, "1", *spec.Autoscaler.BufferCPU)
+		assert.False(t, *spec.Webhook.Deploy)
+	})
+
+	t.Run("overridden by cluster name", func(t *testing.T) {
+		// when
+		spec, err := EffectiveSpec(members, "member-gpu-1", map[string]string{gpuRole: "", "zone": "us-east"}, labelOverrides...)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "prod", *spec.Environment)
+		assert.Equal(t, "16Gi", *spec.Autoscaler.BufferMemory)
+		assert.Equal(t, "1", *spec.Autoscaler.BufferCPU)
+		assert.Equal(t, 5, *spec.Autoscaler.BufferReplicas)
+		assert.False(t, *spec.Webhook.Deploy)
+	})
+
+	t.Run("a key of SpecificPerMemberCluster is not a label selector", func(t *testing.T) {
+		// given
+		members := toolchainv1alpha1.Members{
+			SpecificPerMemberCluster: map[string]toolchainv1alpha1.MemberOperatorConfigSpec{
+				gpuRole: {Environment: ptr.To("gpu")},
+			},
+		}
+
+		// when
+		spec, err := EffectiveSpec(members, "member-gpu-2", map[string]string{gpuRole: ""})
+
+		// then
+		require.NoError(t, err)
+		assert.Nil(t, spec.Environment)
+	})
+
+	t.Run("members not modified", func(t *testing.T) {
+		// given
+		spec, err := EffectiveSpec(members, "member-gpu-1", nil)
+		require.NoError(t, err)
+
+		// when
+		*spec.Autoscaler.BufferMemory = "2Gi"
+
+		// then
+		assert.Equal(t, "1Gi", *members.Default.Autoscaler.BufferMemory)
+		assert.Equal(t, "16Gi", *members.SpecificPerMemberCluster["member-gpu-1"].Autoscaler.BufferMemory)
+	})
+}
+
+func TestEffectiveSpecForCluster(t *testing.T) {
+	// given
+	gpuRole := cluster.RoleLabel("gpu")
+	memberCluster := &cluster.CachedToolchainCluster{
+		Config: &cluster.Config{
+			Name:   "member-1",
+			Labels: map[string]string{gpuRole: ""},
+		},
+	}
+	gpuOverride := LabelOverride{
+		Selector: labels.SelectorFromSet(labels.Set{gpuRole: ""}),
+		Spec: toolchainv1alpha1.MemberOperatorConfigSpec{
+			Autoscaler: toolchainv1alpha1.AutoscalerConfig{BufferMemory: ptr.To("8Gi")},
+		},
+	}
+
+	t.Run("no cached config", func(t *testing.T) {
+		// given
+		commonconfig.ResetCache()
+
+		// when
+		spec, err := EffectiveSpecForCluster(memberCluster, gpuOverride)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, toolchainv1alpha1.MemberOperatorConfigSpec{}, spec)
+	})
+
+	t.Run("with cached config", func(t *testing.T) {
+		// given
+		config := commonconfig.NewToolchainConfigObjWithReset(t,
+			testconfig.Members().Default(toolchainv1alpha1.MemberOperatorConfigSpec{
+				Environment: ptr.To("prod"),
+				Autoscaler:  toolchainv1alpha1.AutoscalerConfig{BufferMemory: ptr.To("1Gi")},
+			}),
+			testconfig.Members().SpecificPerMemberCluster("member-1", toolchainv1alpha1.MemberOperatorConfigSpec{
+				Autoscaler: toolchainv1alpha1.AutoscalerConfig{BufferCPU: ptr.To("1")},
+			}))
+		commonconfig.UpdateConfig(config, nil)
+
+		// when
+		spec, err := EffectiveSpecForCluster(memberCluster, gpuOverride)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "prod", *spec.Environment)
+		assert.Equal(t, "8Gi", *spec.Autoscaler.BufferMemory)
+		assert.Equal(t, "1", *spec.Autoscaler.BufferCPU)
+	})
+
+	t.Run("cached config is not a ToolchainConfig", func(t *testing.T) {
+		// given
+		commonconfig.UpdateConfig(commonconfig.NewMemberOperatorConfigWithReset(t), nil)
+
+		// when
+		_, err := EffectiveSpecForCluster(memberCluster)
+
+		// then
+		require.EqualError(t, err, "the cached configuration is not a ToolchainConfig but a *v1alpha1.MemberOperatorConfig")
+	})
+}
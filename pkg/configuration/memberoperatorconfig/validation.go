@@ -0,0 +1,119 @@
+package memberoperatorconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate checks all the values of the configuration, in all its layers (see NewOperatorLayers), and returns all the
+// errors found, ie, the durations, quantities and numbers which cannot be parsed (and which the accessors silently
+// ignore in favor of the values of the lower layers or of the default values), and the secrets and keys which are
+// referenced but which do not exist. The errors about the values which are not set in the MemberOperatorConfig resource
+// mention the layer which contains the invalid value (eg, the env var).
+func (c *Configuration) Validate() field.ErrorList {
+	layers := c.layers()
+	var errs field.ErrorList
+
+	errs = append(errs, validate(layers, "autoscaler.bufferMemory", validateQuantity)...)
+	errs = append(errs, validate(layers, "autoscaler.bufferCPU", validateQuantity)...)
+	errs = append(errs, validate(layers, "autoscaler.bufferReplicas", validateReplicas)...)
+
+	errs = append(errs, validate(layers, "memberStatus.refreshPeriod", validateDuration)...)
+	errs = append(errs, c.validateSecretRef(layers, "memberStatus.gitHubSecret", "accessTokenKey")...)
+
+	errs = append(errs, validate(layers, "toolchainCluster.healthCheckPeriod", validateDuration)...)
+	errs = append(errs, validate(layers, "toolchainCluster.healthCheckTimeout", validateDuration)...)
+
+	errs = append(errs, c.validateSecretRef(layers, "webhook.secret", "virtualMachineAccessKey")...)
+	return errs
+}
+
+// validate validates the value of the given key in each layer which has one, since an invalid value is ignored in
+// favor of the value of the lower layers
+func validate(layers commonconfig.Layers, key string, validateValue func(string) error) field.ErrorList {
+	var errs field.ErrorList
+	for _, layer := range layers {
+		value, found := layer.Lookup(key)
+		if !found {
+			continue
+		}
+		if err := validateValue(value); err != nil {
+			errs = append(errs, field.Invalid(pathOf(key), value, detail(layer, key, err.Error())))
+		}
+	}
+	return errs
+}
+
+// pathOf returns the path of the field of the given key in the MemberOperatorConfig resource
+func pathOf(key string) *field.Path {
+	return field.NewPath("spec", strings.Split(key, ".")...)
+}
+
+// detail returns the given detail, along with the provenance of the value if it is not set in the MemberOperatorConfig resource
+func detail(layer commonconfig.Layer, key, detail string) string {
+	if layer.Source() == commonconfig.SourceResource {
+		return detail
+	}
+	if origin := layer.Origin(key); origin != "" {
+		return fmt.Sprintf("%s (from %s '%s')", detail, layer.Source(), origin)
+	}
+	return fmt.Sprintf("%s (from %s)", detail, layer.Source())
+}
+
+func validateDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("must be a valid duration: %s", err)
+	}
+	return nil
+}
+
+func validateQuantity(value string) error {
+	if _, err := resource.ParseQuantity(value); err != nil {
+		return fmt.Errorf("must be a valid quantity: %s", err)
+	}
+	return nil
+}
+
+func validateReplicas(value string) error {
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("must be a valid number: %s", err)
+	}
+	if replicas < 0 {
+		return fmt.Errorf("must be greater than or equal to 0")
+	}
+	return nil
+}
+
+// validateSecretRef verifies that the secret referenced by the `ref` key of the given secret config exists along with
+// the key referenced by the given key field. Nothing is verified if neither the secret nor the key is set, since the
+// feature is then not configured.
+func (c *Configuration) validateSecretRef(layers commonconfig.Layers, secretConfig, keyField string) field.ErrorList {
+	path := pathOf(secretConfig)
+	ref, refFound := layers.Lookup(secretConfig + ".ref")
+	key, keyFound := layers.Lookup(secretConfig + "." + keyField)
+	if !refFound && !keyFound {
+		return nil
+	}
+	if ref.Value == "" {
+		return field.ErrorList{field.Required(path.Child("ref"), fmt.Sprintf("must be set when '%s' is set", keyField))}
+	}
+	secret, found := c.secrets[ref.Value]
+	if !found {
+		return field.ErrorList{field.NotFound(path.Child("ref"), ref.Value)}
+	}
+	if key.Value == "" {
+		return field.ErrorList{field.Required(path.Child(keyField), "must be set when 'ref' is set")}
+	}
+	if _, found := secret[key.Value]; !found {
+		return field.ErrorList{field.NotFound(path.Child(keyField), key.Value)}
+	}
+	return nil
+}
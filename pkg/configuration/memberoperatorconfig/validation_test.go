@@ -0,0 +1,130 @@
+package memberoperatorconfig
+
+import (
+	"testing"
+
+	commonconfig "github.com/codeready-toolchain/toolchain-common/pkg/configuration"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidate(t *testing.T) {
+	secrets := map[string]map[string]string{
+		"github": {
+			"accessToken": "abc123",
+		},
+		"webhook": {
+			"vmKey": "ssh-rsa",
+		},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Run("default", func(t *testing.T) {
+			// given
+			cfg := commonconfig.NewMemberOperatorConfigWithReset(t)
+			memberOperatorCfg := Configuration{cfg: &cfg.Spec}
+
+			// when
+			errs := memberOperatorCfg.Validate()
+
+			// then
+			assert.Empty(t, errs)
+		})
+
+		t.Run("all fields set", func(t *testing.T) {
+			// given
+			cfg := commonconfig.NewMemberOperatorConfigWithReset(t,
+				testconfig.Autoscaler().BufferMemory("5Gi").BufferCPU("1000m").BufferReplicas(3),
+				testconfig.MemberStatus().RefreshPeriod("10s").GitHubSecretRef("github").GitHubSecretAccessTokenKey("accessToken"),
+				testconfig.ToolchainCluster().HealthCheckPeriod("1m").HealthCheckTimeout("5s"),
+				testconfig.Webhook().WebhookSecretRef("webhook").VMSSHKey("vmKey"))
+			memberOperatorCfg := Configuration{cfg: &cfg.Spec, secrets: secrets}
+
+			// when
+			errs := memberOperatorCfg.Validate()
+
+			// then
+			assert.Empty(t, errs)
+		})
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Run("all errors are reported", func(t *testing.T) {
+			// given
+			cfg := commonconfig.NewMemberOperatorConfigWithReset(t,
+				testconfig.Autoscaler().BufferMemory("5GiB").BufferCPU("one").BufferReplicas(-1),
+				testconfig.MemberStatus().RefreshPeriod("10").GitHubSecretRef("unknown").GitHubSecretAccessTokenKey("accessToken"),
+				testconfig.ToolchainCluster().HealthCheckPeriod("1 minute").HealthCheckTimeout("5s"),
+				testconfig.Webhook().WebhookSecretRef("webhook").VMSSHKey("unknown"))
+			memberOperatorCfg := Configuration{cfg: &cfg.Spec, secrets: secrets}
+
+			// when
+			errs := memberOperatorCfg.Validate()
+
+			// then
+			require.Len(t, errs, 7)
+			assertFieldError(t, errs[0], field.ErrorTypeInvalid, "spec.autoscaler.bufferMemory")
+			assertFieldError(t, errs[1], field.ErrorTypeInvalid, "spec.autoscaler.bufferCPU")
+			assertFieldError(t, errs[2], field.ErrorTypeInvalid, "spec.autoscaler.bufferReplicas")
+			assertFieldError(t, errs[3], field.ErrorTypeInvalid, "spec.memberStatus.refreshPeriod")
+			assertFieldError(t, errs[4], field.ErrorTypeNotFound, "spec.memberStatus.gitHubSecret.ref")
+			assertFieldError(t, errs[5], field.ErrorTypeInvalid, "spec.toolchainCluster.healthCheckPeriod")
+			assertFieldError(t, errs[6], field.ErrorTypeNotFound, "spec.webhook.secret.virtualMachineAccessKey")
+			assert.Contains(t, errs[5].Error(), `spec.toolchainCluster.healthCheckPeriod: Invalid value: "1 minute": must be a valid duration`)
+		})
+
+		t.Run("missing key", func(t *testing.T) {
+			// given
+			cfg := commonconfig.NewMemberOperatorConfigWithReset(t, testconfig.MemberStatus().GitHubSecretRef("github"))
+			memberOperatorCfg := Configuration{cfg: &cfg.Spec, secrets: secrets}
+
+			// when
+			errs := memberOperatorCfg.Validate()
+
+			// then
+			require.Len(t, errs, 1)
+			assertFieldError(t, errs[0], field.ErrorTypeRequired, "spec.memberStatus.gitHubSecret.accessTokenKey")
+		})
+
+		t.Run("missing ref", func(t *testing.T) {
+			// given
+			cfg := commonconfig.NewMemberOperatorConfigWithReset(t, testconfig.MemberStatus().GitHubSecretAccessTokenKey("accessToken"))
+			memberOperatorCfg := Configuration{cfg: &cfg.Spec, secrets: secrets}
+
+			// when
+			errs := memberOperatorCfg.Validate()
+
+			// then
+			require.Len(t, errs, 1)
+			assertFieldError(t, errs[0], field.ErrorTypeRequired, "spec.memberStatus.gitHubSecret.ref")
+		})
+
+		t.Run("invalid env var", func(t *testing.T) {
+			// given
+			restore := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_TOOLCHAINCLUSTER_HEALTHCHECKTIMEOUT", "1 minute")
+			defer restore()
+			restoreSecret := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_WEBHOOK_SECRET_REF", "unknown")
+			defer restoreSecret()
+			cfg := commonconfig.NewMemberOperatorConfigWithReset(t, testconfig.ToolchainCluster().HealthCheckTimeout("5s"))
+			memberOperatorCfg := Configuration{cfg: &cfg.Spec, secrets: secrets}
+
+			// when
+			errs := memberOperatorCfg.Validate()
+
+			// then
+			require.Len(t, errs, 2)
+			assertFieldError(t, errs[0], field.ErrorTypeInvalid, "spec.toolchainCluster.healthCheckTimeout")
+			assert.Contains(t, errs[0].Error(), `(from envVar 'MEMBER_OPERATOR_TOOLCHAINCLUSTER_HEALTHCHECKTIMEOUT')`)
+			assertFieldError(t, errs[1], field.ErrorTypeNotFound, "spec.webhook.secret.ref")
+		})
+	})
+}
+
+func assertFieldError(t *testing.T, err *field.Error, errType field.ErrorType, path string) {
+	assert.Equal(t, errType, err.Type, err.Error())
+	assert.Equal(t, path, err.Field)
+}
@@ -0,0 +1,214 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// SchemaVersionAnnotationKey the annotation with the schema version of a configuration object (ToolchainConfig or MemberOperatorConfig).
+// A configuration object without this annotation is at version 0.
+const SchemaVersionAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "config-schema-version"
+
+// MigrationFunc rewrites the given content of a configuration object and returns warnings about the changes.
+// Since the objects without the schema version annotation are migrated from version 0, the func should leave
+// the content unchanged if it is already in the new shape.
+type MigrationFunc func(content map[string]interface{}) ([]string, error)
+
+// Migration a migration of a configuration object to a given schema version
+type Migration struct {
+	// Version the schema version of the configuration object after the migration
+	Version int
+	// Description a description of the migration, for logging purpose
+	Description string
+	Migrate     MigrationFunc
+}
+
+var migrations = struct {
+	sync.RWMutex
+	byKind map[string][]Migration
+}{byKind: map[string][]Migration{}}
+
+// RegisterMigrations registers the given migrations for the configuration objects of the given kind (eg: `ToolchainConfig`).
+// Returns an error if a migration with the same version is already registered.
+func RegisterMigrations(kind string, toRegister ...Migration) error {
+	migrations.Lock()
+	defer migrations.Unlock()
+	registered := append([]Migration{}, migrations.byKind[kind]...)
+	for _, m := range toRegister {
+		if m.Version <= 0 {
+			return fmt.Errorf("invalid version of the '%s' migration for %s: %d", m.Description, kind, m.Version)
+		}
+		for _, existing := range registered {
+			if existing.Version == m.Version {
+				return fmt.Errorf("a migration to version %d is already registered for %s", m.Version, kind)
+			}
+		}
+		registered = append(registered, m)
+	}
+	sort.Slice(registered, func(i, j int) bool {
+		return registered[i].Version < registered[j].Version
+	})
+	migrations.byKind[kind] = registered
+	return nil
+}
+
+// ResetMigrations removes all the registered migrations.
+// Should be used only in tests.
+func ResetMigrations() {
+	migrations.Lock()
+	defer migrations.Unlock()
+	migrations.byKind = map[string][]Migration{}
+}
+
+// CurrentSchemaVersion returns the schema version of the configuration objects of the given kind, ie, the version
+// of the last registered migration
+func CurrentSchemaVersion(kind string) int {
+	migrations.RLock()
+	defer migrations.RUnlock()
+	registered := migrations.byKind[kind]
+	if len(registered) == 0 {
+		return 0
+	}
+	return registered[len(registered)-1].Version
+}
+
+func hasMigrations(kind string) bool {
+	return CurrentSchemaVersion(kind) > 0
+}
+
+// Migrate applies the registered migrations whose version is higher than the schema version of the given object
+// and sets the schema version annotation. Returns true if the object was migrated, along with the warnings returned
+// by the migrations.
+func Migrate(obj *unstructured.Unstructured) (bool, []string, error) {
+	kind := obj.GetKind()
+	version := 0
+	if v, found := obj.GetAnnotations()[SchemaVersionAnnotationKey]; found {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil {
+			return false, nil, errs.Wrapf(err, "invalid schema version of the '%s' %s", obj.GetName(), kind)
+		}
+	}
+
+	migrations.RLock()
+	registered := migrations.byKind[kind]
+	migrations.RUnlock()
+
+	migrated := false
+	var warnings []string
+	for _, m := range registered {
+		if m.Version <= version {
+			continue
+		}
+		w, err := m.Migrate(obj.Object)
+		if err != nil {
+			return false, nil, errs.Wrapf(err, "unable to migrate the '%s' %s to version %d (%s)", obj.GetName(), kind, m.Version, m.Description)
+		}
+		for _, warning := range w {
+			warnings = append(warnings, fmt.Sprintf("version %d (%s): %s", m.Version, m.Description, warning))
+		}
+		version = m.Version
+		migrated = true
+	}
+	if migrated {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[SchemaVersionAnnotationKey] = strconv.Itoa(version)
+		obj.SetAnnotations(annotations)
+	}
+	return migrated, warnings, nil
+}
+
+// GetMigratedConfig retrieves the configuration object with the given key and applies the registered migrations.
+// The returned object can be persisted (with an Update) when the returned bool is true, so that the migrations
+// are not applied anymore every time the configuration is loaded.
+func GetMigratedConfig(ctx context.Context, cl client.Client, key types.NamespacedName, configObj client.Object) (*unstructured.Unstructured, bool, []string, error) {
+	gvk, err := apiutil.GVKForObject(configObj, cl.Scheme())
+	if err != nil {
+		return nil, false, nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, key, obj); err != nil {
+		return nil, false, nil, err
+	}
+	migrated, warnings, err := Migrate(obj)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	return obj, migrated, warnings, nil
+}
+
+// getConfig retrieves the configuration object with the given key and applies the registered migrations (if any).
+// When some migrations are registered for the kind of the object, the object is retrieved as an unstructured object,
+// so that the migrations also see the fields which were renamed or removed from the API types, and then converted
+// to the typed object.
+func getConfig(ctx context.Context, cl client.Reader, key types.NamespacedName, configObj client.Object) error {
+	kind := reflect.Indirect(reflect.ValueOf(configObj)).Type().Name()
+	if !hasMigrations(kind) {
+		return cl.Get(ctx, key, configObj)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(toolchainv1alpha1.GroupVersion.WithKind(kind))
+	if err := cl.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	migrated, warnings, err := Migrate(obj)
+	if err != nil {
+		return err
+	}
+	if migrated {
+		cacheLog.Info("the configuration was migrated, the resource should be updated", "kind", kind, "warnings", warnings)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, configObj); err != nil {
+		return errs.Wrapf(err, "unable to convert the '%s' %s", key.Name, kind)
+	}
+	return nil
+}
+
+// MoveField returns a MigrationFunc which moves the value of the field at the given path (eg: `spec.host.oldName`)
+// to the given new path, unless the new field is already set
+func MoveField(from, to string) MigrationFunc {
+	return func(content map[string]interface{}) ([]string, error) {
+		fromPath, toPath := strings.Split(from, "."), strings.Split(to, ".")
+		value, found, err := unstructured.NestedFieldNoCopy(content, fromPath...)
+		if err != nil || !found {
+			return nil, err
+		}
+		unstructured.RemoveNestedField(content, fromPath...)
+		if _, exists, _ := unstructured.NestedFieldNoCopy(content, toPath...); exists {
+			return []string{fmt.Sprintf("field '%s' is deprecated and was removed, since '%s' is already set", from, to)}, nil
+		}
+		if err := unstructured.SetNestedField(content, value, toPath...); err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("field '%s' is deprecated and was moved to '%s'", from, to)}, nil
+	}
+}
+
+// RemoveField returns a MigrationFunc which removes the field at the given path (eg: `spec.host.oldName`)
+func RemoveField(path string) MigrationFunc {
+	return func(content map[string]interface{}) ([]string, error) {
+		fieldPath := strings.Split(path, ".")
+		if _, found, err := unstructured.NestedFieldNoCopy(content, fieldPath...); err != nil || !found {
+			return nil, err
+		}
+		unstructured.RemoveNestedField(content, fieldPath...)
+		return []string{fmt.Sprintf("field '%s' is not supported anymore and was removed", path)}, nil
+	}
+}
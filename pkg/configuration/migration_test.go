@@ -0,0 +1,316 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRegisterMigrations(t *testing.T) {
+	ResetMigrations()
+	t.Cleanup(ResetMigrations)
+
+	t.Run("ok", func(t *testing.T) {
+		// when
+		err := RegisterMigrations("ToolchainConfig",
+			Migration{Version: 2, Description: "second", Migrate: RemoveField("spec.b")},
+			Migration{Version: 1, Description: "first", Migrate: RemoveField("spec.a")})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 2, CurrentSchemaVersion("ToolchainConfig"))
+		assert.Equal(t, 0, CurrentSchemaVersion("MemberOperatorConfig"))
+	})
+
+	t.Run("duplicate version", func(t *testing.T) {
+		// when
+		err := RegisterMigrations("ToolchainConfig", Migration{Version: 2, Description: "other", Migrate: RemoveField("spec.c")})
+
+		// then
+		require.EqualError(t, err, "a migration to version 2 is already registered for ToolchainConfig")
+		assert.Equal(t, 2, CurrentSchemaVersion("ToolchainConfig"))
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		// when
+		err := RegisterMigrations("ToolchainConfig", Migration{Version: 0, Description: "zero", Migrate: RemoveField("spec.c")})
+
+		// then
+		require.EqualError(t, err, "invalid version of the 'zero' migration for ToolchainConfig: 0")
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	ResetMigrations()
+	t.Cleanup(ResetMigrations)
+	require.NoError(t, RegisterMigrations("ToolchainConfig",
+		Migration{Version: 1, Description: "move adminEmail", Migrate: MoveField("spec.host.adminEmail", "spec.host.notifications.adminEmail")},
+		Migration{Version: 2, Description: "remove legacy", Migrate: RemoveField("spec.host.legacy")}))
+
+	newObj := func(version string, spec map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "toolchain.dev.openshift.com/v1alpha1",
+			"kind":       "ToolchainConfig",
+			"metadata": map[string]interface{}{
+				"name": "config",
+			},
+			"spec": spec,
+		}}
+		if version != "" {
+			obj.SetAnnotations(map[string]string{SchemaVersionAnnotationKey: version})
+		}
+		return obj
+	}
+
+	t.Run("from version 0", func(t *testing.T) {
+		// given
+		obj := newObj("", map[string]interface{}{
+			"host": map[string]interface{}{
+				"adminEmail": "admin@acme.com",
+				"legacy":     true,
+			},
+		})
+
+		// when
+		migrated, warnings, err := Migrate(obj)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, migrated)
+		assert.Equal(t, []string{
+			"version 1 (move adminEmail): field 'spec.host.adminEmail' is deprecated and was moved to 'spec.host.notifications.adminEmail'",
+			"version 2 (remove legacy): field 'spec.host.legacy' is not supported anymore and was removed",
+		}, warnings)
+		assert.Equal(t, map[string]interface{}{
+			"host": map[string]interface{}{
+				"notifications": map[string]interface{}{
+					"adminEmail": "admin@acme.com",
+				},
+			},
+		}, obj.Object["spec"])
+		assert.Equal(t, "2", obj.GetAnnotations()[SchemaVersionAnnotationKey])
+	})
+
+	t.Run("from version 1", func(t *testing.T) {
+		// given
+		obj := newObj("1", map[string]interface{}{
+			"host": map[string]interface{}{
+				"adminEmail": "admin@acme.com", // not migrated anymore
+				"legacy":     true,
+			},
+		})
+
+		// when
+		migrated, warnings, err := Migrate(obj)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, migrated)
+		assert.Len(t, warnings, 1)
+		assert.Equal(t, map[string]interface{}{
+			"host": map[string]interface{}{
+				"adminEmail": "admin@acme.com",
+			},
+		}, obj.Object["spec"])
+	})
+
+	t.Run("already in the new shape", func(t *testing.T) {
+		// given
+		obj := newObj("", map[string]interface{}{
+			"host": map[string]interface{}{
+				"notifications": map[string]interface{}{
+					"adminEmail": "admin@acme.com",
+				},
+			},
+		})
+
+		// when
+		migrated, warnings, err := Migrate(obj)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, migrated) // the version annotation is set
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("new field already set", func(t *testing.T) {
+		// given
+		obj := newObj("", map[string]interface{}{
+			"host": map[string]interface{}{
+				"adminEmail": "old@acme.com",
+				"notifications": map[string]interface{}{
+					"adminEmail": "admin@acme.com",
+				},
+			},
+		})
+
+		// when
+		_, warnings, err := Migrate(obj)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"version 1 (move adminEmail): field 'spec.host.adminEmail' is deprecated and was removed, since 'spec.host.notifications.adminEmail' is already set"}, warnings)
+		adminEmail, _, _ := unstructured.NestedString(obj.Object, "spec", "host", "notifications", "adminEmail")
+		assert.Equal(t, "admin@acme.com", adminEmail)
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		// given
+		obj := newObj("2", map[string]interface{}{})
+
+		// when
+		migrated, warnings, err := Migrate(obj)
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, migrated)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		// given
+		obj := newObj("two", map[string]interface{}{})
+
+		// when
+		_, _, err := Migrate(obj)
+
+		// then
+		require.ErrorContains(t, err, "invalid schema version of the 'config' ToolchainConfig")
+	})
+
+	t.Run("migration failure", func(t *testing.T) {
+		// given
+		obj := newObj("", map[string]interface{}{
+			"host": "invalid",
+		})
+
+		// when
+		_, _, err := Migrate(obj)
+
+		// then
+		require.ErrorContains(t, err, "unable to migrate the 'config' ToolchainConfig to version 1 (move adminEmail)")
+	})
+}
+
+func TestLoadLatestWithMigrations(t *testing.T) {
+	ResetMigrations()
+	t.Cleanup(ResetMigrations)
+	restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.HostOperatorNs)
+	defer restore()
+	require.NoError(t, RegisterMigrations("ToolchainConfig", Migration{
+		Version:     1,
+		Description: "rename 'stage' environment",
+		Migrate: func(content map[string]interface{}) ([]string, error) {
+			env, _, _ := unstructured.NestedString(content, "spec", "host", "environment")
+			if env != "stage" {
+				return nil, nil
+			}
+			return []string{"environment 'stage' renamed to 'staging'"}, unstructured.SetNestedField(content, "staging", "spec", "host", "environment")
+		},
+	}))
+	initConfig := NewToolchainConfigObjWithReset(t, testconfig.Environment("stage"))
+	cl := test.NewFakeClient(t, initConfig)
+
+	t.Run("migrated on load", func(t *testing.T) {
+		// when
+		actual, _, err := LoadLatest(cl, &toolchainv1alpha1.ToolchainConfig{})
+
+		// then
+		require.NoError(t, err)
+		toolchaincfg, ok := actual.(*toolchainv1alpha1.ToolchainConfig)
+		require.True(t, ok)
+		assert.Equal(t, "staging", *toolchaincfg.Spec.Host.Environment)
+		assert.Equal(t, "1", toolchaincfg.Annotations[SchemaVersionAnnotationKey])
+	})
+
+	t.Run("persist the migrated object", func(t *testing.T) {
+		// given
+		key := types.NamespacedName{Namespace: test.HostOperatorNs, Name: "config"}
+
+		// when
+		obj, migrated, warnings, err := GetMigratedConfig(context.TODO(), cl, key, &toolchainv1alpha1.ToolchainConfig{})
+
+		// then
+		require.NoError(t, err)
+		require.True(t, migrated)
+		assert.Equal(t, []string{"version 1 (rename 'stage' environment): environment 'stage' renamed to 'staging'"}, warnings)
+		require.NoError(t, cl.Update(context.TODO(), obj))
+		persisted := &toolchainv1alpha1.ToolchainConfig{}
+		require.NoError(t, cl.Get(context.TODO(), key, persisted))
+		assert.Equal(t, "staging", *persisted.Spec.Host.Environment)
+		assert.Equal(t, "1", persisted.Annotations[SchemaVersionAnnotationKey])
+
+		t.Run("not migrated anymore", func(t *testing.T) {
+			// when
+			_, migrated, _, err := GetMigratedConfig(context.TODO(), cl, key, &toolchainv1alpha1.ToolchainConfig{})
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, migrated)
+		})
+	})
+
+	t.Run("renamed field reaches the typed config", func(t *testing.T) {
+		// given
+		ResetMigrations()
+		require.NoError(t, RegisterMigrations("ToolchainConfig", Migration{
+			Version:     1,
+			Description: "rename the 'env' field",
+			Migrate:     MoveField("spec.host.env", "spec.host.environment"),
+		}))
+		cl := test.NewFakeClient(t)
+		cl.MockGet = func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return fmt.Errorf("the config must be read as an unstructured object")
+			}
+			// the 'env' field is not known to the API types anymore
+			u.Object = map[string]interface{}{
+				"apiVersion": "toolchain.dev.openshift.com/v1alpha1",
+				"kind":       "ToolchainConfig",
+				"metadata":   map[string]interface{}{"name": key.Name, "namespace": key.Namespace},
+				"spec":       map[string]interface{}{"host": map[string]interface{}{"env": "staging"}},
+			}
+			return nil
+		}
+
+		// when
+		actual, _, err := LoadLatest(cl, &toolchainv1alpha1.ToolchainConfig{})
+
+		// then
+		require.NoError(t, err)
+		toolchaincfg, ok := actual.(*toolchainv1alpha1.ToolchainConfig)
+		require.True(t, ok)
+		require.NotNil(t, toolchaincfg.Spec.Host.Environment)
+		assert.Equal(t, "staging", *toolchaincfg.Spec.Host.Environment)
+		assert.Equal(t, "1", toolchaincfg.Annotations[SchemaVersionAnnotationKey])
+	})
+
+	t.Run("get error", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, initConfig)
+		cl.MockGet = mockGetError(fmt.Errorf("get error"))
+
+		// when
+		_, _, err := LoadLatest(cl, &toolchainv1alpha1.ToolchainConfig{})
+
+		// then
+		require.EqualError(t, err, "get error")
+	})
+}
+
+func mockGetError(err error) func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return func(_ context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+		return err
+	}
+}
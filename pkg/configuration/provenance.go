@@ -0,0 +1,104 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Source the provenance of a configuration value
+type Source string
+
+const (
+	// SourceDefault the value is the default value
+	SourceDefault Source = "default"
+	// SourceResource the value is set in the configuration resource (ToolchainConfig or MemberOperatorConfig)
+	SourceResource Source = "resource"
+	// SourceSecret the value is read from a key of a secret
+	SourceSecret Source = "secret"
+	// SourceEnvVar the value is read from an env var (see NewEnvLayer)
+	SourceEnvVar Source = "envVar"
+
+	// RedactedValue the value displayed instead of the actual value of the secrets
+	RedactedValue = "<redacted>"
+)
+
+// EffectiveValue a configuration value actually in effect, along with its provenance
+type EffectiveValue struct {
+	// Path the path of the field in the spec of the configuration resource, or the name of the env var
+	Path string `json:"path"`
+	// Value the value in effect (redacted for the secrets)
+	Value string `json:"value"`
+	// Source the provenance of the value
+	Source Source `json:"source"`
+	// Origin details about the provenance of the value, eg, the name and key of the secret (`<name>/<key>`),
+	// or the invalid value which was ignored in favor of the default value
+	Origin string `json:"origin,omitempty"`
+}
+
+// SecretValue returns the redacted effective value of the given key in the given secret.
+// The value is empty if the secret or the key is not set, or if the secret or the key does not exist.
+func SecretValue(path string, secrets map[string]map[string]string, secretRef, key *string) EffectiveValue {
+	secretName, secretKey := GetString(secretRef, ""), GetString(key, "")
+	if secretName == "" || secretKey == "" {
+		return EffectiveValue{Path: path, Source: SourceDefault}
+	}
+	origin := secretName + "/" + secretKey
+	if _, found := secrets[secretName][secretKey]; !found {
+		return EffectiveValue{Path: path, Source: SourceDefault, Origin: fmt.Sprintf("secret key '%s' not found", origin)}
+	}
+	return EffectiveValue{Path: path, Value: RedactedValue, Source: SourceSecret, Origin: origin}
+}
+
+// envVarsFromConfigMaps the env vars set by LoadFromConfigMap, along with their origin (`<configmap>/<key>`)
+var envVarsFromConfigMaps = struct {
+	sync.RWMutex
+	origins map[string]string
+}{origins: map[string]string{}}
+
+func recordEnvVarFromConfigMap(envVar, configMapName, key string) {
+	envVarsFromConfigMaps.Lock()
+	defer envVarsFromConfigMaps.Unlock()
+	envVarsFromConfigMaps.origins[envVar] = configMapName + "/" + key
+}
+
+// isEnvVarFromConfigMap returns true if the given env var was set by LoadFromConfigMap
+func isEnvVarFromConfigMap(envVar string) bool {
+	envVarsFromConfigMaps.RLock()
+	defer envVarsFromConfigMaps.RUnlock()
+	_, found := envVarsFromConfigMaps.origins[envVar]
+	return found
+}
+
+// EnvVarValues returns the effective values of the env vars set by LoadFromConfigMap, sorted by name
+func EnvVarValues() []EffectiveValue {
+	envVarsFromConfigMaps.RLock()
+	defer envVarsFromConfigMaps.RUnlock()
+	values := make([]EffectiveValue, 0, len(envVarsFromConfigMaps.origins))
+	for envVar, origin := range envVarsFromConfigMaps.origins {
+		values = append(values, EffectiveValue{
+			Path:   envVar,
+			Value:  os.Getenv(envVar),
+			Source: SourceEnvVar,
+			Origin: origin,
+		})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Path < values[j].Path
+	})
+	return values
+}
+
+// EffectiveConfigHandler returns an HTTP handler which writes the values returned by the given func as JSON,
+// eg, to be exposed on a debug endpoint
+func EffectiveConfigHandler(effective func() []EffectiveValue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(effective()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
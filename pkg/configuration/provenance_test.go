@@ -0,0 +1,82 @@
+package configuration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestEffectiveValues(t *testing.T) {
+	t.Run("secret", func(t *testing.T) {
+		secrets := map[string]map[string]string{
+			"notifications": {
+				"mailgunAPIKey": "abc123",
+			},
+		}
+		assert.Equal(t, EffectiveValue{Path: "a", Value: RedactedValue, Source: SourceSecret, Origin: "notifications/mailgunAPIKey"},
+			SecretValue("a", secrets, ptr.To("notifications"), ptr.To("mailgunAPIKey")))
+		assert.Equal(t, EffectiveValue{Path: "a", Source: SourceDefault, Origin: "secret key 'notifications/unknown' not found"},
+			SecretValue("a", secrets, ptr.To("notifications"), ptr.To("unknown")))
+		assert.Equal(t, EffectiveValue{Path: "a", Source: SourceDefault, Origin: "secret key 'unknown/mailgunAPIKey' not found"},
+			SecretValue("a", secrets, ptr.To("unknown"), ptr.To("mailgunAPIKey")))
+		assert.Equal(t, EffectiveValue{Path: "a", Source: SourceDefault}, SecretValue("a", secrets, nil, ptr.To("mailgunAPIKey")))
+	})
+
+	t.Run("env vars", func(t *testing.T) {
+		// given
+		restore := test.SetEnvVarAndRestore(t, "WATCH_NAMESPACE", test.MemberOperatorNs)
+		defer restore()
+		restoreName := test.SetEnvVarAndRestore(t, "MEMBER_OPERATOR_CONFIG_MAP_NAME", "provenance-config")
+		defer restoreName()
+		cl := test.NewFakeClient(t, createConfigMap("provenance-config", test.MemberOperatorNs, map[string]string{
+			"provenance.key": "value",
+		}))
+		defer os.Unsetenv("MEMBER_OPERATOR_PROVENANCE_KEY")
+
+		// when
+		err := LoadFromConfigMap("MEMBER_OPERATOR", "MEMBER_OPERATOR_CONFIG_MAP_NAME", cl)
+
+		// then
+		require.NoError(t, err)
+		assert.Contains(t, EnvVarValues(), EffectiveValue{
+			Path:   "MEMBER_OPERATOR_PROVENANCE_KEY",
+			Value:  "value",
+			Source: SourceEnvVar,
+			Origin: "provenance-config/provenance.key",
+		})
+	})
+}
+
+func TestEffectiveConfigHandler(t *testing.T) {
+	// given
+	values := []EffectiveValue{
+		{Path: "environment", Value: "prod", Source: SourceDefault},
+		{Path: "webhook.secret.virtualMachineAccessKey", Value: RedactedValue, Source: SourceSecret, Origin: "webhook/vmKey"},
+	}
+	handler := EffectiveConfigHandler(func() []EffectiveValue {
+		return values
+	})
+	resp := httptest.NewRecorder()
+
+	// when
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+
+	// then
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[
+		{"path": "environment", "value": "prod", "source": "default"},
+		{"path": "webhook.secret.virtualMachineAccessKey", "value": "<redacted>", "source": "secret", "origin": "webhook/vmKey"}
+	]`, resp.Body.String())
+	var actual []EffectiveValue
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &actual))
+	assert.Equal(t, values, actual)
+}
@@ -0,0 +1,192 @@
+package configuration
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	errs "github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var storeLog = logf.Log.WithName("configuration_store")
+
+// Snapshot the configuration object and the secrets loaded at a given time.
+// The configuration object is nil if the resource does not exist.
+type Snapshot struct {
+	Config  runtime.Object
+	Secrets map[string]map[string]string
+}
+
+// ChangeHandler a function called with the previous and the current snapshots when the configuration object or
+// the secrets changed
+type ChangeHandler func(previous, current Snapshot)
+
+// WhenChanged returns a ChangeHandler which calls the given handler only when the value extracted from the snapshots
+// changed, eg:
+//
+//	store.Subscribe(WhenChanged(healthCheckPeriod, func(previous, current time.Duration) {
+//	    // requeue
+//	}))
+func WhenChanged[V comparable](extract func(Snapshot) V, handler func(previous, current V)) ChangeHandler {
+	return func(previous, current Snapshot) {
+		previousValue, currentValue := extract(previous), extract(current)
+		if previousValue != currentValue {
+			handler(previousValue, currentValue)
+		}
+	}
+}
+
+// Store keeps the configuration object (ToolchainConfig or MemberOperatorConfig) and the secrets in sync with
+// the cluster by watching the configuration object via the informers of the manager's cache, instead of loading it
+// on demand. The secrets referenced by the configuration object are read without any cache (so that no informer
+// is started on the secrets of the namespace) every time the configuration object changes, or when Reload is called.
+// Every change is also propagated to the configuration cache, so that GetCachedConfig returns the latest values.
+type Store struct {
+	cl          client.Reader
+	apiReader   client.Reader
+	namespace   string
+	newObj      func() client.Object
+	mu          sync.RWMutex
+	current     Snapshot
+	subscribers []ChangeHandler
+}
+
+// NewStore returns a new Store for the configuration objects created by the given func (eg: `&toolchainv1alpha1.ToolchainConfig{}`)
+// in the given namespace. The given reader (usually the manager's cache) is used to retrieve the config, and the given
+// API reader (usually the manager's APIReader) is used to retrieve the secrets.
+func NewStore(cl, apiReader client.Reader, namespace string, newObj func() client.Object) *Store {
+	return &Store{
+		cl:        cl,
+		apiReader: apiReader,
+		namespace: namespace,
+		newObj:    newObj,
+	}
+}
+
+// Subscribe registers the given handler, which will be called after every change of the configuration object or secrets
+func (s *Store) Subscribe(handler ChangeHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, handler)
+}
+
+// Current returns the latest snapshot of the configuration object and secrets
+func (s *Store) Current() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.deepCopy()
+}
+
+// Start registers the Store on the informer of the configuration objects, so that the Store is reloaded every time
+// the configuration object is created, updated or deleted.
+func (s *Store) Start(ctx context.Context, informers runtimecache.Informers) error {
+	obj := s.newObj()
+	informer, err := informers.GetInformer(ctx, obj)
+	if err != nil {
+		return errs.Wrapf(err, "unable to get informer for %T", obj)
+	}
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.onEvent(ctx, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			s.onEvent(ctx, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			s.onEvent(ctx, obj)
+		},
+	}); err != nil {
+		return errs.Wrapf(err, "unable to add event handler on informer for %T", obj)
+	}
+	return nil
+}
+
+// onEvent reloads the store if the given object is the configuration object in the same namespace
+func (s *Store) onEvent(ctx context.Context, obj interface{}) {
+	o, ok := obj.(client.Object)
+	if !ok || o.GetNamespace() != s.namespace || o.GetName() != configName {
+		return
+	}
+	if err := s.Reload(ctx); err != nil {
+		storeLog.Error(err, "failed to reload the configuration")
+	}
+}
+
+// Reload retrieves the configuration object (applying the registered migrations) and the secrets, updates the configuration cache
+// and notifies the subscribers if there was any change
+func (s *Store) Reload(ctx context.Context) error {
+	configObj := s.newObj()
+	var config runtime.Object = configObj
+	if err := getConfig(ctx, s.cl, types.NamespacedName{Namespace: s.namespace, Name: configName}, configObj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		config = nil
+	}
+	secrets, err := LoadSecrets(s.apiReader, s.namespace, WithSecretNames(ReferencedSecrets(config)...))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	previous := s.current
+	current := Snapshot{Config: config, Secrets: secrets}
+	s.current = current
+	subscribers := append([]ChangeHandler{}, s.subscribers...)
+	if config != nil {
+		configCache.set(config, secrets)
+	} else {
+		configCache.reset()
+	}
+	s.mu.Unlock()
+
+	if previous.equal(current) {
+		return nil
+	}
+	storeLog.Info("configuration changed", "namespace", s.namespace)
+	for _, notify := range subscribers {
+		notify(previous.deepCopy(), current.deepCopy())
+	}
+	return nil
+}
+
+func (s Snapshot) deepCopy() Snapshot {
+	c := Snapshot{Secrets: CopyOf(s.Secrets)}
+	if s.Config != nil {
+		c.Config = s.Config.DeepCopyObject()
+	}
+	return c
+}
+
+// equal compares the specs of the configuration objects (the metadata are ignored) and the secrets
+func (s Snapshot) equal(other Snapshot) bool {
+	if !reflect.DeepEqual(spec(s.Config), spec(other.Config)) {
+		return false
+	}
+	// a nil map and an empty map are equivalent
+	return (len(s.Secrets) == 0 && len(other.Secrets) == 0) || reflect.DeepEqual(s.Secrets, other.Secrets)
+}
+
+// spec returns the value of the `Spec` field of the given object, or the object itself if there is no such field
+func spec(obj runtime.Object) interface{} {
+	if obj == nil {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName("Spec"); f.IsValid() {
+			return f.Interface()
+		}
+	}
+	return obj
+}
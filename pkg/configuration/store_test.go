@@ -0,0 +1,269 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestStore(t *testing.T) {
+	ResetCache()
+	t.Cleanup(ResetCache)
+	ctx := context.TODO()
+
+	newStore := func(t *testing.T, initObjs ...client.Object) (*Store, *test.FakeClient, *informertest.FakeInformers) {
+		cl := test.NewFakeClient(t, initObjs...)
+		informers := &informertest.FakeInformers{Scheme: cl.Scheme()}
+		store := NewStore(cl, cl, test.HostOperatorNs, func() client.Object {
+			return &toolchainv1alpha1.ToolchainConfig{}
+		})
+		require.NoError(t, store.Start(ctx, informers))
+		return store, cl, informers
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		t.Run("without config", func(t *testing.T) {
+			// given
+			store, _, _ := newStore(t)
+			var notified int
+			store.Subscribe(func(_, _ Snapshot) {
+				notified++
+			})
+
+			// when
+			err := store.Reload(ctx)
+
+			// then
+			require.NoError(t, err)
+			assert.Nil(t, store.Current().Config)
+			assert.Empty(t, store.Current().Secrets)
+			assert.Zero(t, notified)
+			config, _ := GetCachedConfig()
+			assert.Nil(t, config)
+		})
+
+		t.Run("with config and secrets", func(t *testing.T) {
+			// given
+			config := testconfig.NewToolchainConfigObj(t,
+				testconfig.AutomaticApproval().Enabled(true),
+				testconfig.Notifications().Secret().Ref("notifications"))
+			store, _, _ := newStore(t, config, newSecret("notifications", "mailgunAPIKey", "abc123"), newSecret("unreferenced", "key", "value"))
+			var previous, current Snapshot
+			store.Subscribe(func(p, c Snapshot) {
+				previous, current = p, c
+			})
+
+			// when
+			err := store.Reload(ctx)
+
+			// then
+			require.NoError(t, err)
+			assert.Nil(t, previous.Config)
+			require.IsType(t, &toolchainv1alpha1.ToolchainConfig{}, current.Config)
+			assert.Equal(t, config.Spec, current.Config.(*toolchainv1alpha1.ToolchainConfig).Spec)
+			assert.Equal(t, map[string]map[string]string{"notifications": {"mailgunAPIKey": "abc123"}}, current.Secrets)
+			assert.Equal(t, current, store.Current())
+			cached, secrets := GetCachedConfig()
+			assert.Equal(t, config.Spec, cached.(*toolchainv1alpha1.ToolchainConfig).Spec)
+			assert.Equal(t, current.Secrets, secrets)
+		})
+
+		t.Run("with legacy config", func(t *testing.T) {
+			// given
+			ResetMigrations()
+			t.Cleanup(ResetMigrations)
+			require.NoError(t, RegisterMigrations("ToolchainConfig", Migration{
+				Version:     1,
+				Description: "rename 'stage' environment",
+				Migrate: func(content map[string]interface{}) ([]string, error) {
+					if env, _, _ := unstructured.NestedString(content, "spec", "host", "environment"); env != "stage" {
+						return nil, nil
+					}
+					return nil, unstructured.SetNestedField(content, "staging", "spec", "host", "environment")
+				},
+			}))
+			store, _, _ := newStore(t, testconfig.NewToolchainConfigObj(t, testconfig.Environment("stage")))
+
+			// when
+			err := store.Reload(ctx)
+
+			// then
+			require.NoError(t, err)
+			current, ok := store.Current().Config.(*toolchainv1alpha1.ToolchainConfig)
+			require.True(t, ok)
+			assert.Equal(t, "staging", *current.Spec.Host.Environment)
+			assert.Equal(t, "1", current.Annotations[SchemaVersionAnnotationKey])
+			cached, _ := GetCachedConfig()
+			assert.Equal(t, "staging", *cached.(*toolchainv1alpha1.ToolchainConfig).Spec.Host.Environment)
+		})
+
+		t.Run("secrets are read with the API reader", func(t *testing.T) {
+			// given
+			config := testconfig.NewToolchainConfigObj(t, testconfig.Notifications().Secret().Ref("notifications"))
+			cl := test.NewFakeClient(t, config)
+			cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*v1.Secret); ok {
+					return fmt.Errorf("secrets must not be read from the cache")
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+			apiReader := test.NewFakeClient(t, newSecret("notifications", "mailgunAPIKey", "abc123"))
+			store := NewStore(cl, apiReader, test.HostOperatorNs, func() client.Object {
+				return &toolchainv1alpha1.ToolchainConfig{}
+			})
+
+			// when
+			err := store.Reload(ctx)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, map[string]map[string]string{"notifications": {"mailgunAPIKey": "abc123"}}, store.Current().Secrets)
+		})
+
+		t.Run("failure", func(t *testing.T) {
+			// given
+			store, cl, _ := newStore(t, testconfig.NewToolchainConfigObj(t, testconfig.Notifications().Secret().Ref("notifications")))
+			cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*v1.Secret); ok {
+					return fmt.Errorf("mock error")
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+
+			// when
+			err := store.Reload(ctx)
+
+			// then
+			require.EqualError(t, err, "mock error")
+		})
+	})
+
+	t.Run("events", func(t *testing.T) {
+		// given
+		config := testconfig.NewToolchainConfigObj(t,
+			testconfig.AutomaticApproval().Enabled(false),
+			testconfig.Notifications().Secret().Ref("notifications"))
+		store, cl, informers := newStore(t)
+		configInformer, err := informers.FakeInformerFor(ctx, &toolchainv1alpha1.ToolchainConfig{})
+		require.NoError(t, err)
+		var changes []Snapshot
+		store.Subscribe(func(_, current Snapshot) {
+			changes = append(changes, current)
+		})
+		var approvals [][]bool
+		store.Subscribe(WhenChanged(automaticApprovalEnabled, func(previous, current bool) {
+			approvals = append(approvals, []bool{previous, current})
+		}))
+
+		t.Run("config created", func(t *testing.T) {
+			// when
+			require.NoError(t, cl.Create(ctx, config))
+			configInformer.Add(config)
+
+			// then
+			require.Len(t, changes, 1)
+			assert.Equal(t, config.Spec, changes[0].Config.(*toolchainv1alpha1.ToolchainConfig).Spec)
+			assert.Empty(t, approvals) // disabled by default and explicitly disabled
+		})
+
+		t.Run("config updated", func(t *testing.T) {
+			// given
+			config.Spec.Host.AutomaticApproval.Enabled = ptr.To(true)
+
+			// when
+			require.NoError(t, cl.Update(ctx, config))
+			configInformer.Update(config, config)
+
+			// then
+			require.Len(t, changes, 2)
+			assert.Equal(t, [][]bool{{false, true}}, approvals)
+			cached, _ := GetCachedConfig()
+			assert.True(t, *cached.(*toolchainv1alpha1.ToolchainConfig).Spec.Host.AutomaticApproval.Enabled)
+		})
+
+		t.Run("config updated without change in the spec", func(t *testing.T) {
+			// given
+			config.Labels = map[string]string{"foo": "bar"}
+
+			// when
+			require.NoError(t, cl.Update(ctx, config))
+			configInformer.Update(config, config)
+
+			// then
+			require.Len(t, changes, 2)
+		})
+
+		t.Run("secret created", func(t *testing.T) {
+			// given
+			secret := newSecret("notifications", "mailgunAPIKey", "abc123")
+			require.NoError(t, cl.Create(ctx, secret))
+
+			// when
+			configInformer.Update(config, config)
+
+			// then
+			require.Len(t, changes, 3)
+			assert.Equal(t, map[string]map[string]string{"notifications": {"mailgunAPIKey": "abc123"}}, changes[2].Secrets)
+			assert.Len(t, approvals, 1)
+		})
+
+		t.Run("events in other namespaces are ignored", func(t *testing.T) {
+			// given
+			other := config.DeepCopy()
+			other.Namespace = test.MemberOperatorNs
+			require.NoError(t, cl.Delete(ctx, newSecret("notifications", "mailgunAPIKey", "abc123")))
+
+			// when
+			configInformer.Add(other)
+
+			// then
+			require.Len(t, changes, 3)
+		})
+
+		t.Run("config deleted", func(t *testing.T) {
+			// when
+			require.NoError(t, cl.Delete(ctx, config))
+			configInformer.Delete(config)
+
+			// then
+			require.Len(t, changes, 4)
+			assert.Nil(t, changes[3].Config)
+			assert.Equal(t, [][]bool{{false, true}, {true, false}}, approvals)
+			cached, _ := GetCachedConfig()
+			assert.Nil(t, cached)
+		})
+	})
+}
+
+func automaticApprovalEnabled(s Snapshot) bool {
+	config, ok := s.Config.(*toolchainv1alpha1.ToolchainConfig)
+	if !ok || config.Spec.Host.AutomaticApproval.Enabled == nil {
+		return false
+	}
+	return *config.Spec.Host.AutomaticApproval.Enabled
+}
+
+func newSecret(name, key, value string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+		},
+		Data: map[string][]byte{
+			key: []byte(value),
+		},
+	}
+}
@@ -0,0 +1,142 @@
+package featuretoggle
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reason the reason of a decision
+type Reason string
+
+const (
+	// ReasonWeight the decision is based on the weight of the feature
+	ReasonWeight Reason = "weight"
+	// ReasonAssigned the decision is based on the features already assigned to the object (see assignedFeatures)
+	ReasonAssigned Reason = "assigned"
+	// ReasonUnknown the feature is not configured, hence it is disabled
+	ReasonUnknown Reason = "unknown"
+)
+
+// Decision whether a feature is enabled for an object, along with the reason of the decision
+type Decision struct {
+	Feature string
+	Enabled bool
+	Reason  Reason
+}
+
+// evaluations the number of evaluations of the feature toggles, ie, of calls of Decide (including via IsEnabled,
+// and via EnabledFeatures, once per configured feature), by feature, result and reason
+var evaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sandbox_feature_toggle_evaluations_total",
+	Help: "Number of evaluations of the feature toggles (not of distinct objects), by feature, result and reason",
+}, []string{"feature", "enabled", "reason"})
+
+// RegisterMetrics registers the metrics of the feature toggle evaluations in the given registry
+// (eg: `metrics.Registry` of controller-runtime)
+func RegisterMetrics(registry prometheus.Registerer) error {
+	return registry.Register(evaluations)
+}
+
+// Evaluator decides whether the features are enabled for a given object (eg: a Space or a UserSignup).
+// The decisions are deterministic, so that all the operators make the same decision for the same object.
+type Evaluator struct {
+	toggles map[string]toolchainv1alpha1.FeatureToggle
+}
+
+// NewEvaluator returns a new Evaluator for the given feature toggles
+func NewEvaluator(toggles []toolchainv1alpha1.FeatureToggle) *Evaluator {
+	e := &Evaluator{
+		toggles: make(map[string]toolchainv1alpha1.FeatureToggle, len(toggles)),
+	}
+	for _, toggle := range toggles {
+		e.toggles[toggle.Name] = toggle
+	}
+	return e
+}
+
+// NewEvaluatorForConfig returns a new Evaluator for the feature toggles of the tiers in the given ToolchainConfig
+func NewEvaluatorForConfig(config *toolchainv1alpha1.ToolchainConfig) *Evaluator {
+	return NewEvaluator(config.Spec.Host.Tiers.FeatureToggles)
+}
+
+// IsEnabled returns true if the feature with the given name is enabled for the given object
+func (e *Evaluator) IsEnabled(feature string, obj metav1.Object) bool {
+	return e.Decide(feature, obj).Enabled
+}
+
+// Decide decides whether the feature with the given name is enabled for the given object:
+//   - if the features were already assigned to the object by the host operator, then the feature is enabled if it is
+//     one of them (see assignedFeatures),
+//   - if the feature is not configured, then it is disabled,
+//   - otherwise, the feature is enabled if the stable hash of the feature and object names, modulo 100, is lower
+//     than the weight of the feature (100 by default).
+func (e *Evaluator) Decide(feature string, obj metav1.Object) Decision {
+	decision := e.decide(feature, obj)
+	evaluations.WithLabelValues(decision.Feature, strconv.FormatBool(decision.Enabled), string(decision.Reason)).Inc()
+	return decision
+}
+
+func (e *Evaluator) decide(feature string, obj metav1.Object) Decision {
+	if features, assigned := assignedFeatures(obj); assigned {
+		return Decision{Feature: feature, Enabled: slices.Contains(features, feature), Reason: ReasonAssigned}
+	}
+	toggle, found := e.toggles[feature]
+	if !found {
+		return Decision{Feature: feature, Enabled: false, Reason: ReasonUnknown}
+	}
+	weight := uint(100)
+	if toggle.Weight != nil {
+		weight = *toggle.Weight
+	}
+	return Decision{Feature: feature, Enabled: uint(bucket(feature, obj.GetName())) < weight, Reason: ReasonWeight}
+}
+
+// EnabledFeatures returns the sorted names of all the configured features which are enabled for the given object
+func (e *Evaluator) EnabledFeatures(obj metav1.Object) []string {
+	var enabled []string
+	for name := range e.toggles {
+		if e.IsEnabled(name, obj) {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+// assignedFeatures returns the features which were assigned to the given object by the host operator, ie, the
+// comma-separated features of its FeatureToggleNameAnnotationKey annotation (eg: on a Space), or the features of the
+// status of an NSTemplateSet. Returns false if no features were assigned to the object.
+func assignedFeatures(obj metav1.Object) ([]string, bool) {
+	if value, found := obj.GetAnnotations()[toolchainv1alpha1.FeatureToggleNameAnnotationKey]; found {
+		var features []string
+		for _, feature := range strings.Split(value, ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				features = append(features, feature)
+			}
+		}
+		return features, true
+	}
+	if nsTemplateSet, ok := obj.(*toolchainv1alpha1.NSTemplateSet); ok && len(nsTemplateSet.Status.FeatureToggles) > 0 {
+		return nsTemplateSet.Status.FeatureToggles, true
+	}
+	return nil, false
+}
+
+// bucket returns the bucket (between 0 and 99) of the given object for the given feature.
+// The name of the feature is part of the hash so that the features are weighted independently of each other.
+func bucket(feature, name string) uint32 {
+	h := fnv.New32a()
+	// Ignore the errors, as this implementation cannot return one
+	_, _ = h.Write([]byte(feature))
+	_, _ = h.Write([]byte("/"))
+	_, _ = h.Write([]byte(name))
+	return h.Sum32() % 100
+}
@@ -0,0 +1,250 @@
+package featuretoggle
+
+import (
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	testconfig "github.com/codeready-toolchain/toolchain-common/pkg/test/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDecide(t *testing.T) {
+	// given
+	config := testconfig.NewToolchainConfigObj(t,
+		testconfig.Tiers().
+			FeatureToggle("feature-all", nil).
+			FeatureToggle("feature-none", weight(0)).
+			FeatureToggle("feature-half", weight(50)))
+	evaluator := NewEvaluatorForConfig(config)
+
+	t.Run("feature without weight is enabled for all", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			// when
+			decision := evaluator.Decide("feature-all", newSpace(fmt.Sprintf("space-%d", i)))
+
+			// then
+			assert.Equal(t, Decision{Feature: "feature-all", Enabled: true, Reason: ReasonWeight}, decision)
+		}
+	})
+
+	t.Run("feature with zero weight is disabled for all", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			// when
+			decision := evaluator.Decide("feature-none", newSpace(fmt.Sprintf("space-%d", i)))
+
+			// then
+			assert.Equal(t, Decision{Feature: "feature-none", Enabled: false, Reason: ReasonWeight}, decision)
+		}
+	})
+
+	t.Run("feature is enabled according to its weight", func(t *testing.T) {
+		// when
+		enabled := 0
+		for i := 0; i < 1000; i++ {
+			if evaluator.IsEnabled("feature-half", newSpace(fmt.Sprintf("space-%d", i))) {
+				enabled++
+			}
+		}
+
+		// then
+		assert.InDelta(t, 500, enabled, 75)
+	})
+
+	t.Run("decision is deterministic", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			space := newSpace(fmt.Sprintf("space-%d", i))
+			expected := evaluator.IsEnabled("feature-half", space)
+
+			// when
+			other := NewEvaluator(config.Spec.Host.Tiers.FeatureToggles)
+
+			// then
+			assert.Equal(t, expected, other.IsEnabled("feature-half", space))
+			assert.Equal(t, expected, evaluator.IsEnabled("feature-half", newUserSignup(space.Name)))
+		}
+	})
+
+	t.Run("features are weighted independently", func(t *testing.T) {
+		// given
+		evaluator := NewEvaluator([]toolchainv1alpha1.FeatureToggle{
+			{Name: "feature-1", Weight: weight(50)},
+			{Name: "feature-2", Weight: weight(50)},
+		})
+
+		// when
+		different := 0
+		for i := 0; i < 1000; i++ {
+			space := newSpace(fmt.Sprintf("space-%d", i))
+			if evaluator.IsEnabled("feature-1", space) != evaluator.IsEnabled("feature-2", space) {
+				different++
+			}
+		}
+
+		// then
+		assert.Greater(t, different, 0)
+	})
+
+	t.Run("unknown feature is disabled", func(t *testing.T) {
+		// when
+		decision := evaluator.Decide("unknown", newSpace("space"))
+
+		// then
+		assert.Equal(t, Decision{Feature: "unknown", Enabled: false, Reason: ReasonUnknown}, decision)
+	})
+
+	t.Run("assigned features", func(t *testing.T) {
+		t.Run("enables the assigned feature", func(t *testing.T) {
+			// given
+			space := newSpace("space", toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-half,feature-none")
+
+			// when
+			decision := evaluator.Decide("feature-none", space)
+
+			// then
+			assert.Equal(t, Decision{Feature: "feature-none", Enabled: true, Reason: ReasonAssigned}, decision)
+		})
+
+		t.Run("disables the feature which is not assigned", func(t *testing.T) {
+			// given
+			space := newSpace("space", toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-none")
+
+			// when
+			decision := evaluator.Decide("feature-all", space)
+
+			// then
+			assert.Equal(t, Decision{Feature: "feature-all", Enabled: false, Reason: ReasonAssigned}, decision)
+		})
+
+		t.Run("enables an unknown feature", func(t *testing.T) {
+			// given
+			space := newSpace("space", toolchainv1alpha1.FeatureToggleNameAnnotationKey, "unknown")
+
+			// when
+			decision := evaluator.Decide("unknown", space)
+
+			// then
+			assert.Equal(t, Decision{Feature: "unknown", Enabled: true, Reason: ReasonAssigned}, decision)
+		})
+
+		t.Run("from the status of the NSTemplateSet", func(t *testing.T) {
+			// given
+			nsTemplateSet := &toolchainv1alpha1.NSTemplateSet{
+				ObjectMeta: newObjectMeta("space"),
+				Status: toolchainv1alpha1.NSTemplateSetStatus{
+					FeatureToggles: []string{"feature-none"},
+				},
+			}
+
+			// when
+			enabled := evaluator.Decide("feature-none", nsTemplateSet)
+			disabled := evaluator.Decide("feature-all", nsTemplateSet)
+
+			// then
+			assert.Equal(t, Decision{Feature: "feature-none", Enabled: true, Reason: ReasonAssigned}, enabled)
+			assert.Equal(t, Decision{Feature: "feature-all", Enabled: false, Reason: ReasonAssigned}, disabled)
+		})
+
+		t.Run("NSTemplateSet without features in its status", func(t *testing.T) {
+			// when
+			decision := evaluator.Decide("feature-all", &toolchainv1alpha1.NSTemplateSet{ObjectMeta: newObjectMeta("space")})
+
+			// then
+			assert.Equal(t, Decision{Feature: "feature-all", Enabled: true, Reason: ReasonWeight}, decision)
+		})
+	})
+}
+
+func TestEnabledFeatures(t *testing.T) {
+	// given
+	evaluator := NewEvaluator([]toolchainv1alpha1.FeatureToggle{
+		{Name: "feature-b"},
+		{Name: "feature-a", Weight: weight(100)},
+		{Name: "feature-c", Weight: weight(0)},
+	})
+
+	t.Run("from the weights", func(t *testing.T) {
+		// when
+		enabled := evaluator.EnabledFeatures(newSpace("space"))
+
+		// then
+		assert.Equal(t, []string{"feature-a", "feature-b"}, enabled)
+	})
+
+	t.Run("from the assigned features", func(t *testing.T) {
+		// given
+		space := newSpace("space", toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-c, feature-a")
+
+		// when
+		enabled := evaluator.EnabledFeatures(space)
+
+		// then
+		assert.Equal(t, []string{"feature-a", "feature-c"}, enabled)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	// given
+	evaluations.Reset()
+	evaluator := NewEvaluator([]toolchainv1alpha1.FeatureToggle{
+		{Name: "feature-all"},
+		{Name: "feature-none", Weight: weight(0)},
+	})
+
+	// when
+	evaluator.IsEnabled("feature-all", newSpace("space-1"))
+	evaluator.IsEnabled("feature-all", newSpace("space-2"))
+	evaluator.IsEnabled("feature-none", newSpace("space-1"))
+	evaluator.IsEnabled("feature-none", newSpace("space-2", toolchainv1alpha1.FeatureToggleNameAnnotationKey, "feature-none"))
+	evaluator.IsEnabled("unknown", newSpace("space-1"))
+
+	// then
+	metrics.AssertMetricsCounterEquals(t, 2, evaluations.WithLabelValues("feature-all", "true", "weight"))
+	metrics.AssertMetricsCounterEquals(t, 1, evaluations.WithLabelValues("feature-none", "false", "weight"))
+	metrics.AssertMetricsCounterEquals(t, 1, evaluations.WithLabelValues("feature-none", "true", "assigned"))
+	metrics.AssertMetricsCounterEquals(t, 1, evaluations.WithLabelValues("unknown", "false", "unknown"))
+
+	t.Run("register metrics", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+
+		// when
+		err := RegisterMetrics(registry)
+
+		// then
+		require.NoError(t, err)
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		require.Len(t, families, 1)
+		assert.Equal(t, "sandbox_feature_toggle_evaluations_total", families[0].GetName())
+	})
+}
+
+func weight(value uint) *uint {
+	return &value
+}
+
+func newSpace(name string, annotations ...string) *toolchainv1alpha1.Space {
+	return &toolchainv1alpha1.Space{ObjectMeta: newObjectMeta(name, annotations...)}
+}
+
+func newUserSignup(name string, annotations ...string) *toolchainv1alpha1.UserSignup {
+	return &toolchainv1alpha1.UserSignup{ObjectMeta: newObjectMeta(name, annotations...)}
+}
+
+// newObjectMeta returns the ObjectMeta with the given name and the given annotations, as key/value pairs
+func newObjectMeta(name string, annotations ...string) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{Name: name, Namespace: "toolchain-host-operator"}
+	if len(annotations) > 0 {
+		meta.Annotations = map[string]string{}
+		for i := 0; i+1 < len(annotations); i += 2 {
+			meta.Annotations[annotations[i]] = annotations[i+1]
+		}
+	}
+	return meta
+}
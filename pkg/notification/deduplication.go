@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeduplicationKeyAnnotationKey the annotation which contains the deduplication key of a notification
+const DeduplicationKeyAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-deduplication-key"
+
+type deduplication struct {
+	discriminator string
+	ttl           time.Duration
+}
+
+// key returns the deduplication key of the given notification, ie, `<user name>/<notification type>/<discriminator>`,
+// followed by `/<recipient>` if a recipient is given. If the notification has no user name (eg, it is sent to the
+// recipients of a space without user context), the key starts with `space:<space name>` instead of the user name.
+func (d *deduplication) key(notification *toolchainv1alpha1.Notification, space, recipient string) (string, error) {
+	owner := notification.Spec.Context["UserName"]
+	if owner == "" && space != "" {
+		owner = "space:" + space
+	}
+	notificationType := notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey]
+	if owner == "" || notificationType == "" {
+		return "", errors.New("the deduplication of the notification requires a user name (or a space) and a notification type")
+	}
+	key := fmt.Sprintf("%s/%s/%s", owner, notificationType, d.discriminator)
+	if recipient != "" {
+		key += "/" + recipient
+	}
+	return key, nil
+}
+
+// setName sets the name of the notification derived from its deduplication key, ie, `<user name>-<notification type>-<hash of the key>`
+// (or `<space name>-<notification type>-<hash of the key>` if the notification has no user name)
+func (d *deduplication) setName(notification *toolchainv1alpha1.Notification, space, recipient string) error {
+	key, err := d.key(notification, space, recipient)
+	if err != nil {
+		return err
+	}
+	owner := notification.Spec.Context["UserName"]
+	if owner == "" {
+		owner = space
+	}
+	hash := sha256.Sum256([]byte(key))
+	notification.Name = fmt.Sprintf("%s-%s-%s", owner, notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey], hex.EncodeToString(hash[:])[:10])
+	notification.GenerateName = ""
+	if notification.Annotations == nil {
+		notification.Annotations = map[string]string{}
+	}
+	notification.Annotations[DeduplicationKeyAnnotationKey] = key
+	return nil
+}
+
+// create creates the given notification, unless a notification with the same name (hence the same deduplication key)
+// already exists and is not older than the ttl, in which case the existing notification is returned
+func (d *deduplication) create(ctx context.Context, cl client.Client, notification *toolchainv1alpha1.Notification) (*toolchainv1alpha1.Notification, error) {
+	existing := &toolchainv1alpha1.Notification{}
+	err := cl.Get(ctx, client.ObjectKeyFromObject(notification), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrapf(err, "unable to get the existing notification '%s'", notification.Name)
+	case d.ttl <= 0 || time.Since(existing.CreationTimestamp.Time) < d.ttl:
+		return existing, nil
+	default:
+		if err := cl.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "unable to delete the expired notification '%s'", notification.Name)
+		}
+	}
+
+	if err := cl.Create(ctx, notification); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		// created concurrently
+		if err := cl.Get(ctx, client.ObjectKeyFromObject(notification), existing); err != nil {
+			return nil, errors.Wrapf(err, "unable to get the existing notification '%s'", notification.Name)
+		}
+		return existing, nil
+	}
+	return notification, nil
+}
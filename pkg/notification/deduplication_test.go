@@ -0,0 +1,201 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testusersignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestNotificationBuilderWithDeduplication(t *testing.T) {
+	// given
+	userSignup := testusersignup.NewUserSignup()
+	userSignup.Status.CompliantUsername = "foo"
+	create := func(cl runtimeclient.Client, discriminator string, ttl time.Duration, subject string) (*toolchainv1alpha1.Notification, error) {
+		return NewExtendedNotificationBuilder(cl, test.HostOperatorNs).
+			WithUserContext(userSignup).
+			WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivated).
+			WithSubjectAndContent(subject, "content").
+			WithDeduplication(discriminator, ttl).
+			Create(context.TODO(), "foo@redhat.com")
+	}
+	listNotifications := func(t *testing.T, cl runtimeclient.Client) []toolchainv1alpha1.Notification {
+		notifications := &toolchainv1alpha1.NotificationList{}
+		require.NoError(t, cl.List(context.TODO(), notifications))
+		return notifications.Items
+	}
+
+	t.Run("name is derived from the deduplication key", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		notification, err := create(cl, "2024-01-31", 0, "first")
+
+		// then
+		require.NoError(t, err)
+		assert.Regexp(t, "^foo-deactivated-[0-9a-f]{10}$", notification.Name)
+		assert.Empty(t, notification.GenerateName)
+		assert.Equal(t, "foo/deactivated/2024-01-31", notification.Annotations[DeduplicationKeyAnnotationKey])
+		assert.Len(t, listNotifications(t, cl), 1)
+	})
+
+	t.Run("existing notification is returned", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		first, err := create(cl, "2024-01-31", 0, "first")
+		require.NoError(t, err)
+
+		// when
+		second, err := create(cl, "2024-01-31", 0, "second")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, first.Name, second.Name)
+		assert.Equal(t, "first", second.Spec.Subject)
+		assert.Len(t, listNotifications(t, cl), 1)
+	})
+
+	t.Run("different discriminators", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		first, err := create(cl, "2024-01-31", 0, "first")
+		require.NoError(t, err)
+
+		// when
+		second, err := create(cl, "2024-02-29", 0, "second")
+
+		// then
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Name, second.Name)
+		assert.Len(t, listNotifications(t, cl), 2)
+	})
+
+	t.Run("with ttl", func(t *testing.T) {
+		existing := func(t *testing.T, age time.Duration) *toolchainv1alpha1.Notification {
+			notification, err := NewExtendedNotificationBuilder(nil, test.HostOperatorNs).
+				WithUserContext(userSignup).
+				WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivated).
+				WithSubjectAndContent("existing", "content").
+				WithDeduplication("2024-01-31", time.Hour).
+				Preview("foo@redhat.com")
+			require.NoError(t, err)
+			notification.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+			return notification
+		}
+
+		t.Run("existing notification within the ttl is returned", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, existing(t, 10*time.Minute))
+
+			// when
+			notification, err := create(cl, "2024-01-31", time.Hour, "new")
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "existing", notification.Spec.Subject)
+			assert.Len(t, listNotifications(t, cl), 1)
+		})
+
+		t.Run("existing notification older than the ttl is replaced", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, existing(t, 2*time.Hour))
+
+			// when
+			notification, err := create(cl, "2024-01-31", time.Hour, "new")
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "new", notification.Spec.Subject)
+			notifications := listNotifications(t, cl)
+			require.Len(t, notifications, 1)
+			assert.Equal(t, "new", notifications[0].Spec.Subject)
+		})
+
+		t.Run("failed to delete the expired notification", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, existing(t, 2*time.Hour))
+			cl.MockDelete = func(context.Context, runtimeclient.Object, ...runtimeclient.DeleteOption) error {
+				return fmt.Errorf("mock error")
+			}
+
+			// when
+			_, err := create(cl, "2024-01-31", time.Hour, "new")
+
+			// then
+			require.ErrorContains(t, err, "unable to delete the expired notification 'foo-deactivated-")
+		})
+	})
+
+	t.Run("notification created concurrently", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+			concurrent := obj.DeepCopyObject().(*toolchainv1alpha1.Notification)
+			concurrent.Spec.Subject = "concurrent"
+			require.NoError(t, cl.Client.Create(ctx, concurrent, opts...))
+			return apierrors.NewAlreadyExists(schema.GroupResource{}, obj.GetName())
+		}
+
+		// when
+		notification, err := create(cl, "2024-01-31", 0, "new")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "concurrent", notification.Spec.Subject)
+		assert.Len(t, listNotifications(t, cl), 1)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("missing notification type", func(t *testing.T) {
+			// when
+			_, err := NewExtendedNotificationBuilder(test.NewFakeClient(t), test.HostOperatorNs).
+				WithUserContext(userSignup).
+				WithDeduplication("2024-01-31", 0).
+				Create(context.TODO(), "foo@redhat.com")
+
+			// then
+			require.EqualError(t, err, "the deduplication of the notification requires a user name (or a space) and a notification type")
+		})
+
+		t.Run("failed to get the existing notification", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockGet = func(context.Context, runtimeclient.ObjectKey, runtimeclient.Object, ...runtimeclient.GetOption) error {
+				return fmt.Errorf("mock error")
+			}
+
+			// when
+			_, err := create(cl, "2024-01-31", 0, "new")
+
+			// then
+			require.ErrorContains(t, err, "unable to get the existing notification 'foo-deactivated-")
+			require.ErrorContains(t, err, "mock error")
+		})
+
+		t.Run("failed to create the notification", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockCreate = func(context.Context, runtimeclient.Object, ...runtimeclient.CreateOption) error {
+				return fmt.Errorf("mock error")
+			}
+
+			// when
+			_, err := create(cl, "2024-01-31", 0, "new")
+
+			// then
+			require.EqualError(t, err, "mock error")
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+)
+
+const (
+	// LocaleAnnotationKey the annotation of the UserSignup which contains the locale of the user, eg, `fr` or `pt-BR`.
+	// The identity claims do not contain any locale, so the annotation is the only source of the locale of the user.
+	LocaleAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "locale"
+	// OptOutAnnotationKey the annotation of the UserSignup which contains the comma-separated types of the notifications
+	// that the user opted out of, or `*` for all the notifications
+	OptOutAnnotationKey = toolchainv1alpha1.LabelKeyPrefix + "notification-opt-out"
+
+	// DefaultLocale the locale used when the locale of the user is not set or not supported
+	DefaultLocale = "en"
+)
+
+// Locale the formats of the values of the notification context in a given language
+type Locale struct {
+	Tag         string
+	DateLayout  string
+	DayFormat   string
+	DaysFormat  string
+	HourFormat  string
+	HoursFormat string
+	Unlimited   string
+}
+
+var locales = map[string]Locale{
+	"en": {Tag: "en", DateLayout: "January 2, 2006", DayFormat: "%d day", DaysFormat: "%d days", HourFormat: "%d hour", HoursFormat: "%d hours", Unlimited: "(unlimited)"},
+	"de": {Tag: "de", DateLayout: "02.01.2006", DayFormat: "%d Tag", DaysFormat: "%d Tage", HourFormat: "%d Stunde", HoursFormat: "%d Stunden", Unlimited: "(unbegrenzt)"},
+	"es": {Tag: "es", DateLayout: "02/01/2006", DayFormat: "%d día", DaysFormat: "%d días", HourFormat: "%d hora", HoursFormat: "%d horas", Unlimited: "(ilimitado)"},
+	"fr": {Tag: "fr", DateLayout: "02/01/2006", DayFormat: "%d jour", DaysFormat: "%d jours", HourFormat: "%d heure", HoursFormat: "%d heures", Unlimited: "(illimité)"},
+	"ja": {Tag: "ja", DateLayout: "2006年1月2日", DayFormat: "%d日", DaysFormat: "%d日", HourFormat: "%d時間", HoursFormat: "%d時間", Unlimited: "(無制限)"},
+	"pt": {Tag: "pt", DateLayout: "02/01/2006", DayFormat: "%d dia", DaysFormat: "%d dias", HourFormat: "%d hora", HoursFormat: "%d horas", Unlimited: "(ilimitado)"},
+}
+
+// normalizeLocale returns the given locale in lower case, with `-` as the separator of the subtags, eg, `pt_BR` -> `pt-br`
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localeCandidates returns the locales to look up for the given locale, from the most to the least specific,
+// eg, `pt-br` and `pt` for `pt_BR`
+func localeCandidates(locale string) []string {
+	locale = normalizeLocale(locale)
+	if locale == "" {
+		return nil
+	}
+	candidates := []string{locale}
+	if primary, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, primary)
+	}
+	return candidates
+}
+
+// GetLocale returns the Locale matching the given locale (or its primary language), or the default one
+func GetLocale(locale string) Locale {
+	for _, candidate := range localeCandidates(locale) {
+		if l, found := locales[candidate]; found {
+			return l
+		}
+	}
+	return locales[DefaultLocale]
+}
+
+// FormatDate formats the date of the given time
+func (l Locale) FormatDate(t time.Time) string {
+	return t.Format(l.DateLayout)
+}
+
+// FormatDuration formats the given duration in days, or in hours if it is less than a day
+func (l Locale) FormatDuration(d time.Duration) string {
+	if d < 24*time.Hour {
+		return plural(int(d.Round(time.Hour)/time.Hour), l.HourFormat, l.HoursFormat)
+	}
+	return plural(int(d.Round(24*time.Hour)/(24*time.Hour)), l.DayFormat, l.DaysFormat)
+}
+
+// FormatDays formats the given number of days, which is unlimited if not positive
+func (l Locale) FormatDays(days int) string {
+	if days <= 0 {
+		return l.Unlimited
+	}
+	return plural(days, l.DayFormat, l.DaysFormat)
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf(singular, n)
+	}
+	return fmt.Sprintf(plural, n)
+}
+
+// optedOut returns true if the given comma-separated notification types (see OptOutAnnotationKey) contain the given type
+func optedOut(optOuts, notificationType string) bool {
+	for _, t := range strings.Split(optOuts, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || (t != "" && t == notificationType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLocale(t *testing.T) {
+	for locale, expected := range map[string]string{
+		"":        "en",
+		"en":      "en",
+		"fr":      "fr",
+		"FR":      "fr",
+		"pt_BR":   "pt",
+		"de-AT":   "de",
+		"unknown": "en",
+	} {
+		t.Run("locale "+locale, func(t *testing.T) {
+			assert.Equal(t, expected, GetLocale(locale).Tag)
+		})
+	}
+}
+
+func TestLocaleFormats(t *testing.T) {
+	// given
+	date := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	en := GetLocale("en")
+	fr := GetLocale("fr")
+	ja := GetLocale("ja")
+
+	t.Run("dates", func(t *testing.T) {
+		assert.Equal(t, "March 5, 2024", en.FormatDate(date))
+		assert.Equal(t, "05/03/2024", fr.FormatDate(date))
+		assert.Equal(t, "2024年3月5日", ja.FormatDate(date))
+	})
+
+	t.Run("durations", func(t *testing.T) {
+		assert.Equal(t, "1 hour", en.FormatDuration(70*time.Minute))
+		assert.Equal(t, "5 hours", en.FormatDuration(5*time.Hour))
+		assert.Equal(t, "1 day", en.FormatDuration(25*time.Hour))
+		assert.Equal(t, "30 jours", fr.FormatDuration(30*24*time.Hour))
+		assert.Equal(t, "3日", ja.FormatDuration(3*24*time.Hour))
+	})
+
+	t.Run("days", func(t *testing.T) {
+		assert.Equal(t, "1 day", en.FormatDays(1))
+		assert.Equal(t, "15 days", en.FormatDays(15))
+		assert.Equal(t, "(unlimited)", en.FormatDays(0))
+		assert.Equal(t, "(illimité)", fr.FormatDays(-1))
+	})
+}
+
+func TestOptedOut(t *testing.T) {
+	assert.False(t, optedOut("", "deactivated"))
+	assert.False(t, optedOut("provisioned, idled", "deactivated"))
+	assert.True(t, optedOut("provisioned, deactivated", "deactivated"))
+	assert.True(t, optedOut("*", "deactivated"))
+	assert.True(t, optedOut("*", ""))
+	assert.False(t, optedOut("provisioned,", ""))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/mail"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -27,10 +28,55 @@ type Builder interface {
 	WithKeysAndValues(keysAndValues map[string]string) Builder
 	WithUserContext(userSignup *toolchainv1alpha1.UserSignup) Builder
 	WithUserTierContext(userTier *toolchainv1alpha1.UserTier) Builder
+	// Create creates the notification for the given recipient. Returns an OptedOutError (see IsOptedOut) if the user
+	// opted out of the type of the notification, in which case no notification is created.
 	Create(ctx context.Context, recipient string) (*toolchainv1alpha1.Notification, error)
 }
 
+// ExtendedBuilder a Builder with the options of the rendered/localized templates, the deduplication and the multiple
+// recipients. It is a separate interface so that the other implementations of Builder are not broken.
+type ExtendedBuilder interface {
+	WithName(name string) ExtendedBuilder
+	WithTemplate(template string) ExtendedBuilder
+	WithSubjectAndContent(subject, content string) ExtendedBuilder
+	WithNotificationType(notificationType string) ExtendedBuilder
+	WithControllerReference(owner v1.Object, scheme *runtime.Scheme) ExtendedBuilder
+	WithKeysAndValues(keysAndValues map[string]string) ExtendedBuilder
+	WithUserContext(userSignup *toolchainv1alpha1.UserSignup) ExtendedBuilder
+	WithUserTierContext(userTier *toolchainv1alpha1.UserTier) ExtendedBuilder
+	WithRenderedTemplate(template *Template) ExtendedBuilder
+	WithLocalizedTemplate(templates *Templates, name string) ExtendedBuilder
+	WithLocale(locale string) ExtendedBuilder
+	WithDateContext(key string, date time.Time) ExtendedBuilder
+	WithDurationContext(key string, duration time.Duration) ExtendedBuilder
+	WithDeduplication(discriminator string, ttl time.Duration) ExtendedBuilder
+	WithRecipients(role RecipientRole, addresses ...string) ExtendedBuilder
+	WithSpaceRecipients(space *toolchainv1alpha1.Space, roles map[string]RecipientRole) ExtendedBuilder
+	// Create creates the notification for the given recipient. Returns an OptedOutError (see IsOptedOut) if the user
+	// opted out of the type of the notification, in which case no notification is created.
+	Create(ctx context.Context, recipient string) (*toolchainv1alpha1.Notification, error)
+	// CreateForRecipients creates a notification per recipient set with WithRecipients and WithSpaceRecipients.
+	// No notification is created, and no error is returned, if the user opted out of the type of the notification.
+	CreateForRecipients(ctx context.Context) ([]*toolchainv1alpha1.Notification, error)
+	// Preview returns the notification which would be created for the given recipient, without creating it.
+	// Returns an OptedOutError if the user opted out of the type of the notification.
+	Preview(recipient string) (*toolchainv1alpha1.Notification, error)
+	// OptedOut returns true if the user opted out of the type of the notification
+	OptedOut() (bool, error)
+}
+
 func NewNotificationBuilder(client client.Client, namespace string) Builder {
+	return newNotificationBuilder(client, namespace)
+}
+
+// NewExtendedNotificationBuilder returns a new ExtendedBuilder
+func NewExtendedNotificationBuilder(client client.Client, namespace string) ExtendedBuilder {
+	return &extendedNotificationBuilder{
+		notificationBuilderImpl: newNotificationBuilder(client, namespace),
+	}
+}
+
+func newNotificationBuilder(client client.Client, namespace string) *notificationBuilderImpl {
 	return &notificationBuilderImpl{
 		client:    client,
 		namespace: namespace,
@@ -42,14 +88,162 @@ type notificationBuilderImpl struct {
 	client    client.Client
 	namespace string
 	options   []Option
+	template  func(locale string) (*Template, error)
+	dedup     *deduplication
+	// locale the locale set with WithLocale, which takes precedence over the locale of the user
+	locale     string
+	userLocale string
+	optOuts    string
+	// recipients the recipients set with WithRecipients, completed with the ones returned by the resolvers
+	recipients         Recipients
+	recipientResolvers []recipientsResolver
+	// space the name of the space set with WithSpaceRecipients, used in the deduplication key when there is no user context
+	space string
+}
+
+// extendedNotificationBuilder the implementation of ExtendedBuilder, on top of the implementation of Builder
+type extendedNotificationBuilder struct {
+	*notificationBuilderImpl
+}
+
+// OptedOutError returned when the recipient opted out of the type of the notification
+type OptedOutError struct {
+	NotificationType string
+}
+
+func (e *OptedOutError) Error() string {
+	return fmt.Sprintf("the recipient opted out of the '%s' notifications", e.NotificationType)
+}
+
+// IsOptedOut returns true if the given error is an OptedOutError
+func IsOptedOut(err error) bool {
+	optedOutErr := &OptedOutError{}
+	return errors.As(err, &optedOutErr)
 }
 
+// Create creates the notification for the given recipient. Returns an OptedOutError if the user opted out of the
+// type of the notification (see OptOutAnnotationKey and OptedOut), so that a nil notification is never returned
+// without error.
 func (b *notificationBuilderImpl) Create(ctx context.Context, recipient string) (*toolchainv1alpha1.Notification, error) {
+	notification, err := b.Preview(recipient)
+	if err != nil {
+		return nil, err
+	}
+	return b.create(ctx, notification)
+}
+
+// CreateForRecipients creates a notification for each recipient set with WithRecipients and WithSpaceRecipients,
+// whatever its role: the notification API has a single recipient field, which the notification service sends the
+// notification to, so that the `cc` recipients would not be notified if they were only listed in the notification of
+// the `to` recipients. The notifications are thus created for the `to`, then `cc`, then `bcc` recipients, an address
+// being only kept with its most visible role. Each notification has its own deduplication key (see WithDeduplication).
+// No notification is created if the user of the context opted out of the type of the notification (see OptedOut).
+func (b *extendedNotificationBuilder) CreateForRecipients(ctx context.Context) ([]*toolchainv1alpha1.Notification, error) {
+	if optedOut, err := b.OptedOut(); err != nil || optedOut {
+		return nil, err
+	}
+	recipients, err := b.resolveRecipients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var notifications []*toolchainv1alpha1.Notification
+	for _, addresses := range [][]string{recipients.To, recipients.CC, recipients.BCC} {
+		for _, recipient := range addresses {
+			notification, err := b.preview(recipient)
+			if err != nil {
+				return notifications, err
+			}
+			if b.dedup != nil {
+				if err := b.dedup.setName(notification, b.space, recipient); err != nil {
+					return notifications, err
+				}
+			}
+			if notification, err = b.create(ctx, notification); err != nil {
+				return notifications, err
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
 
+func (b *notificationBuilderImpl) resolveRecipients(ctx context.Context) (Recipients, error) {
+	recipients := Recipients{
+		To:  append([]string{}, b.recipients.To...),
+		CC:  append([]string{}, b.recipients.CC...),
+		BCC: append([]string{}, b.recipients.BCC...),
+	}
+	if len(b.recipientResolvers) > 0 {
+		notification, err := b.build("")
+		if err != nil {
+			return recipients, err
+		}
+		for _, resolve := range b.recipientResolvers {
+			resolved, err := resolve(ctx, notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey])
+			if err != nil {
+				return recipients, err
+			}
+			recipients.To = append(recipients.To, resolved.To...)
+			recipients.CC = append(recipients.CC, resolved.CC...)
+			recipients.BCC = append(recipients.BCC, resolved.BCC...)
+		}
+	}
+	return recipients.normalize()
+}
+
+func (b *notificationBuilderImpl) create(ctx context.Context, notification *toolchainv1alpha1.Notification) (*toolchainv1alpha1.Notification, error) {
+	if b.dedup != nil {
+		return b.dedup.create(ctx, b.client, notification)
+	}
+	return notification, b.client.Create(ctx, notification)
+}
+
+// Preview returns the notification which would be created for the given recipient, without creating it.
+// Returns an OptedOutError if the user opted out of the type of the notification (see OptOutAnnotationKey), since
+// no notification would be created.
+func (b *notificationBuilderImpl) Preview(recipient string) (*toolchainv1alpha1.Notification, error) {
+	notification, err := b.preview(recipient)
+	if err != nil {
+		return nil, err
+	}
+	if b.optedOut(notification) {
+		return nil, &OptedOutError{NotificationType: notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey]}
+	}
+	return notification, nil
+}
+
+// OptedOut returns true if the user of the context opted out of the type of the notification (see OptOutAnnotationKey),
+// in which case Create and CreateForRecipients do not create any notification
+func (b *extendedNotificationBuilder) OptedOut() (bool, error) {
+	notification, err := b.build("")
+	if err != nil {
+		return false, err
+	}
+	return b.optedOut(notification), nil
+}
+
+// preview returns the notification for the given recipient, regardless of the opt-out of the user
+func (b *notificationBuilderImpl) preview(recipient string) (*toolchainv1alpha1.Notification, error) {
 	if list, err := mail.ParseAddressList(recipient); err != nil || len(list) == 0 {
 		return nil, errors.Wrap(err, fmt.Sprintf("The specified recipient [%s] is not a valid email address", recipient))
 	}
+	return b.build(recipient)
+}
 
+// optedOut returns true if the user opted out of the type of the given notification
+func (b *notificationBuilderImpl) optedOut(notification *toolchainv1alpha1.Notification) bool {
+	return optedOut(b.optOuts, notification.Labels[toolchainv1alpha1.NotificationTypeLabelKey])
+}
+
+// effectiveLocale returns the locale set with WithLocale, or the locale of the user
+func (b *notificationBuilderImpl) effectiveLocale() string {
+	if b.locale != "" {
+		return b.locale
+	}
+	return b.userLocale
+}
+
+func (b *notificationBuilderImpl) build(recipient string) (*toolchainv1alpha1.Notification, error) {
 	notification := &toolchainv1alpha1.Notification{
 		ObjectMeta: v1.ObjectMeta{
 			Namespace: b.namespace,
@@ -68,9 +262,32 @@ func (b *notificationBuilderImpl) Create(ctx context.Context, recipient string)
 		}
 	}
 
+	if locale := b.effectiveLocale(); locale != "" {
+		notification.Spec.Context["Locale"] = GetLocale(locale).Tag
+	}
+
+	if b.template != nil {
+		template, err := b.template(b.effectiveLocale())
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := template.Render(notification.Spec.Context)
+		if err != nil {
+			return nil, err
+		}
+		notification.Spec.Subject = rendered.Subject
+		notification.Spec.Content = rendered.HTMLContent
+	}
+
+	if b.dedup != nil {
+		if err := b.dedup.setName(notification, b.space, ""); err != nil {
+			return nil, err
+		}
+	}
+
 	generateName(notification)
 
-	return notification, b.client.Create(ctx, notification)
+	return notification, nil
 }
 
 func generateName(notification *toolchainv1alpha1.Notification) {
@@ -104,6 +321,85 @@ func (b *notificationBuilderImpl) WithTemplate(template string) Builder {
 	return b
 }
 
+// WithRenderedTemplate sets the subject and content of the notification rendered from the given template, with the
+// context set by the other options, regardless of their order
+func (b *extendedNotificationBuilder) WithRenderedTemplate(template *Template) ExtendedBuilder {
+	b.template = func(string) (*Template, error) {
+		return template, nil
+	}
+	return b
+}
+
+// WithLocalizedTemplate is similar to WithRenderedTemplate, with the variant of the template with the given name
+// which matches the locale of the notification (see Templates.GetLocalized)
+func (b *extendedNotificationBuilder) WithLocalizedTemplate(templates *Templates, name string) ExtendedBuilder {
+	b.template = func(locale string) (*Template, error) {
+		template, found := templates.GetLocalized(name, locale)
+		if !found {
+			return nil, errors.Errorf("the notification template '%s' was not found", name)
+		}
+		return template, nil
+	}
+	return b
+}
+
+// WithRecipients adds the given addresses with the given role to the recipients of the notifications created by CreateForRecipients
+func (b *extendedNotificationBuilder) WithRecipients(role RecipientRole, addresses ...string) ExtendedBuilder {
+	b.recipients.Add(role, addresses...)
+	return b
+}
+
+// WithSpaceRecipients adds the users bound to the given space (including via its parent spaces) to the recipients of
+// the notifications created by CreateForRecipients, with the recipient role of their space role in the given map.
+// The users whose space role is not in the map, or who opted out of the type of the notification, are ignored.
+func (b *extendedNotificationBuilder) WithSpaceRecipients(space *toolchainv1alpha1.Space, roles map[string]RecipientRole) ExtendedBuilder {
+	b.space = space.Name
+	b.recipientResolvers = append(b.recipientResolvers, func(ctx context.Context, notificationType string) (Recipients, error) {
+		return spaceRecipients(ctx, b.client, b.namespace, space, roles, notificationType)
+	})
+	return b
+}
+
+// WithLocale sets the locale of the notification, regardless of the locale of the user (see LocaleAnnotationKey)
+func (b *extendedNotificationBuilder) WithLocale(locale string) ExtendedBuilder {
+	b.locale = locale
+	return b
+}
+
+// WithDateContext sets the date of the given time under the given key of the context, formatted per the locale of the notification
+func (b *extendedNotificationBuilder) WithDateContext(key string, date time.Time) ExtendedBuilder {
+	b.options = append(b.options, func(n *toolchainv1alpha1.Notification) error {
+		n.Spec.Context[key] = GetLocale(b.effectiveLocale()).FormatDate(date)
+		return nil
+	})
+	return b
+}
+
+// WithDurationContext sets the given duration under the given key of the context, formatted per the locale of the notification
+func (b *extendedNotificationBuilder) WithDurationContext(key string, duration time.Duration) ExtendedBuilder {
+	b.options = append(b.options, func(n *toolchainv1alpha1.Notification) error {
+		n.Spec.Context[key] = GetLocale(b.effectiveLocale()).FormatDuration(duration)
+		return nil
+	})
+	return b
+}
+
+// WithDeduplication derives the name of the notification from a stable key made of the user name, the notification type
+// and the given discriminator (eg, a deactivation date), so that an existing notification with the same key is returned
+// by Create instead of creating a duplicate. If the ttl is positive, an existing notification older than the ttl is
+// replaced by a new one.
+// Without user context (see WithUserContext), the key is made of the name of the space set with WithSpaceRecipients
+// instead of the user name. Since the existing notification is the only record of the deduplication, a notification
+// is no longer deduplicated once it is deleted by the host operator, ie, after the `durationBeforeNotificationDeletion`
+// of the ToolchainConfig: a ttl longer than this duration has thus the same effect as this duration.
+func (b *extendedNotificationBuilder) WithDeduplication(discriminator string, ttl time.Duration) ExtendedBuilder {
+	b.dedup = &deduplication{
+		discriminator: discriminator,
+		ttl:           ttl,
+	}
+	return b
+}
+
 func (b *notificationBuilderImpl) WithSubjectAndContent(subject, content string) Builder {
 	b.options = append(b.options, func(n *toolchainv1alpha1.Notification) error {
 		n.Spec.Subject = subject
@@ -138,7 +434,11 @@ func (b *notificationBuilderImpl) WithKeysAndValues(keysAndValues map[string]str
 	return b
 }
 
+// WithUserContext sets the context of the user, as well as the locale of the user and the types of the notifications
+// that the user opted out of (see LocaleAnnotationKey and OptOutAnnotationKey)
 func (b *notificationBuilderImpl) WithUserContext(userSignup *toolchainv1alpha1.UserSignup) Builder {
+	b.userLocale = userSignup.Annotations[LocaleAnnotationKey]
+	b.optOuts = userSignup.Annotations[OptOutAnnotationKey]
 	b.options = append(b.options, func(n *toolchainv1alpha1.Notification) error {
 
 		n.Spec.Context["Sub"] = userSignup.Spec.IdentityClaims.Sub
@@ -161,12 +461,54 @@ func (b *notificationBuilderImpl) WithUserContext(userSignup *toolchainv1alpha1.
 
 func (b *notificationBuilderImpl) WithUserTierContext(userTier *toolchainv1alpha1.UserTier) Builder {
 	b.options = append(b.options, func(n *toolchainv1alpha1.Notification) error {
+		locale := GetLocale(b.effectiveLocale())
 		if userTier.Spec.DeactivationTimeoutDays > 0 {
 			n.Spec.Context["DeactivationTimeoutDays"] = strconv.Itoa(userTier.Spec.DeactivationTimeoutDays)
 		} else {
-			n.Spec.Context["DeactivationTimeoutDays"] = "(unlimited)"
+			n.Spec.Context["DeactivationTimeoutDays"] = locale.Unlimited
 		}
+		n.Spec.Context["DeactivationTimeout"] = locale.FormatDays(userTier.Spec.DeactivationTimeoutDays)
 		return nil
 	})
 	return b
 }
+
+func (b *extendedNotificationBuilder) WithName(name string) ExtendedBuilder {
+	b.notificationBuilderImpl.WithName(name)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithTemplate(template string) ExtendedBuilder {
+	b.notificationBuilderImpl.WithTemplate(template)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithSubjectAndContent(subject, content string) ExtendedBuilder {
+	b.notificationBuilderImpl.WithSubjectAndContent(subject, content)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithNotificationType(notificationType string) ExtendedBuilder {
+	b.notificationBuilderImpl.WithNotificationType(notificationType)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithControllerReference(owner v1.Object, scheme *runtime.Scheme) ExtendedBuilder {
+	b.notificationBuilderImpl.WithControllerReference(owner, scheme)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithKeysAndValues(keysAndValues map[string]string) ExtendedBuilder {
+	b.notificationBuilderImpl.WithKeysAndValues(keysAndValues)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithUserContext(userSignup *toolchainv1alpha1.UserSignup) ExtendedBuilder {
+	b.notificationBuilderImpl.WithUserContext(userSignup)
+	return b
+}
+
+func (b *extendedNotificationBuilder) WithUserTierContext(userTier *toolchainv1alpha1.UserTier) ExtendedBuilder {
+	b.notificationBuilderImpl.WithUserTierContext(userTier)
+	return b
+}
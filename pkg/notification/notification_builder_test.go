@@ -2,8 +2,10 @@ package notification
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
@@ -218,3 +220,182 @@ func TestNotificationBuilder(t *testing.T) {
 		assert.False(t, strings.HasPrefix(notification.Name, "-"))
 	})
 }
+
+func TestNotificationBuilderWithLocale(t *testing.T) {
+	// given
+	templates, err := LoadTemplates(&testTemplatesFS, "testdata/templates")
+	require.NoError(t, err)
+	userTier := &toolchainv1alpha1.UserTier{
+		Spec: toolchainv1alpha1.UserTierSpec{
+			DeactivationTimeoutDays: 30,
+		},
+	}
+	newUserSignup := func(annotations map[string]string) *toolchainv1alpha1.UserSignup {
+		userSignup := testusersignup.NewUserSignup()
+		userSignup.Status.CompliantUsername = "foo"
+		for k, v := range annotations {
+			userSignup.Annotations[k] = v
+		}
+		return userSignup
+	}
+	deactivationDate := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	t.Run("locale of the user", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{LocaleAnnotationKey: "fr_FR"})
+
+		// when
+		notification, err := NewExtendedNotificationBuilder(test.NewFakeClient(t), test.HostOperatorNs).
+			WithLocalizedTemplate(templates, "userdeactivated").
+			WithUserTierContext(userTier).
+			WithDateContext("DeactivationDate", deactivationDate).
+			WithDurationContext("GracePeriod", 3*24*time.Hour).
+			WithUserContext(userSignup).
+			Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "fr", notification.Spec.Context["Locale"])
+		assert.Equal(t, "30", notification.Spec.Context["DeactivationTimeoutDays"])
+		assert.Equal(t, "30 jours", notification.Spec.Context["DeactivationTimeout"])
+		assert.Equal(t, "05/03/2024", notification.Spec.Context["DeactivationDate"])
+		assert.Equal(t, "3 jours", notification.Spec.Context["GracePeriod"])
+		assert.Equal(t, "Votre compte foo a été désactivé\n", notification.Spec.Subject)
+		assert.Equal(t, "<p>Bonjour Foo Bar,</p>\n<p>Votre compte chez Red Hat a été désactivé après 30 jours.</p>\n", notification.Spec.Content)
+	})
+
+	t.Run("explicit locale takes precedence", func(t *testing.T) {
+		// given
+		userSignup := newUserSignup(map[string]string{LocaleAnnotationKey: "fr"})
+
+		// when
+		notification, err := NewExtendedNotificationBuilder(test.NewFakeClient(t), test.HostOperatorNs).
+			WithLocale("de").
+			WithUserContext(userSignup).
+			WithUserTierContext(&toolchainv1alpha1.UserTier{}).
+			WithDateContext("DeactivationDate", deactivationDate).
+			Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "de", notification.Spec.Context["Locale"])
+		assert.Equal(t, "(unbegrenzt)", notification.Spec.Context["DeactivationTimeoutDays"])
+		assert.Equal(t, "05.03.2024", notification.Spec.Context["DeactivationDate"])
+	})
+
+	t.Run("default locale", func(t *testing.T) {
+		// when
+		notification, err := NewExtendedNotificationBuilder(test.NewFakeClient(t), test.HostOperatorNs).
+			WithLocalizedTemplate(templates, "userdeactivated").
+			WithUserContext(newUserSignup(nil)).
+			WithUserTierContext(userTier).
+			WithDateContext("DeactivationDate", deactivationDate).
+			Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.NotContains(t, notification.Spec.Context, "Locale")
+		assert.Equal(t, "30 days", notification.Spec.Context["DeactivationTimeout"])
+		assert.Equal(t, "March 5, 2024", notification.Spec.Context["DeactivationDate"])
+		assert.Equal(t, "Your account foo has been deactivated\n", notification.Spec.Subject)
+	})
+
+	t.Run("unknown localized template", func(t *testing.T) {
+		// when
+		_, err := NewExtendedNotificationBuilder(test.NewFakeClient(t), test.HostOperatorNs).
+			WithLocalizedTemplate(templates, "unknown").
+			WithUserContext(newUserSignup(nil)).
+			Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.EqualError(t, err, "the notification template 'unknown' was not found")
+	})
+}
+
+func TestNotificationBuilderWithOptOut(t *testing.T) {
+	// given
+	userSignup := testusersignup.NewUserSignup()
+	userSignup.Status.CompliantUsername = "foo"
+	userSignup.Annotations[OptOutAnnotationKey] = "idled, deactivating"
+
+	t.Run("opted out", func(t *testing.T) {
+		newBuilder := func(cl runtimeclient.Client) ExtendedBuilder {
+			return NewExtendedNotificationBuilder(cl, test.HostOperatorNs).
+				WithUserContext(userSignup).
+				WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivating)
+		}
+
+		t.Run("create returns an opted out error", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+
+			// when
+			notification, err := newBuilder(cl).Create(context.TODO(), "foo@redhat.com")
+
+			// then
+			require.EqualError(t, err, "the recipient opted out of the 'deactivating' notifications")
+			assert.True(t, IsOptedOut(err))
+			assert.Nil(t, notification)
+			notifications := &toolchainv1alpha1.NotificationList{}
+			require.NoError(t, cl.List(context.TODO(), notifications))
+			assert.Empty(t, notifications.Items)
+		})
+
+		t.Run("create for recipients does not fail", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+
+			// when
+			notifications, err := newBuilder(cl).
+				WithRecipients(RecipientTo, "foo@redhat.com").
+				WithRecipients(RecipientBCC, "audit@redhat.com").
+				CreateForRecipients(context.TODO())
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, notifications)
+			existing := &toolchainv1alpha1.NotificationList{}
+			require.NoError(t, cl.List(context.TODO(), existing))
+			assert.Empty(t, existing.Items)
+		})
+
+		t.Run("opted out", func(t *testing.T) {
+			// when
+			optedOut, err := newBuilder(test.NewFakeClient(t)).OptedOut()
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, optedOut)
+		})
+
+		t.Run("preview", func(t *testing.T) {
+			// when
+			_, err := newBuilder(test.NewFakeClient(t)).Preview("foo@redhat.com")
+
+			// then
+			require.EqualError(t, err, "the recipient opted out of the 'deactivating' notifications")
+			assert.True(t, IsOptedOut(err))
+		})
+	})
+
+	t.Run("not opted out", func(t *testing.T) {
+		// given
+		builder := NewExtendedNotificationBuilder(test.NewFakeClient(t), test.HostOperatorNs).
+			WithUserContext(userSignup).
+			WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivated)
+
+		// when
+		optedOut, err := builder.OptedOut()
+		notification, createErr := builder.Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, optedOut)
+		require.NoError(t, createErr)
+		assert.NotNil(t, notification)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		assert.False(t, IsOptedOut(fmt.Errorf("mock error")))
+	})
+}
@@ -0,0 +1,143 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/spacebinding"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RecipientRole the role of a recipient of a notification. Since a separate notification is created for each recipient
+// (see ExtendedBuilder.CreateForRecipients), the addresses are never visible to the other recipients, and the role only
+// determines the order of the notifications and the role kept for an address given with several roles.
+type RecipientRole string
+
+const (
+	// RecipientTo the main recipients of a notification
+	RecipientTo RecipientRole = "to"
+	// RecipientCC the recipients in copy of a notification
+	RecipientCC RecipientRole = "cc"
+	// RecipientBCC the recipients in blind copy of a notification
+	RecipientBCC RecipientRole = "bcc"
+)
+
+// Recipients the addresses of the recipients of a notification, per role
+type Recipients struct {
+	To  []string
+	CC  []string
+	BCC []string
+}
+
+// Add adds the given addresses with the given role
+func (r *Recipients) Add(role RecipientRole, addresses ...string) {
+	switch role {
+	case RecipientCC:
+		r.CC = append(r.CC, addresses...)
+	case RecipientBCC:
+		r.BCC = append(r.BCC, addresses...)
+	default:
+		r.To = append(r.To, addresses...)
+	}
+}
+
+// normalize validates the addresses and removes the duplicates: an address is only kept with its most visible role,
+// ie, `to`, then `cc`, then `bcc`
+func (r Recipients) normalize() (Recipients, error) {
+	seen := map[string]bool{}
+	dedup := func(addresses []string) ([]string, error) {
+		var result []string
+		for _, address := range addresses {
+			parsed, err := mail.ParseAddress(address)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("The specified recipient [%s] is not a valid email address", address))
+			}
+			key := strings.ToLower(parsed.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, address)
+		}
+		return result, nil
+	}
+	var normalized Recipients
+	var err error
+	if normalized.To, err = dedup(r.To); err != nil {
+		return normalized, err
+	}
+	if normalized.CC, err = dedup(r.CC); err != nil {
+		return normalized, err
+	}
+	if normalized.BCC, err = dedup(r.BCC); err != nil {
+		return normalized, err
+	}
+	if len(normalized.To) == 0 && len(normalized.CC) == 0 && len(normalized.BCC) == 0 {
+		return normalized, errors.New("the notification has no recipient")
+	}
+	return normalized, nil
+}
+
+type recipientsResolver func(ctx context.Context, notificationType string) (Recipients, error)
+
+// spaceRecipients returns the recipients of a notification about the given space: the users bound to the space
+// (including via the parent spaces) whose space role is in the given roles, unless they opted out of the given
+// notification type (see OptOutAnnotationKey)
+func spaceRecipients(ctx context.Context, cl client.Client, namespace string, space *toolchainv1alpha1.Space, roles map[string]RecipientRole, notificationType string) (Recipients, error) {
+	lister := spacebinding.NewLister(
+		func(spaceName string) ([]toolchainv1alpha1.SpaceBinding, error) {
+			bindings := &toolchainv1alpha1.SpaceBindingList{}
+			if err := cl.List(ctx, bindings, client.InNamespace(namespace), client.MatchingLabels{toolchainv1alpha1.SpaceBindingSpaceLabelKey: spaceName}); err != nil {
+				return nil, errors.Wrapf(err, "unable to list the SpaceBindings of the '%s' space", spaceName)
+			}
+			return bindings.Items, nil
+		},
+		func(spaceName string) (*toolchainv1alpha1.Space, error) {
+			parentSpace := &toolchainv1alpha1.Space{}
+			if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: spaceName}, parentSpace); err != nil {
+				return nil, err
+			}
+			return parentSpace, nil
+		})
+	bindings, err := lister.ListForSpace(space, nil)
+	if err != nil {
+		return Recipients{}, err
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Spec.MasterUserRecord < bindings[j].Spec.MasterUserRecord
+	})
+
+	var recipients Recipients
+	for _, binding := range bindings {
+		role, found := roles[binding.Spec.SpaceRole]
+		if !found {
+			continue
+		}
+		mur := &toolchainv1alpha1.MasterUserRecord{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: binding.Spec.MasterUserRecord}, mur); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return Recipients{}, errors.Wrapf(err, "unable to get the MasterUserRecord '%s'", binding.Spec.MasterUserRecord)
+		}
+		if mur.Spec.PropagatedClaims.Email == "" {
+			continue
+		}
+		if owner := mur.Labels[toolchainv1alpha1.MasterUserRecordOwnerLabelKey]; owner != "" {
+			userSignup := &toolchainv1alpha1.UserSignup{}
+			if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: owner}, userSignup); err != nil && !apierrors.IsNotFound(err) {
+				return Recipients{}, errors.Wrapf(err, "unable to get the UserSignup '%s'", owner)
+			} else if err == nil && optedOut(userSignup.Annotations[OptOutAnnotationKey], notificationType) {
+				continue
+			}
+		}
+		recipients.Add(role, mur.Spec.PropagatedClaims.Email)
+	}
+	return recipients, nil
+}
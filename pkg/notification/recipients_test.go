@@ -0,0 +1,314 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/spacebinding"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	murtest "github.com/codeready-toolchain/toolchain-common/pkg/test/masteruserrecord"
+	spacetest "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	testusersignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestNotificationBuilderWithRecipients(t *testing.T) {
+	// given
+	userSignup := testusersignup.NewUserSignup()
+	userSignup.Status.CompliantUsername = "foo"
+	newBuilder := func(cl runtimeclient.Client) ExtendedBuilder {
+		return NewExtendedNotificationBuilder(cl, test.HostOperatorNs).
+			WithUserContext(userSignup).
+			WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivating).
+			WithSubjectAndContent("subject", "content")
+	}
+	listNotifications := func(t *testing.T, cl runtimeclient.Client) []toolchainv1alpha1.Notification {
+		notifications := &toolchainv1alpha1.NotificationList{}
+		require.NoError(t, cl.List(context.TODO(), notifications))
+		return notifications.Items
+	}
+
+	t.Run("to, cc and bcc recipients", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		notifications, err := newBuilder(cl).
+			WithRecipients(RecipientTo, "foo@redhat.com", "John <john@redhat.com>").
+			WithRecipients(RecipientCC, "admin@redhat.com").
+			WithRecipients(RecipientBCC, "audit@redhat.com", "security@redhat.com").
+			CreateForRecipients(context.TODO())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifications, 5)
+		assert.Equal(t, "foo@redhat.com", notifications[0].Spec.Recipient)
+		assert.Equal(t, "John <john@redhat.com>", notifications[1].Spec.Recipient)
+		assert.Equal(t, "admin@redhat.com", notifications[2].Spec.Recipient)
+		assert.Equal(t, "audit@redhat.com", notifications[3].Spec.Recipient)
+		assert.Equal(t, "security@redhat.com", notifications[4].Spec.Recipient)
+		assert.Len(t, listNotifications(t, cl), 5)
+	})
+
+	t.Run("duplicate addresses are kept with their most visible role", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		notifications, err := newBuilder(cl).
+			WithRecipients(RecipientBCC, "Foo <FOO@redhat.com>", "audit@redhat.com").
+			WithRecipients(RecipientCC, "admin@redhat.com", "foo@redhat.com").
+			WithRecipients(RecipientTo, "foo@redhat.com").
+			WithRecipients(RecipientCC, "admin@redhat.com").
+			CreateForRecipients(context.TODO())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifications, 3)
+		assert.Equal(t, "foo@redhat.com", notifications[0].Spec.Recipient)
+		assert.Equal(t, "admin@redhat.com", notifications[1].Spec.Recipient)
+		assert.Equal(t, "audit@redhat.com", notifications[2].Spec.Recipient)
+	})
+
+	t.Run("only cc recipients", func(t *testing.T) {
+		// when
+		notifications, err := newBuilder(test.NewFakeClient(t)).
+			WithRecipients(RecipientCC, "admin@redhat.com", "other-admin@redhat.com").
+			CreateForRecipients(context.TODO())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifications, 2)
+		assert.Equal(t, "admin@redhat.com", notifications[0].Spec.Recipient)
+		assert.Equal(t, "other-admin@redhat.com", notifications[1].Spec.Recipient)
+	})
+
+	t.Run("the user opted out of the type of the notification", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		optedOutUserSignup := testusersignup.NewUserSignup()
+		optedOutUserSignup.Annotations[OptOutAnnotationKey] = toolchainv1alpha1.NotificationTypeDeactivating
+
+		// when
+		notifications, err := newBuilder(cl).
+			WithUserContext(optedOutUserSignup).
+			WithRecipients(RecipientTo, "foo@redhat.com").
+			WithRecipients(RecipientBCC, "audit@redhat.com").
+			CreateForRecipients(context.TODO())
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, notifications)
+		assert.Empty(t, listNotifications(t, cl))
+	})
+
+	t.Run("with deduplication", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		create := func() ([]*toolchainv1alpha1.Notification, error) {
+			return newBuilder(cl).
+				WithDeduplication("2024-01-31", 0).
+				WithRecipients(RecipientTo, "foo@redhat.com").
+				WithRecipients(RecipientBCC, "audit@redhat.com", "security@redhat.com").
+				CreateForRecipients(context.TODO())
+		}
+
+		// when
+		first, err1 := create()
+		second, err2 := create()
+
+		// then
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		require.Len(t, first, 3)
+		require.Len(t, second, 3)
+		assert.NotEqual(t, first[0].Name, first[1].Name)
+		assert.NotEqual(t, first[1].Name, first[2].Name)
+		assert.Equal(t, "foo/deactivating/2024-01-31/foo@redhat.com", first[0].Annotations[DeduplicationKeyAnnotationKey])
+		assert.Equal(t, "foo/deactivating/2024-01-31/audit@redhat.com", first[1].Annotations[DeduplicationKeyAnnotationKey])
+		for i := range first {
+			assert.Equal(t, first[i].Name, second[i].Name)
+		}
+		assert.Len(t, listNotifications(t, cl), 3)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("no recipient", func(t *testing.T) {
+			// when
+			_, err := newBuilder(test.NewFakeClient(t)).CreateForRecipients(context.TODO())
+
+			// then
+			require.EqualError(t, err, "the notification has no recipient")
+		})
+
+		t.Run("invalid address", func(t *testing.T) {
+			// when
+			_, err := newBuilder(test.NewFakeClient(t)).
+				WithRecipients(RecipientTo, "foo@redhat.com").
+				WithRecipients(RecipientBCC, "foo").
+				CreateForRecipients(context.TODO())
+
+			// then
+			require.EqualError(t, err, "The specified recipient [foo] is not a valid email address: mail: missing '@' or angle-addr")
+		})
+
+		t.Run("failed to create a notification", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockCreate = func(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+				if obj.(*toolchainv1alpha1.Notification).Spec.Recipient == "security@redhat.com" {
+					return fmt.Errorf("mock error")
+				}
+				return cl.Client.Create(ctx, obj, opts...)
+			}
+
+			// when
+			notifications, err := newBuilder(cl).
+				WithRecipients(RecipientTo, "foo@redhat.com").
+				WithRecipients(RecipientBCC, "audit@redhat.com", "security@redhat.com").
+				CreateForRecipients(context.TODO())
+
+			// then
+			require.EqualError(t, err, "mock error")
+			assert.Len(t, notifications, 2)
+		})
+	})
+}
+
+func TestNotificationBuilderWithSpaceRecipients(t *testing.T) {
+	// given
+	parentSpace := spacetest.NewSpace(test.HostOperatorNs, "parent")
+	space := spacetest.NewSpace(test.HostOperatorNs, "space", spacetest.WithSpecParentSpace(parentSpace.Name))
+	owner := murtest.NewMasterUserRecord(t, "owner", murtest.Email("owner@redhat.com"), murtest.WithOwnerLabel("owner"))
+	admin := murtest.NewMasterUserRecord(t, "admin", murtest.Email("admin@redhat.com"), murtest.WithOwnerLabel("admin"))
+	parentAdmin := murtest.NewMasterUserRecord(t, "parent-admin", murtest.Email("parent-admin@redhat.com"), murtest.WithOwnerLabel("parent-admin"))
+	viewer := murtest.NewMasterUserRecord(t, "viewer", murtest.Email("viewer@redhat.com"), murtest.WithOwnerLabel("viewer"))
+	optedOutAdmin := murtest.NewMasterUserRecord(t, "opted-out", murtest.Email("opted-out@redhat.com"), murtest.WithOwnerLabel("opted-out"))
+	optedOutUserSignup := testusersignup.NewUserSignup()
+	optedOutUserSignup.Name = "opted-out"
+	optedOutUserSignup.Annotations[OptOutAnnotationKey] = toolchainv1alpha1.NotificationTypeDeactivating
+	objects := []runtimeclient.Object{
+		parentSpace, space, owner, admin, parentAdmin, viewer, optedOutAdmin, optedOutUserSignup,
+		spacebinding.NewSpaceBinding(owner, space, "owner", spacebinding.WithRole("owner")),
+		spacebinding.NewSpaceBinding(admin, space, "owner", spacebinding.WithRole("admin")),
+		spacebinding.NewSpaceBinding(viewer, space, "owner", spacebinding.WithRole("viewer")),
+		spacebinding.NewSpaceBinding(optedOutAdmin, space, "owner", spacebinding.WithRole("admin")),
+		spacebinding.NewSpaceBinding(parentAdmin, parentSpace, "parent-admin", spacebinding.WithRole("admin")),
+		// the binding of a MasterUserRecord which does not exist anymore
+		spacebinding.NewSpaceBinding(murtest.NewMasterUserRecord(t, "deleted"), space, "owner", spacebinding.WithRole("admin")),
+	}
+	roles := map[string]RecipientRole{
+		"owner": RecipientTo,
+		"admin": RecipientCC,
+	}
+	userSignup := testusersignup.NewUserSignup()
+	userSignup.Status.CompliantUsername = "owner"
+	newBuilder := func(cl runtimeclient.Client) ExtendedBuilder {
+		return NewExtendedNotificationBuilder(cl, test.HostOperatorNs).
+			WithUserContext(userSignup).
+			WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivating).
+			WithSubjectAndContent("subject", "content").
+			WithSpaceRecipients(space, roles)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objects...)
+
+		// when
+		notifications, err := newBuilder(cl).
+			WithRecipients(RecipientBCC, "audit@redhat.com").
+			CreateForRecipients(context.TODO())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, notifications, 4)
+		assert.Equal(t, "owner@redhat.com", notifications[0].Spec.Recipient)
+		assert.Equal(t, "admin@redhat.com", notifications[1].Spec.Recipient)
+		assert.Equal(t, "parent-admin@redhat.com", notifications[2].Spec.Recipient)
+		assert.Equal(t, "audit@redhat.com", notifications[3].Spec.Recipient)
+	})
+
+	t.Run("with deduplication and without user context", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objects...)
+		create := func() ([]*toolchainv1alpha1.Notification, error) {
+			return NewExtendedNotificationBuilder(cl, test.HostOperatorNs).
+				WithNotificationType(toolchainv1alpha1.NotificationTypeDeactivating).
+				WithSubjectAndContent("subject", "content").
+				WithSpaceRecipients(space, roles).
+				WithDeduplication("2024-01-31", 0).
+				CreateForRecipients(context.TODO())
+		}
+
+		// when
+		first, err1 := create()
+		second, err2 := create()
+
+		// then
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		require.Len(t, first, 3)
+		require.Len(t, second, 3)
+		assert.Equal(t, "space:space/deactivating/2024-01-31/owner@redhat.com", first[0].Annotations[DeduplicationKeyAnnotationKey])
+		assert.Regexp(t, "^space-deactivating-", first[0].Name)
+		for i := range first {
+			assert.Equal(t, first[i].Name, second[i].Name)
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("failed to list the space bindings", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, objects...)
+			cl.MockList = func(context.Context, runtimeclient.ObjectList, ...runtimeclient.ListOption) error {
+				return fmt.Errorf("mock error")
+			}
+
+			// when
+			_, err := newBuilder(cl).CreateForRecipients(context.TODO())
+
+			// then
+			require.EqualError(t, err, "unable to list the SpaceBindings of the 'space' space: mock error")
+		})
+
+		t.Run("failed to get a MasterUserRecord", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, objects...)
+			cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.MasterUserRecord); ok {
+					return fmt.Errorf("mock error")
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+
+			// when
+			_, err := newBuilder(cl).CreateForRecipients(context.TODO())
+
+			// then
+			require.EqualError(t, err, "unable to get the MasterUserRecord 'admin': mock error")
+		})
+
+		t.Run("failed to get a UserSignup", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, objects...)
+			cl.MockGet = func(ctx context.Context, key runtimeclient.ObjectKey, obj runtimeclient.Object, opts ...runtimeclient.GetOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.UserSignup); ok {
+					return fmt.Errorf("mock error")
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+
+			// when
+			_, err := newBuilder(cl).CreateForRecipients(context.TODO())
+
+			// then
+			require.EqualError(t, err, "unable to get the UserSignup 'admin': mock error")
+		})
+	})
+}
@@ -0,0 +1,256 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SubjectTemplateFile the name of the file of the template of the notification subject
+	SubjectTemplateFile = "subject.txt"
+	// HTMLContentTemplateFile the name of the file of the template of the notification content, in HTML
+	HTMLContentTemplateFile = "content.html"
+	// PlainContentTemplateFile the name of the (optional) file of the template of the notification content, in plain text
+	PlainContentTemplateFile = "content.txt"
+)
+
+// Template the templates of the subject and content (HTML and plain text) of a notification.
+// The templates reference the keys of the notification context, eg, `{{.FirstName}}`
+type Template struct {
+	Name         string
+	subject      *texttemplate.Template
+	htmlContent  *htmltemplate.Template
+	plainContent *texttemplate.Template
+	keys         []string
+}
+
+// RenderedNotification the subject and content of a notification rendered from a Template
+type RenderedNotification struct {
+	Subject      string
+	HTMLContent  string
+	PlainContent string
+}
+
+// Templates the notification templates, keyed by template set name
+type Templates struct {
+	templates map[string]*Template
+}
+
+// LoadTemplates loads the notification templates from the given embedded filesystem. Each directory under the given
+// root is a template set, whose name is the name of the directory and which contains the `subject.txt` and `content.html`
+// files, and optionally the `content.txt` file.
+func LoadTemplates(efs *embed.FS, root string) (*Templates, error) {
+	entries, err := efs.ReadDir(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the notification templates in '%s'", root)
+	}
+	templates := &Templates{templates: map[string]*Template{}}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		tmpl, err := loadTemplate(efs, path.Join(root, entry.Name()), entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		templates.templates[tmpl.Name] = tmpl
+	}
+	return templates, nil
+}
+
+func loadTemplate(efs *embed.FS, dir, name string) (*Template, error) {
+	subject, err := efs.ReadFile(path.Join(dir, SubjectTemplateFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the subject of the notification template '%s'", name)
+	}
+	htmlContent, err := efs.ReadFile(path.Join(dir, HTMLContentTemplateFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the HTML content of the notification template '%s'", name)
+	}
+	tmpl := &Template{Name: name}
+	if tmpl.subject, err = texttemplate.New(SubjectTemplateFile).Option("missingkey=error").Parse(string(subject)); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the subject of the notification template '%s'", name)
+	}
+	if tmpl.htmlContent, err = htmltemplate.New(HTMLContentTemplateFile).Option("missingkey=error").Parse(string(htmlContent)); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the HTML content of the notification template '%s'", name)
+	}
+	trees := []*parse.Tree{tmpl.subject.Tree, tmpl.htmlContent.Tree}
+
+	plainContent, err := efs.ReadFile(path.Join(dir, PlainContentTemplateFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, errors.Wrapf(err, "unable to read the plain content of the notification template '%s'", name)
+	default:
+		if tmpl.plainContent, err = texttemplate.New(PlainContentTemplateFile).Option("missingkey=error").Parse(string(plainContent)); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse the plain content of the notification template '%s'", name)
+		}
+		trees = append(trees, tmpl.plainContent.Tree)
+	}
+
+	keys := map[string]struct{}{}
+	for _, tree := range trees {
+		collectKeys(tree.Root, keys)
+	}
+	for key := range keys {
+		tmpl.keys = append(tmpl.keys, key)
+	}
+	sort.Strings(tmpl.keys)
+	return tmpl, nil
+}
+
+// collectKeys collects the keys of the context referenced by the given node of a template, ie, `{{.Key}}` at the top-level
+// scope, or `{{$.Key}}` anywhere. The fields referenced within the body of a `range` or `with` action (where the dot is
+// no longer the context) are ignored.
+func collectKeys(node parse.Node, keys map[string]struct{}) {
+	collectScopedKeys(node, true, keys)
+}
+
+// collectScopedKeys collects the keys referenced by the given node. The `{{.Key}}` fields are collected only if the dot is
+// the context, ie, if topLevel is true.
+func collectScopedKeys(node parse.Node, topLevel bool, keys map[string]struct{}) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectScopedKeys(child, topLevel, keys)
+		}
+	case *parse.ActionNode:
+		collectScopedKeys(n.Pipe, topLevel, keys)
+	case *parse.IfNode:
+		collectBranchKeys(&n.BranchNode, topLevel, topLevel, keys)
+	case *parse.RangeNode:
+		collectBranchKeys(&n.BranchNode, topLevel, false, keys)
+	case *parse.WithNode:
+		collectBranchKeys(&n.BranchNode, topLevel, false, keys)
+	case *parse.TemplateNode:
+		collectScopedKeys(n.Pipe, topLevel, keys)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectScopedKeys(cmd, topLevel, keys)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectScopedKeys(arg, topLevel, keys)
+		}
+	case *parse.ChainNode:
+		collectScopedKeys(n.Node, topLevel, keys)
+	case *parse.FieldNode:
+		if topLevel {
+			keys[n.Ident[0]] = struct{}{}
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			keys[n.Ident[1]] = struct{}{}
+		}
+	}
+}
+
+// collectBranchKeys collects the keys referenced by the given branch. The pipeline and the `else` list are evaluated in
+// the enclosing scope, the body in the given scope (which is not the top-level one in a `range` or `with` action).
+func collectBranchKeys(n *parse.BranchNode, topLevel, bodyTopLevel bool, keys map[string]struct{}) {
+	collectScopedKeys(n.Pipe, topLevel, keys)
+	collectScopedKeys(n.List, bodyTopLevel, keys)
+	collectScopedKeys(n.ElseList, topLevel, keys)
+}
+
+// Get returns the template set with the given name
+func (t *Templates) Get(name string) (*Template, bool) {
+	tmpl, found := t.templates[name]
+	return tmpl, found
+}
+
+// GetLocalized returns the variant of the template set with the given name which matches the given locale, ie, the
+// template set named `<name>.<locale>` (in lower case), `<name>.<primary language of the locale>` or `<name>`
+func (t *Templates) GetLocalized(name, locale string) (*Template, bool) {
+	for _, candidate := range localeCandidates(locale) {
+		if tmpl, found := t.templates[name+"."+candidate]; found {
+			return tmpl, true
+		}
+	}
+	return t.Get(name)
+}
+
+// Names returns the sorted names of the template sets
+func (t *Templates) Names() []string {
+	names := make([]string, 0, len(t.templates))
+	for name := range t.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Keys returns the sorted keys of the context referenced by the templates
+func (t *Template) Keys() []string {
+	return append([]string{}, t.keys...)
+}
+
+// Validate returns an error if some keys referenced by the templates are missing in the given context
+func (t *Template) Validate(context map[string]string) error {
+	var missing []string
+	for _, key := range t.keys {
+		if _, found := context[key]; !found {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("missing keys in the context of the notification template '%s': %v", t.Name, missing)
+	}
+	return nil
+}
+
+// Render renders the subject and content of the notification with the given context, after validating it
+func (t *Template) Render(context map[string]string) (*RenderedNotification, error) {
+	if err := t.Validate(context); err != nil {
+		return nil, err
+	}
+	rendered := &RenderedNotification{}
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, context); err != nil {
+		return nil, errors.Wrapf(err, "unable to render the subject of the notification template '%s'", t.Name)
+	}
+	rendered.Subject = buf.String()
+	buf.Reset()
+	if err := t.htmlContent.Execute(&buf, context); err != nil {
+		return nil, errors.Wrapf(err, "unable to render the HTML content of the notification template '%s'", t.Name)
+	}
+	rendered.HTMLContent = buf.String()
+	if t.plainContent != nil {
+		buf.Reset()
+		if err := t.plainContent.Execute(&buf, context); err != nil {
+			return nil, errors.Wrapf(err, "unable to render the plain content of the notification template '%s'", t.Name)
+		}
+		rendered.PlainContent = buf.String()
+	}
+	return rendered, nil
+}
+
+// Preview renders the notification of the given template for the given UserSignup (and UserTier, if not nil),
+// without creating it. The context is the same as the one of the notifications created with the WithUserContext
+// and WithUserTierContext options of the Builder.
+func Preview(tmpl *Template, userSignup *toolchainv1alpha1.UserSignup, userTier *toolchainv1alpha1.UserTier) (*RenderedNotification, error) {
+	builder := NewNotificationBuilder(nil, "").WithUserContext(userSignup)
+	if userTier != nil {
+		builder = builder.WithUserTierContext(userTier)
+	}
+	notification, err := builder.(*notificationBuilderImpl).build(userSignup.Spec.IdentityClaims.Email)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Render(notification.Spec.Context)
+}
@@ -0,0 +1,255 @@
+package notification
+
+import (
+	"context"
+	"embed"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testusersignup "github.com/codeready-toolchain/toolchain-common/pkg/test/usersignup"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata
+var testTemplatesFS embed.FS
+
+func TestLoadTemplates(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// when
+		templates, err := LoadTemplates(&testTemplatesFS, "testdata/templates")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"userdeactivated", "userdeactivated.fr", "userprovisioned"}, templates.Names())
+		deactivated, found := templates.Get("userdeactivated")
+		require.True(t, found)
+		assert.Equal(t, []string{"CompanyName", "DeactivationTimeoutDays", "FirstName", "LastName", "UserName"}, deactivated.Keys())
+		provisioned, found := templates.Get("userprovisioned")
+		require.True(t, found)
+		assert.Equal(t, []string{"CompanyName", "FirstName", "UserName"}, provisioned.Keys())
+		_, found = templates.Get("unknown")
+		assert.False(t, found)
+	})
+
+	t.Run("keys in the scopes of the with and range actions", func(t *testing.T) {
+		// when
+		templates, err := LoadTemplates(&testTemplatesFS, "testdata/scopes")
+
+		// then
+		require.NoError(t, err)
+		scoped, found := templates.Get("scoped")
+		require.True(t, found)
+		// `.Ignored` and `.Name` are fields of the values of `.FirstName` and `.Spaces`, not keys of the context
+		assert.Equal(t, []string{"CompanyName", "FirstName", "NoSpaceMessage", "Spaces", "UserName"}, scoped.Keys())
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("unknown root", func(t *testing.T) {
+			// when
+			_, err := LoadTemplates(&testTemplatesFS, "testdata/unknown")
+
+			// then
+			require.EqualError(t, err, "unable to read the notification templates in 'testdata/unknown': open testdata/unknown: file does not exist")
+		})
+
+		t.Run("missing subject", func(t *testing.T) {
+			// when
+			_, err := LoadTemplates(&testTemplatesFS, "testdata/missing-subject")
+
+			// then
+			require.EqualError(t, err, "unable to read the subject of the notification template 'broken': open testdata/missing-subject/broken/subject.txt: file does not exist")
+		})
+
+		t.Run("malformed subject", func(t *testing.T) {
+			// when
+			_, err := LoadTemplates(&testTemplatesFS, "testdata/malformed")
+
+			// then
+			require.ErrorContains(t, err, "unable to parse the subject of the notification template 'broken'")
+		})
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	// given
+	templates, err := LoadTemplates(&testTemplatesFS, "testdata/templates")
+	require.NoError(t, err)
+	deactivated, _ := templates.Get("userdeactivated")
+	provisioned, _ := templates.Get("userprovisioned")
+
+	t.Run("success", func(t *testing.T) {
+		// when
+		rendered, err := deactivated.Render(map[string]string{
+			"UserName":                "jsmith",
+			"FirstName":               "John",
+			"LastName":                "Smith",
+			"CompanyName":             "Smith & Sons <Ltd>",
+			"DeactivationTimeoutDays": "30",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Your account jsmith has been deactivated\n", rendered.Subject)
+		assert.Equal(t, "<p>Hello John Smith,</p>\n<p>Your account at Smith &amp; Sons &lt;Ltd&gt; has been deactivated after 30 days.</p>\n", rendered.HTMLContent)
+		assert.Equal(t, "Hello John Smith,\nYour account at Smith & Sons <Ltd> has been deactivated after 30 days.\n", rendered.PlainContent)
+	})
+
+	t.Run("success without plain content", func(t *testing.T) {
+		// when
+		rendered, err := provisioned.Render(map[string]string{
+			"UserName":    "jsmith",
+			"FirstName":   "John",
+			"CompanyName": "ACME",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome John!\n", rendered.Subject)
+		assert.Equal(t, "<p>Your account jsmith is ready. Enjoy it at ACME!</p>\n", rendered.HTMLContent)
+		assert.Empty(t, rendered.PlainContent)
+	})
+
+	t.Run("missing keys", func(t *testing.T) {
+		// when
+		_, err := deactivated.Render(map[string]string{
+			"UserName":  "jsmith",
+			"FirstName": "John",
+		})
+
+		// then
+		require.EqualError(t, err, "missing keys in the context of the notification template 'userdeactivated': [CompanyName DeactivationTimeoutDays LastName]")
+	})
+}
+
+func TestNotificationBuilderWithRenderedTemplate(t *testing.T) {
+	// given
+	templates, err := LoadTemplates(&testTemplatesFS, "testdata/templates")
+	require.NoError(t, err)
+	deactivated, _ := templates.Get("userdeactivated")
+	userSignup := testusersignup.NewUserSignup()
+	userSignup.Status.CompliantUsername = "foo"
+	userTier := &toolchainv1alpha1.UserTier{
+		Spec: toolchainv1alpha1.UserTierSpec{
+			DeactivationTimeoutDays: 30,
+		},
+	}
+
+	t.Run("create", func(t *testing.T) {
+		// given
+		client := test.NewFakeClient(t)
+
+		// when
+		notification, err := NewExtendedNotificationBuilder(client, test.HostOperatorNs).
+			WithRenderedTemplate(deactivated).
+			WithUserContext(userSignup).
+			WithUserTierContext(userTier).
+			Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, notification.Spec.Template)
+		assert.Equal(t, "Your account foo has been deactivated\n", notification.Spec.Subject)
+		assert.Equal(t, "<p>Hello Foo Bar,</p>\n<p>Your account at Red Hat has been deactivated after 30 days.</p>\n", notification.Spec.Content)
+		notifications := &toolchainv1alpha1.NotificationList{}
+		require.NoError(t, client.List(context.TODO(), notifications))
+		require.Len(t, notifications.Items, 1)
+		assert.Equal(t, notification.Spec.Content, notifications.Items[0].Spec.Content)
+	})
+
+	t.Run("create fails when keys are missing", func(t *testing.T) {
+		// given
+		client := test.NewFakeClient(t)
+
+		// when
+		_, err := NewExtendedNotificationBuilder(client, test.HostOperatorNs).
+			WithRenderedTemplate(deactivated).
+			WithUserContext(userSignup).
+			Create(context.TODO(), "foo@redhat.com")
+
+		// then
+		require.EqualError(t, err, "missing keys in the context of the notification template 'userdeactivated': [DeactivationTimeoutDays]")
+		notifications := &toolchainv1alpha1.NotificationList{}
+		require.NoError(t, client.List(context.TODO(), notifications))
+		assert.Empty(t, notifications.Items)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		// given
+		client := test.NewFakeClient(t)
+
+		// when
+		notification, err := NewExtendedNotificationBuilder(client, test.HostOperatorNs).
+			WithRenderedTemplate(deactivated).
+			WithUserContext(userSignup).
+			WithUserTierContext(userTier).
+			Preview("foo@redhat.com")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Your account foo has been deactivated\n", notification.Spec.Subject)
+		notifications := &toolchainv1alpha1.NotificationList{}
+		require.NoError(t, client.List(context.TODO(), notifications))
+		assert.Empty(t, notifications.Items)
+	})
+}
+
+func TestPreview(t *testing.T) {
+	// given
+	templates, err := LoadTemplates(&testTemplatesFS, "testdata/templates")
+	require.NoError(t, err)
+	deactivated, _ := templates.Get("userdeactivated")
+	userSignup := testusersignup.NewUserSignup()
+	userSignup.Status.CompliantUsername = "foo"
+
+	t.Run("with user tier", func(t *testing.T) {
+		// when
+		rendered, err := Preview(deactivated, userSignup, &toolchainv1alpha1.UserTier{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Your account foo has been deactivated\n", rendered.Subject)
+		assert.Equal(t, "Hello Foo Bar,\nYour account at Red Hat has been deactivated after (unlimited) days.\n", rendered.PlainContent)
+	})
+
+	t.Run("without user tier", func(t *testing.T) {
+		// when
+		_, err := Preview(deactivated, userSignup, nil)
+
+		// then
+		require.EqualError(t, err, "missing keys in the context of the notification template 'userdeactivated': [DeactivationTimeoutDays]")
+	})
+}
+
+func TestGetLocalizedTemplate(t *testing.T) {
+	// given
+	templates, err := LoadTemplates(&testTemplatesFS, "testdata/templates")
+	require.NoError(t, err)
+
+	for locale, expected := range map[string]string{
+		"":      "userdeactivated",
+		"en":    "userdeactivated",
+		"fr":    "userdeactivated.fr",
+		"fr_CA": "userdeactivated.fr",
+		"FR-fr": "userdeactivated.fr",
+	} {
+		t.Run("locale "+locale, func(t *testing.T) {
+			// when
+			tmpl, found := templates.GetLocalized("userdeactivated", locale)
+
+			// then
+			require.True(t, found)
+			assert.Equal(t, expected, tmpl.Name)
+		})
+	}
+
+	t.Run("unknown template", func(t *testing.T) {
+		// when
+		_, found := templates.GetLocalized("unknown", "fr")
+
+		// then
+		assert.False(t, found)
+	})
+}
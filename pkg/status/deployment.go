@@ -3,6 +3,8 @@ package status
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
 
@@ -10,8 +12,10 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -20,11 +24,63 @@ const (
 
 	// ErrMsgDeploymentConditionNotReady deployment not ready
 	ErrMsgDeploymentConditionNotReady = "deployment has unready status conditions"
+
+	// ErrMsgDeploymentRolloutStuck the deployment exceeded its progress deadline
+	ErrMsgDeploymentRolloutStuck = "deployment rollout exceeded its progress deadline"
+
+	// ErrMsgDeploymentGenerationNotObserved the latest spec of the deployment was not observed yet
+	ErrMsgDeploymentGenerationNotObserved = "deployment controller has not observed the latest generation"
+
+	// ErrMsgDeploymentReplicasUnavailable some replicas of the deployment are unavailable
+	ErrMsgDeploymentReplicasUnavailable = "deployment has unavailable replicas"
+
+	// ErrMsgDeploymentPodsCrashLooping some pods of the deployment are crash-looping
+	ErrMsgDeploymentPodsCrashLooping = "deployment has crash-looping pods"
+)
+
+// reasons of the deployment conditions which are more specific than toolchainv1alpha1.ToolchainStatusDeploymentNotReadyReason
+const (
+	DeploymentRolloutStuckReason          = "DeploymentRolloutStuck"
+	DeploymentGenerationNotObservedReason = "DeploymentGenerationNotObserved"
+	DeploymentReplicasUnavailableReason   = "DeploymentReplicasUnavailable"
+	DeploymentPodsCrashLoopingReason      = "DeploymentPodsCrashLooping"
+
+	// progressDeadlineExceededReason the reason of the Progressing condition of a deployment which exceeded its progress deadline
+	progressDeadlineExceededReason = "ProgressDeadlineExceeded"
+	// crashLoopBackOffReason the reason of the waiting state of a crash-looping container
+	crashLoopBackOffReason = "CrashLoopBackOff"
 )
 
+// DeploymentCheckOption an option of the checks of GetDeploymentStatusConditions
+type DeploymentCheckOption func(*deploymentCheck)
+
+// WithCrashLoopingPodsCheck samples the pods matching the selector of the deployment (eg: the pods of the new ReplicaSet
+// during a rollout) to detect the crash-looping containers, at the cost of listing the pods. If the pods cannot be
+// listed, the error is logged and the check is skipped.
+func WithCrashLoopingPodsCheck() DeploymentCheckOption {
+	return func(c *deploymentCheck) {
+		c.crashLoopingPods = true
+	}
+}
+
+type deploymentCheck struct {
+	crashLoopingPods bool
+}
+
 // GetDeploymentStatusConditions looks up a deployment with the given name within the given namespace and checks its status
-// and finally returns a condition summarizing the status
-func GetDeploymentStatusConditions(ctx context.Context, client client.Client, name, namespace string) []toolchainv1alpha1.Condition {
+// and finally returns a condition summarizing the status. In addition to the Available and Progressing conditions, it detects:
+//   - the stuck rollouts (progress deadline exceeded),
+//   - the crash-looping pods, if the WithCrashLoopingPodsCheck option is given,
+//   - the unavailable replicas, when the deployment is not available.
+//
+// The unavailable replicas of an available deployment (eg: during a rollout) are not reported, unless the rollout exceeds
+// its progress deadline. Likewise, the latest generation not observed yet by the deployment controller is transient:
+// the returned condition is ready, with the DeploymentGenerationNotObserved reason.
+func GetDeploymentStatusConditions(ctx context.Context, client client.Client, name, namespace string, options ...DeploymentCheckOption) []toolchainv1alpha1.Condition {
+	check := deploymentCheck{}
+	for _, apply := range options {
+		apply(&check)
+	}
 	deploymentName := types.NamespacedName{Namespace: namespace, Name: name}
 	deployment := &appsv1.Deployment{}
 	err := client.Get(ctx, deploymentName, deployment)
@@ -34,8 +90,28 @@ func GetDeploymentStatusConditions(ctx context.Context, client client.Client, na
 		return []toolchainv1alpha1.Condition{*errCondition}
 	}
 
+	// check if the rollout is stuck
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse && condition.Reason == progressDeadlineExceededReason {
+			errCondition := NewComponentErrorCondition(DeploymentRolloutStuckReason, fmt.Sprintf("%s: %s", ErrMsgDeploymentRolloutStuck, condition.Message))
+			return []toolchainv1alpha1.Condition{*errCondition}
+		}
+	}
+
+	// check if some pods are crash-looping
+	if check.crashLoopingPods {
+		if errCondition := checkCrashLoopingPods(ctx, client, deployment); errCondition != nil {
+			return []toolchainv1alpha1.Condition{*errCondition}
+		}
+	}
+
 	// get and check conditions
 	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable && condition.Status != corev1.ConditionTrue && deployment.Status.UnavailableReplicas > 0 {
+			err := fmt.Errorf("%s: %d unavailable out of %d", ErrMsgDeploymentReplicasUnavailable, deployment.Status.UnavailableReplicas, deployment.Status.Replicas)
+			errCondition := NewComponentErrorCondition(DeploymentReplicasUnavailableReason, err.Error())
+			return []toolchainv1alpha1.Condition{*errCondition}
+		}
 		if (condition.Type == appsv1.DeploymentAvailable || condition.Type == appsv1.DeploymentProgressing) && condition.Status != corev1.ConditionTrue {
 			// there is a condition that is not ready, return it
 			err := fmt.Errorf("%s: %s", ErrMsgDeploymentConditionNotReady, condition.Type)
@@ -44,11 +120,49 @@ func GetDeploymentStatusConditions(ctx context.Context, client client.Client, na
 		}
 	}
 
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		readyCondition := NewComponentReadyCondition(DeploymentGenerationNotObservedReason)
+		readyCondition.Message = fmt.Sprintf("%s: observed generation %d, latest generation %d", ErrMsgDeploymentGenerationNotObserved, deployment.Status.ObservedGeneration, deployment.Generation)
+		return []toolchainv1alpha1.Condition{*readyCondition}
+	}
+
 	// no problems with the deployment, return a ready condition
 	deploymentReadyCondition := NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentReadyReason)
 	return []toolchainv1alpha1.Condition{*deploymentReadyCondition}
 }
 
+// checkCrashLoopingPods returns an error condition if some containers of the pods matching the selector of the given
+// deployment are crash-looping, nil otherwise (including when the pods cannot be listed, in which case the error is logged)
+func checkCrashLoopingPods(ctx context.Context, cl client.Client, deployment *appsv1.Deployment) *toolchainv1alpha1.Condition {
+	if deployment.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		logger.FromContext(ctx).Error(err, "invalid selector of the deployment, skipping the check of the crash-looping pods", "deployment", deployment.Name)
+		return nil
+	}
+	pods := &corev1.PodList{}
+	if err := cl.List(ctx, pods, client.InNamespace(deployment.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		logger.FromContext(ctx).Error(err, "unable to list the pods of the deployment, skipping the check of the crash-looping pods", "deployment", deployment.Name)
+		return nil
+	}
+	var crashLooping []string
+	for _, pod := range pods.Items {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == crashLoopBackOffReason {
+				crashLooping = append(crashLooping, fmt.Sprintf("%s/%s (%d restarts)", pod.Name, status.Name, status.RestartCount))
+			}
+		}
+	}
+	if len(crashLooping) == 0 {
+		return nil
+	}
+	sort.Strings(crashLooping)
+	return NewComponentErrorCondition(DeploymentPodsCrashLoopingReason, fmt.Sprintf("%s: %s", ErrMsgDeploymentPodsCrashLooping, strings.Join(crashLooping, ", ")))
+}
+
 func DeploymentAvailableCondition() appsv1.DeploymentCondition {
 	return appsv1.DeploymentCondition{
 		Type:   appsv1.DeploymentAvailable,
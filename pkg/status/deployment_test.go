@@ -2,6 +2,7 @@ package status
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
@@ -12,6 +13,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestGetDeploymentStatusConditions(t *testing.T) {
@@ -77,9 +79,136 @@ func TestGetDeploymentStatusConditions(t *testing.T) {
 			}
 			test.AssertConditionsMatchAndRecentTimestamps(t, conditions, expected)
 		})
+
+		t.Run("deployment rollout stuck", func(t *testing.T) {
+			progressing := DeploymentNotProgressingCondition()
+			progressing.Reason = "ProgressDeadlineExceeded"
+			progressing.Message = `ReplicaSet "test-deployment-5d4f" has timed out progressing.`
+			fakeClient := test.NewFakeClient(t, newFakeDeployment("test-deployment", test.HostOperatorNs, DeploymentAvailableCondition(), progressing))
+			conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs)
+
+			expected := toolchainv1alpha1.Condition{
+				Type:    toolchainv1alpha1.ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  "DeploymentRolloutStuck",
+				Message: `deployment rollout exceeded its progress deadline: ReplicaSet "test-deployment-5d4f" has timed out progressing.`,
+			}
+			test.AssertConditionsMatchAndRecentTimestamps(t, conditions, expected)
+		})
+
+		t.Run("deployment generation not observed is transient", func(t *testing.T) {
+			deployment := fakeDeploymentReady()
+			deployment.Generation = 3
+			deployment.Status.ObservedGeneration = 2
+			fakeClient := test.NewFakeClient(t, deployment)
+			conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs)
+
+			expected := toolchainv1alpha1.Condition{
+				Type:    toolchainv1alpha1.ConditionReady,
+				Status:  corev1.ConditionTrue,
+				Reason:  "DeploymentGenerationNotObserved",
+				Message: "deployment controller has not observed the latest generation: observed generation 2, latest generation 3",
+			}
+			test.AssertConditionsMatchAndRecentTimestamps(t, conditions, expected)
+		})
+
+		t.Run("deployment replicas unavailable", func(t *testing.T) {
+			t.Run("while the deployment is not available", func(t *testing.T) {
+				deployment := fakeDeploymentNotAvailable()
+				deployment.Status.Replicas = 3
+				deployment.Status.UnavailableReplicas = 3
+				fakeClient := test.NewFakeClient(t, deployment)
+				conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs)
+
+				expected := toolchainv1alpha1.Condition{
+					Type:    toolchainv1alpha1.ConditionReady,
+					Status:  corev1.ConditionFalse,
+					Reason:  "DeploymentReplicasUnavailable",
+					Message: "deployment has unavailable replicas: 3 unavailable out of 3",
+				}
+				test.AssertConditionsMatchAndRecentTimestamps(t, conditions, expected)
+			})
+
+			t.Run("while the deployment is available", func(t *testing.T) {
+				deployment := fakeDeploymentReady()
+				deployment.Status.Replicas = 3
+				deployment.Status.UnavailableReplicas = 1
+				fakeClient := test.NewFakeClient(t, deployment)
+				conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs)
+
+				require.NoError(t, ValidateComponentConditionReady(conditions...))
+			})
+		})
+
+		t.Run("deployment pods", func(t *testing.T) {
+			deployment := fakeDeploymentReady()
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test-deployment"}}
+
+			t.Run("running pods", func(t *testing.T) {
+				fakeClient := test.NewFakeClient(t, deployment,
+					newFakePod("test-deployment-1", "test-deployment", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}, 0))
+				conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs, WithCrashLoopingPodsCheck())
+
+				require.NoError(t, ValidateComponentConditionReady(conditions...))
+			})
+
+			t.Run("crash-looping pods of the new ReplicaSet while the deployment is available", func(t *testing.T) {
+				crashLooping := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+				initCrashLooping := newFakePod("test-deployment-3", "test-deployment", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}, 0)
+				initCrashLooping.Status.InitContainerStatuses = []corev1.ContainerStatus{{Name: "init", State: crashLooping, RestartCount: 2}}
+				fakeClient := test.NewFakeClient(t, deployment,
+					newFakePod("test-deployment-1", "test-deployment", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}, 0),
+					newFakePod("test-deployment-2", "test-deployment", crashLooping, 5),
+					initCrashLooping,
+					newFakePod("other-1", "other", crashLooping, 7))
+				conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs, WithCrashLoopingPodsCheck())
+
+				expected := toolchainv1alpha1.Condition{
+					Type:    toolchainv1alpha1.ConditionReady,
+					Status:  corev1.ConditionFalse,
+					Reason:  "DeploymentPodsCrashLooping",
+					Message: "deployment has crash-looping pods: test-deployment-2/manager (5 restarts), test-deployment-3/init (2 restarts)",
+				}
+				test.AssertConditionsMatchAndRecentTimestamps(t, conditions, expected)
+			})
+
+			t.Run("crash-looping pods are not checked without the option", func(t *testing.T) {
+				crashLooping := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+				fakeClient := test.NewFakeClient(t, deployment, newFakePod("test-deployment-1", "test-deployment", crashLooping, 5))
+				fakeClient.MockList = func(_ context.Context, _ runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					return fmt.Errorf("should not be called")
+				}
+				conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs)
+
+				require.NoError(t, ValidateComponentConditionReady(conditions...))
+			})
+
+			t.Run("unable to list the pods skips the check", func(t *testing.T) {
+				fakeClient := test.NewFakeClient(t, deployment)
+				fakeClient.MockList = func(_ context.Context, _ runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					return fmt.Errorf("mock error")
+				}
+				conditions := GetDeploymentStatusConditions(context.TODO(), fakeClient, "test-deployment", test.HostOperatorNs, WithCrashLoopingPodsCheck())
+
+				require.NoError(t, ValidateComponentConditionReady(conditions...))
+			})
+		})
 	})
 }
 
+func newFakePod(name, app string, state corev1.ContainerState, restartCount int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.HostOperatorNs,
+			Labels:    map[string]string{"app": app},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "manager", State: state, RestartCount: restartCount}},
+		},
+	}
+}
+
 func fakeDeploymentNotAvailable() *appsv1.Deployment {
 	return newFakeDeployment("test-deployment", test.HostOperatorNs, DeploymentNotAvailableCondition(), DeploymentProgressingCondition())
 }
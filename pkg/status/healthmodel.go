@@ -0,0 +1,255 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/client"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	"github.com/go-logr/logr"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ComponentsDegradedReason the reason of the Ready condition when some non-critical components are not ready
+	ComponentsDegradedReason = "ComponentsDegraded"
+	// HealthCheckTimeoutReason the reason of the condition of a component whose checker did not return in time
+	HealthCheckTimeoutReason = "HealthCheckTimeout"
+	// HealthCheckFailedReason the reason of the condition of a component whose checker panicked
+	HealthCheckFailedReason = "HealthCheckFailed"
+
+	// DefaultHealthCheckTimeout the default timeout of the checkers
+	DefaultHealthCheckTimeout = 10 * time.Second
+)
+
+// Severity the health of a component, or of all the components
+type Severity int
+
+const (
+	// SeverityReady the component is ready
+	SeverityReady Severity = iota
+	// SeverityDegraded a non-critical component is not ready. The overall Ready condition is still true (with the
+	// ComponentsDegraded reason), so that the failure of a non-critical component does not make the whole status not ready.
+	SeverityDegraded
+	// SeverityDown a critical component is not ready
+	SeverityDown
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityReady:
+		return "ready"
+	case SeverityDegraded:
+		return "degraded"
+	case SeverityDown:
+		return "down"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// Checker checks the health of a component, and returns its conditions (at least a Ready condition).
+// The checkers must honor the given context, which is cancelled when their timeout expires: a checker which ignores it
+// is reported as timed out, but its goroutine keeps running until it returns.
+type Checker interface {
+	Check(ctx context.Context) []toolchainv1alpha1.Condition
+}
+
+// CheckerFunc a func which implements the Checker interface
+type CheckerFunc func(ctx context.Context) []toolchainv1alpha1.Condition
+
+func (f CheckerFunc) Check(ctx context.Context) []toolchainv1alpha1.Condition {
+	return f(ctx)
+}
+
+// DeploymentChecker returns a Checker of the deployment with the given name and namespace (see GetDeploymentStatusConditions)
+func DeploymentChecker(cl runtimeclient.Client, name, namespace string, options ...DeploymentCheckOption) Checker {
+	return CheckerFunc(func(ctx context.Context) []toolchainv1alpha1.Condition {
+		return GetDeploymentStatusConditions(ctx, cl, name, namespace, options...)
+	})
+}
+
+// ToolchainClusterChecker returns a Checker of the connection to a ToolchainCluster (see GetToolchainClusterConditions)
+func ToolchainClusterChecker(logger logr.Logger, attrs ToolchainClusterAttributes) Checker {
+	return CheckerFunc(func(_ context.Context) []toolchainv1alpha1.Condition {
+		return GetToolchainClusterConditions(logger, attrs)
+	})
+}
+
+// RevisionChecker returns a Checker of the deployed version of the given repo (see VersionCheckManager.CheckDeployedVersionIsUpToDate).
+// The given func returns the conditions of the component set during the previous check, if any.
+func RevisionChecker(mgr *VersionCheckManager, isProd bool, accessTokenKey string, repo client.GitHubRepository, existingConditions func() []toolchainv1alpha1.Condition) Checker {
+	return CheckerFunc(func(ctx context.Context) []toolchainv1alpha1.Condition {
+		var existing []toolchainv1alpha1.Condition
+		if existingConditions != nil {
+			existing = existingConditions()
+		}
+		return []toolchainv1alpha1.Condition{*mgr.CheckDeployedVersionIsUpToDate(ctx, isProd, accessTokenKey, existing, repo)}
+	})
+}
+
+// ComponentOption an option of a component registered in a HealthModel
+type ComponentOption func(*component)
+
+// NonCritical the overall health is degraded (instead of down) when the component is not ready
+func NonCritical() ComponentOption {
+	return func(c *component) {
+		c.critical = false
+	}
+}
+
+// WithTimeout overrides the timeout of the checker of the component
+func WithTimeout(timeout time.Duration) ComponentOption {
+	return func(c *component) {
+		c.timeout = timeout
+	}
+}
+
+type component struct {
+	name     string
+	checker  Checker
+	critical bool
+	timeout  time.Duration
+}
+
+// HealthModel the components whose health is rolled up into an overall health, eg, to build the ToolchainStatus
+type HealthModel struct {
+	components []component
+	timeout    time.Duration
+}
+
+// NewHealthModel returns a new HealthModel whose checkers time out after the given duration (DefaultHealthCheckTimeout if 0)
+func NewHealthModel(timeout time.Duration) *HealthModel {
+	if timeout == 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return &HealthModel{timeout: timeout}
+}
+
+// Register registers a component with the given name and checker. By default, the component is critical, ie, the
+// overall health is down when the component is not ready.
+func (m *HealthModel) Register(name string, checker Checker, options ...ComponentOption) *HealthModel {
+	c := component{
+		name:     name,
+		checker:  checker,
+		critical: true,
+		timeout:  m.timeout,
+	}
+	for _, apply := range options {
+		apply(&c)
+	}
+	m.components = append(m.components, c)
+	return m
+}
+
+// ComponentHealth the health of a component
+type ComponentHealth struct {
+	Name       string
+	Severity   Severity
+	Conditions []toolchainv1alpha1.Condition
+}
+
+// HealthReport the health of all the components, in the order of their registration
+type HealthReport struct {
+	Severity   Severity
+	Components []ComponentHealth
+}
+
+// Component returns the health of the component with the given name
+func (r HealthReport) Component(name string) (ComponentHealth, bool) {
+	for _, c := range r.Components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ComponentHealth{}, false
+}
+
+// ReadyCondition returns the overall Ready condition: true when all the components are ready, true with the
+// `ComponentsDegraded` reason when some non-critical components are not ready (ie, a degraded health does not make
+// the condition false, the callers which need to distinguish it check the reason or the Severity), false otherwise.
+// The message lists the components which are not ready.
+func (r HealthReport) ReadyCondition() toolchainv1alpha1.Condition {
+	var notReady []string
+	for _, c := range r.Components {
+		if c.Severity != SeverityReady {
+			notReady = append(notReady, c.Name)
+		}
+	}
+	msg := fmt.Sprintf("components not ready: [%s]", strings.Join(notReady, ", "))
+	switch r.Severity {
+	case SeverityReady:
+		return *NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason)
+	case SeverityDegraded:
+		cond := NewComponentReadyCondition(ComponentsDegradedReason)
+		cond.Message = msg
+		return *cond
+	default:
+		return *NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusComponentsNotReadyReason, msg)
+	}
+}
+
+// Evaluate runs the checkers of all the components concurrently, and rolls up their health
+func (m *HealthModel) Evaluate(ctx context.Context) HealthReport {
+	start := time.Now()
+	results := make([]chan []toolchainv1alpha1.Condition, len(m.components))
+	for i, c := range m.components {
+		results[i] = make(chan []toolchainv1alpha1.Condition, 1)
+		go func(c component, result chan<- []toolchainv1alpha1.Condition) {
+			defer func() {
+				if r := recover(); r != nil {
+					result <- []toolchainv1alpha1.Condition{*NewComponentErrorCondition(HealthCheckFailedReason, fmt.Sprintf("the health check failed: %v", r))}
+				}
+			}()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			result <- c.checker.Check(checkCtx)
+		}(c, results[i])
+	}
+
+	report := HealthReport{Severity: SeverityReady}
+	for i, c := range m.components {
+		conditions, ok := waitForConditions(results[i], time.Until(start.Add(c.timeout)))
+		if !ok {
+			conditions = []toolchainv1alpha1.Condition{*NewComponentErrorCondition(HealthCheckTimeoutReason, fmt.Sprintf("the health check timed out after %s", c.timeout))}
+		}
+		health := ComponentHealth{
+			Name:       c.name,
+			Severity:   SeverityReady,
+			Conditions: conditions,
+		}
+		if !condition.IsTrue(conditions, toolchainv1alpha1.ConditionReady) {
+			health.Severity = SeverityDegraded
+			if c.critical {
+				health.Severity = SeverityDown
+			}
+		}
+		if health.Severity > report.Severity {
+			report.Severity = health.Severity
+		}
+		report.Components = append(report.Components, health)
+	}
+	return report
+}
+
+// waitForConditions waits for the conditions returned by a checker until the given timeout. Returns false if the checker
+// did not return in time. The conditions are returned if they are available, even if the timeout already expired.
+func waitForConditions(result <-chan []toolchainv1alpha1.Condition, timeout time.Duration) ([]toolchainv1alpha1.Condition, bool) {
+	select {
+	case conditions := <-result:
+		return conditions, true
+	default:
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case conditions := <-result:
+		return conditions, true
+	case <-timer.C:
+		return nil, false
+	}
+}
@@ -0,0 +1,158 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestHealthModel(t *testing.T) {
+	ready := CheckerFunc(func(context.Context) []toolchainv1alpha1.Condition {
+		return []toolchainv1alpha1.Condition{*NewComponentReadyCondition("Ready")}
+	})
+	notReady := CheckerFunc(func(context.Context) []toolchainv1alpha1.Condition {
+		return []toolchainv1alpha1.Condition{*NewComponentErrorCondition("NotReady", "something went wrong")}
+	})
+
+	t.Run("all components ready", func(t *testing.T) {
+		// given
+		model := NewHealthModel(0).
+			Register("host-operator", DeploymentChecker(test.NewFakeClient(t, fakeDeploymentReady()), "test-deployment", test.HostOperatorNs)).
+			Register("registration-service", ready)
+
+		// when
+		report := model.Evaluate(context.TODO())
+
+		// then
+		assert.Equal(t, SeverityReady, report.Severity)
+		require.Len(t, report.Components, 2)
+		assert.Equal(t, "host-operator", report.Components[0].Name)
+		assert.Equal(t, "registration-service", report.Components[1].Name)
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{report.ReadyCondition()}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: toolchainv1alpha1.ToolchainStatusAllComponentsReadyReason,
+		})
+	})
+
+	t.Run("non-critical component not ready", func(t *testing.T) {
+		// given
+		model := NewHealthModel(0).
+			Register("host-operator", ready).
+			Register("revision", notReady, NonCritical())
+
+		// when
+		report := model.Evaluate(context.TODO())
+
+		// then
+		assert.Equal(t, SeverityDegraded, report.Severity)
+		revision, found := report.Component("revision")
+		require.True(t, found)
+		assert.Equal(t, SeverityDegraded, revision.Severity)
+		assert.Equal(t, "something went wrong", revision.Conditions[0].Message)
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{report.ReadyCondition()}, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionTrue,
+			Reason:  ComponentsDegradedReason,
+			Message: "components not ready: [revision]",
+		})
+	})
+
+	t.Run("critical component not ready", func(t *testing.T) {
+		// given
+		model := NewHealthModel(0).
+			Register("host-operator", DeploymentChecker(test.NewFakeClient(t), "test-deployment", test.HostOperatorNs)).
+			Register("revision", notReady, NonCritical()).
+			Register("registration-service", ready)
+
+		// when
+		report := model.Evaluate(context.TODO())
+
+		// then
+		assert.Equal(t, SeverityDown, report.Severity)
+		hostOperator, found := report.Component("host-operator")
+		require.True(t, found)
+		assert.Equal(t, SeverityDown, hostOperator.Severity)
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentNotFoundReason, hostOperator.Conditions[0].Reason)
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{report.ReadyCondition()}, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  toolchainv1alpha1.ToolchainStatusComponentsNotReadyReason,
+			Message: "components not ready: [host-operator, revision]",
+		})
+	})
+
+	t.Run("checkers are evaluated concurrently with timeouts", func(t *testing.T) {
+		// given
+		blocked := CheckerFunc(func(ctx context.Context) []toolchainv1alpha1.Condition {
+			<-ctx.Done()
+			time.Sleep(time.Second) // ignores the cancellation for a while
+			return []toolchainv1alpha1.Condition{*NewComponentReadyCondition("Ready")}
+		})
+		model := NewHealthModel(100*time.Millisecond).
+			Register("slow-1", blocked).
+			Register("slow-2", blocked, NonCritical(), WithTimeout(200*time.Millisecond)).
+			Register("fast", ready)
+
+		// when
+		start := time.Now()
+		report := model.Evaluate(context.TODO())
+
+		// then
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, SeverityDown, report.Severity)
+		slow1, _ := report.Component("slow-1")
+		assert.Equal(t, SeverityDown, slow1.Severity)
+		assert.Equal(t, HealthCheckTimeoutReason, slow1.Conditions[0].Reason)
+		assert.Equal(t, "the health check timed out after 100ms", slow1.Conditions[0].Message)
+		slow2, _ := report.Component("slow-2")
+		assert.Equal(t, SeverityDegraded, slow2.Severity)
+		assert.Equal(t, "the health check timed out after 200ms", slow2.Conditions[0].Message)
+		fast, _ := report.Component("fast")
+		assert.Equal(t, SeverityReady, fast.Severity)
+	})
+
+	t.Run("checker panics", func(t *testing.T) {
+		// given
+		model := NewHealthModel(0).
+			Register("broken", CheckerFunc(func(context.Context) []toolchainv1alpha1.Condition {
+				panic("boom")
+			}))
+
+		// when
+		report := model.Evaluate(context.TODO())
+
+		// then
+		assert.Equal(t, SeverityDown, report.Severity)
+		assert.Equal(t, HealthCheckFailedReason, report.Components[0].Conditions[0].Reason)
+		assert.Equal(t, "the health check failed: boom", report.Components[0].Conditions[0].Message)
+	})
+
+	t.Run("revision checker", func(t *testing.T) {
+		// given
+		mgr := &VersionCheckManager{}
+		model := NewHealthModel(0).
+			Register("revision", RevisionChecker(mgr, false, "", hostOperatorRepo, nil), NonCritical())
+
+		// when
+		report := model.Evaluate(context.TODO())
+
+		// then
+		assert.Equal(t, SeverityReady, report.Severity)
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason, report.Components[0].Conditions[0].Reason)
+	})
+}
+
+func TestSeverityString(t *testing.T) {
+	assert.Equal(t, "ready", SeverityReady.String())
+	assert.Equal(t, "degraded", SeverityDegraded.String())
+	assert.Equal(t, "down", SeverityDown.String())
+	assert.Equal(t, "unknown(5)", Severity(5).String())
+}
@@ -0,0 +1,218 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+
+	"github.com/ghodss/yaml"
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ErrMsgDeploymentIsNotUpToDateWithRelease means that the images of some deployments do not match the release manifest
+	ErrMsgDeploymentIsNotUpToDateWithRelease = "deployment version is not up to date with the release manifest"
+
+	// ReleaseManifestKey the key of the release manifest in a ConfigMap
+	ReleaseManifestKey = "release.yaml"
+)
+
+// ReleaseManifest the expected images of the components of a release
+type ReleaseManifest struct {
+	// Version the version of the release, for information purpose
+	Version string `json:"version,omitempty"`
+	// ReleasedAt the time of the release. If not set, the time the expected digest of a component was first seen is used.
+	ReleasedAt *metav1.Time `json:"releasedAt,omitempty"`
+	// Components the expected images of the components
+	Components []ReleaseComponent `json:"components"`
+}
+
+// ReleaseComponent the expected image of the container of a deployment
+type ReleaseComponent struct {
+	// Deployment the name of the deployment
+	Deployment string `json:"deployment"`
+	// Namespace the namespace of the deployment
+	Namespace string `json:"namespace"`
+	// Container the name of the container. The first container of the deployment if not set.
+	Container string `json:"container,omitempty"`
+	// Digest the expected digest of the image, eg: `sha256:0123...`
+	Digest string `json:"digest"`
+}
+
+// ParseReleaseManifest parses the given release manifest, in YAML or JSON
+func ParseReleaseManifest(content []byte) (ReleaseManifest, error) {
+	manifest := ReleaseManifest{}
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return ReleaseManifest{}, errs.Wrap(err, "unable to parse the release manifest")
+	}
+	for _, c := range manifest.Components {
+		if c.Deployment == "" || c.Namespace == "" || c.Digest == "" {
+			return ReleaseManifest{}, fmt.Errorf("invalid component of the release manifest: the deployment, namespace and digest are required: %+v", c)
+		}
+	}
+	return manifest, nil
+}
+
+// LoadReleaseManifest loads the release manifest from the `release.yaml` key of the ConfigMap with the given namespace and name
+func LoadReleaseManifest(ctx context.Context, cl client.Reader, namespace, name string) (ReleaseManifest, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+		return ReleaseManifest{}, errs.Wrapf(err, "unable to get the '%s' ConfigMap with the release manifest", name)
+	}
+	content, found := configMap.Data[ReleaseManifestKey]
+	if !found {
+		return ReleaseManifest{}, fmt.Errorf("the '%s' ConfigMap has no '%s' key", name, ReleaseManifestKey)
+	}
+	return ParseReleaseManifest([]byte(content))
+}
+
+// ImageDigestCheckManager verifies that the images running in the deployments match the digests of a release manifest.
+// Contrary to the VersionCheckManager, it does not depend on the head of a branch, so it suits the deployments from tags.
+// It is safe for concurrent use.
+type ImageDigestCheckManager struct {
+	Client client.Client
+	// Threshold the delay after the release during which a lagging deployment is not reported. DeploymentThreshold if not set.
+	Threshold time.Duration
+	// mu guards the firstSeenDigests
+	mu sync.Mutex
+	// firstSeenDigests the time the expected digests were first seen, per component
+	firstSeenDigests map[string]firstSeenDigest
+}
+
+type firstSeenDigest struct {
+	digest string
+	time   time.Time
+}
+
+// laggingComponent a component whose running images do not match the expected digest
+type laggingComponent struct {
+	name    string
+	details string
+}
+
+// CheckDeployedImagesAreUpToDate verifies that the pods of the deployments of the given release manifest run the expected
+// image digests. The returned condition lists all the components which still do not run the expected digest after the threshold.
+// The deployments scaled to zero are skipped, and the containers which are not started yet (ie, without image ID) are ignored:
+// a deployment without any started container is reported as lagging (with no running image) after the threshold.
+func (m *ImageDigestCheckManager) CheckDeployedImagesAreUpToDate(ctx context.Context, manifest ReleaseManifest) *toolchainv1alpha1.Condition {
+	threshold := m.Threshold
+	if threshold == 0 {
+		threshold = DeploymentThreshold
+	}
+	var lagging []laggingComponent
+	for _, component := range manifest.Components {
+		running, scaledToZero, err := m.runningDigests(ctx, component)
+		if err != nil {
+			return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason, err.Error())
+		}
+		if scaledToZero {
+			continue
+		}
+		releasedAt := m.releaseTime(manifest, component)
+		if isRunningDigest(running, component.Digest) || time.Now().Before(releasedAt.Add(threshold)) {
+			continue
+		}
+		runningDetails := "no running image"
+		if len(running) > 0 {
+			runningDetails = fmt.Sprintf("running [%s]", strings.Join(running, ","))
+		}
+		lagging = append(lagging, laggingComponent{
+			name: component.Namespace + "/" + component.Deployment,
+			details: fmt.Sprintf("expected %s, %s, expected deployment timestamp: %s",
+				component.Digest, runningDetails, releasedAt.Add(threshold).Format(time.RFC3339)),
+		})
+	}
+	if len(lagging) > 0 {
+		sort.Slice(lagging, func(i, j int) bool {
+			return lagging[i].name < lagging[j].name
+		})
+		msgs := make([]string, len(lagging))
+		for i, c := range lagging {
+			msgs[i] = fmt.Sprintf("%s (%s)", c.name, c.details)
+		}
+		return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason,
+			fmt.Sprintf("%s. lagging components: %s", ErrMsgDeploymentIsNotUpToDateWithRelease, strings.Join(msgs, "; ")))
+	}
+	return NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason)
+}
+
+// releaseTime returns the time of the release or, if the manifest does not provide it, the time when the expected
+// digest of the given component was first seen
+func (m *ImageDigestCheckManager) releaseTime(manifest ReleaseManifest, component ReleaseComponent) time.Time {
+	if manifest.ReleasedAt != nil {
+		return manifest.ReleasedAt.Time
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.firstSeenDigests == nil {
+		m.firstSeenDigests = map[string]firstSeenDigest{}
+	}
+	key := component.Namespace + "/" + component.Deployment + "/" + component.Container
+	if firstSeen, found := m.firstSeenDigests[key]; found && firstSeen.digest == component.Digest {
+		return firstSeen.time
+	}
+	m.firstSeenDigests[key] = firstSeenDigest{digest: component.Digest, time: time.Now()}
+	return m.firstSeenDigests[key].time
+}
+
+// runningDigests returns the sorted digests of the images of the given container, in all the pods of the deployment,
+// ignoring the containers which are not started yet (ie, without image ID). The returned bool is true if the deployment
+// is scaled to zero.
+func (m *ImageDigestCheckManager) runningDigests(ctx context.Context, component ReleaseComponent) ([]string, bool, error) {
+	deployment := &appsv1.Deployment{}
+	if err := m.Client.Get(ctx, types.NamespacedName{Namespace: component.Namespace, Name: component.Deployment}, deployment); err != nil {
+		return nil, false, errs.Wrap(err, ErrMsgCannotGetDeployment)
+	}
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0 {
+		return nil, true, nil
+	}
+	containerName := component.Container
+	if containerName == "" && len(deployment.Spec.Template.Spec.Containers) > 0 {
+		containerName = deployment.Spec.Template.Spec.Containers[0].Name
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, false, errs.Wrapf(err, "invalid selector of the '%s' deployment", component.Deployment)
+	}
+	pods := &corev1.PodList{}
+	if err := m.Client.List(ctx, pods, client.InNamespace(component.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, false, errs.Wrapf(err, "unable to list the pods of the '%s' deployment", component.Deployment)
+	}
+	digests := map[string]bool{}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == containerName && status.ImageID != "" {
+				digests[imageDigest(status.ImageID)] = true
+			}
+		}
+	}
+	running := make([]string, 0, len(digests))
+	for digest := range digests {
+		running = append(running, digest)
+	}
+	sort.Strings(running)
+	return running, false, nil
+}
+
+// isRunningDigest returns true if all the pods run the expected digest
+func isRunningDigest(running []string, expected string) bool {
+	return len(running) == 1 && running[0] == expected
+}
+
+// imageDigest returns the digest of the given image ID, eg: `sha256:0123...` for `quay.io/org/image@sha256:0123...`
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return imageID
+}
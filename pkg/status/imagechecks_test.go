@@ -0,0 +1,324 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParseReleaseManifest(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		// when
+		manifest, err := ParseReleaseManifest([]byte(`
+version: v1.2.3
+releasedAt: "2024-05-01T10:00:00Z"
+components:
+- deployment: host-operator-controller-manager
+  namespace: toolchain-host-operator
+  container: manager
+  digest: sha256:aaaa
+`))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "v1.2.3", manifest.Version)
+		assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), manifest.ReleasedAt.UTC())
+		assert.Equal(t, []ReleaseComponent{{
+			Deployment: "host-operator-controller-manager",
+			Namespace:  "toolchain-host-operator",
+			Container:  "manager",
+			Digest:     "sha256:aaaa",
+		}}, manifest.Components)
+	})
+
+	t.Run("missing digest", func(t *testing.T) {
+		// when
+		_, err := ParseReleaseManifest([]byte(`{"components": [{"deployment": "host-operator", "namespace": "toolchain-host-operator"}]}`))
+
+		// then
+		require.ErrorContains(t, err, "invalid component of the release manifest: the deployment, namespace and digest are required")
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		// when
+		_, err := ParseReleaseManifest([]byte(`components: "not a list"`))
+
+		// then
+		require.ErrorContains(t, err, "unable to parse the release manifest")
+	})
+}
+
+func TestLoadReleaseManifest(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: test.HostOperatorNs},
+			Data: map[string]string{
+				ReleaseManifestKey: `{"version": "v1.2.3", "components": [{"deployment": "host-operator", "namespace": "toolchain-host-operator", "digest": "sha256:aaaa"}]}`,
+			},
+		})
+
+		// when
+		manifest, err := LoadReleaseManifest(context.TODO(), cl, test.HostOperatorNs, "release")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "v1.2.3", manifest.Version)
+		assert.Len(t, manifest.Components, 1)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: test.HostOperatorNs},
+		})
+
+		// when
+		_, err := LoadReleaseManifest(context.TODO(), cl, test.HostOperatorNs, "release")
+
+		// then
+		require.EqualError(t, err, "the 'release' ConfigMap has no 'release.yaml' key")
+	})
+
+	t.Run("ConfigMap not found", func(t *testing.T) {
+		// when
+		_, err := LoadReleaseManifest(context.TODO(), test.NewFakeClient(t), test.HostOperatorNs, "release")
+
+		// then
+		require.ErrorContains(t, err, "unable to get the 'release' ConfigMap with the release manifest")
+	})
+}
+
+func TestCheckDeployedImagesAreUpToDate(t *testing.T) {
+	releasedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+	manifest := ReleaseManifest{
+		Version:    "v1.2.3",
+		ReleasedAt: &releasedAt,
+		Components: []ReleaseComponent{
+			{Deployment: "host-operator", Namespace: test.HostOperatorNs, Container: "manager", Digest: "sha256:aaaa"},
+			{Deployment: "registration-service", Namespace: test.HostOperatorNs, Digest: "sha256:bbbb"},
+		},
+	}
+
+	t.Run("all components are up to date", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t,
+			newDeployment("host-operator", "kube-rbac-proxy", "manager"),
+			newPod("host-operator-1", "host-operator", map[string]string{"kube-rbac-proxy": "quay.io/brancz/kube-rbac-proxy@sha256:cccc", "manager": "quay.io/codeready-toolchain/host-operator@sha256:aaaa"}),
+			newDeployment("registration-service", "registration-service"),
+			newPod("registration-service-1", "registration-service", map[string]string{"registration-service": "docker-pullable://quay.io/codeready-toolchain/registration-service@sha256:bbbb"}),
+			newPod("registration-service-2", "registration-service", map[string]string{"registration-service": "sha256:bbbb"}))
+		mgr := ImageDigestCheckManager{Client: cl}
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), manifest)
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{*cond}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason,
+		})
+	})
+
+	t.Run("lagging components are reported", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t,
+			newDeployment("host-operator", "manager"),
+			newPod("host-operator-1", "host-operator", map[string]string{"manager": "quay.io/codeready-toolchain/host-operator@sha256:0000"}),
+			newDeployment("registration-service", "registration-service"),
+			newPod("registration-service-1", "registration-service", map[string]string{"registration-service": "quay.io/codeready-toolchain/registration-service@sha256:bbbb"}),
+			newPod("registration-service-2", "registration-service", map[string]string{"registration-service": "quay.io/codeready-toolchain/registration-service@sha256:0000"}))
+		mgr := ImageDigestCheckManager{Client: cl}
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), manifest)
+
+		// then
+		expectedTime := releasedAt.Add(DeploymentThreshold).Format(time.RFC3339)
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{*cond}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason,
+			Message: fmt.Sprintf("deployment version is not up to date with the release manifest. lagging components: "+
+				"toolchain-host-operator/host-operator (expected sha256:aaaa, running [sha256:0000], expected deployment timestamp: %[1]s); "+
+				"toolchain-host-operator/registration-service (expected sha256:bbbb, running [sha256:0000,sha256:bbbb], expected deployment timestamp: %[1]s)", expectedTime),
+		})
+
+		t.Run("not reported within the configured threshold", func(t *testing.T) {
+			// given
+			mgr := ImageDigestCheckManager{Client: cl, Threshold: 2 * time.Hour}
+
+			// when
+			cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), manifest)
+
+			// then
+			assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+		})
+
+		t.Run("not reported within the threshold after the digest was first seen", func(t *testing.T) {
+			// given
+			withoutReleaseTime := manifest
+			withoutReleaseTime.ReleasedAt = nil
+
+			// when
+			cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), withoutReleaseTime)
+
+			// then
+			assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+
+			t.Run("reported after the threshold", func(t *testing.T) {
+				// given
+				for key, firstSeen := range mgr.firstSeenDigests {
+					firstSeen.time = time.Now().Add(-DeploymentThreshold - time.Minute)
+					mgr.firstSeenDigests[key] = firstSeen
+				}
+
+				// when
+				cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), withoutReleaseTime)
+
+				// then
+				assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, cond.Reason)
+			})
+		})
+	})
+
+	t.Run("deployment without pods is lagging", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newDeployment("host-operator", "manager"))
+		mgr := ImageDigestCheckManager{Client: cl}
+		singleComponent := manifest
+		singleComponent.Components = manifest.Components[:1]
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), singleComponent)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, cond.Reason)
+		assert.Contains(t, cond.Message, "toolchain-host-operator/host-operator (expected sha256:aaaa, no running image,")
+	})
+
+	t.Run("containers which are not started are ignored", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t,
+			newDeployment("host-operator", "manager"),
+			newPod("host-operator-1", "host-operator", map[string]string{"manager": "quay.io/codeready-toolchain/host-operator@sha256:aaaa"}),
+			newPod("host-operator-2", "host-operator", map[string]string{"manager": ""}))
+		mgr := ImageDigestCheckManager{Client: cl}
+		singleComponent := manifest
+		singleComponent.Components = manifest.Components[:1]
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), singleComponent)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+	})
+
+	t.Run("deployment scaled to zero is skipped", func(t *testing.T) {
+		// given
+		deployment := newDeployment("host-operator", "manager")
+		deployment.Spec.Replicas = ptr.To[int32](0)
+		cl := test.NewFakeClient(t, deployment)
+		mgr := ImageDigestCheckManager{Client: cl}
+		singleComponent := manifest
+		singleComponent.Components = manifest.Components[:1]
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), singleComponent)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+	})
+
+	t.Run("concurrent checks", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newDeployment("host-operator", "manager"))
+		mgr := &ImageDigestCheckManager{Client: cl}
+		withoutReleaseTime := manifest
+		withoutReleaseTime.ReleasedAt = nil
+		withoutReleaseTime.Components = manifest.Components[:1]
+		var wg sync.WaitGroup
+
+		// when
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mgr.CheckDeployedImagesAreUpToDate(context.TODO(), withoutReleaseTime)
+			}()
+		}
+		wg.Wait()
+
+		// then
+		assert.Len(t, mgr.firstSeenDigests, 1)
+	})
+
+	t.Run("deployment not found", func(t *testing.T) {
+		// given
+		mgr := ImageDigestCheckManager{Client: test.NewFakeClient(t)}
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), manifest)
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{*cond}, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason,
+			Message: `unable to get the deployment: deployments.apps "host-operator" not found`,
+		})
+	})
+
+	t.Run("unable to list the pods", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newDeployment("host-operator", "manager"))
+		cl.MockList = func(_ context.Context, _ runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+			return fmt.Errorf("mock error")
+		}
+		mgr := ImageDigestCheckManager{Client: cl}
+
+		// when
+		cond := mgr.CheckDeployedImagesAreUpToDate(context.TODO(), manifest)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckOperatorErrorReason, cond.Reason)
+		assert.Equal(t, "unable to list the pods of the 'host-operator' deployment: mock error", cond.Message)
+	})
+}
+
+func newDeployment(name string, containers ...string) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: test.HostOperatorNs},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+	}
+	for _, c := range containers {
+		deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, corev1.Container{Name: c})
+	}
+	return deployment
+}
+
+func newPod(name, app string, imageIDs map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: test.HostOperatorNs, Labels: map[string]string{"app": app}},
+	}
+	for container, imageID := range imageIDs {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{Name: container, ImageID: imageID})
+	}
+	return pod
+}
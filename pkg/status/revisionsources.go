@@ -0,0 +1,318 @@
+package status
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/client"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Revision the latest revision of a branch of a source code repository
+type Revision struct {
+	// SHA the commit SHA of the revision
+	SHA string
+	// Timestamp the time of the commit. Zero if the source does not provide it.
+	Timestamp time.Time
+}
+
+// RevisionSource provides the latest revision of a branch of a source code repository
+type RevisionSource interface {
+	// Name the name of the source (eg: `github`), used in the messages of the conditions
+	Name() string
+	LatestRevision(ctx context.Context, repo client.GitHubRepository) (Revision, error)
+}
+
+// gitHubRevisionSource retrieves the latest revision from the GitHub commits API
+type gitHubRevisionSource struct {
+	getGithubClientFunc client.GetGitHubClientFunc
+	accessToken         string
+}
+
+// NewGitHubRevisionSource returns a RevisionSource which retrieves the latest revision from the GitHub commits API
+func NewGitHubRevisionSource(getGithubClientFunc client.GetGitHubClientFunc, accessToken string) RevisionSource {
+	return gitHubRevisionSource{
+		getGithubClientFunc: getGithubClientFunc,
+		accessToken:         accessToken,
+	}
+}
+
+func (s gitHubRevisionSource) Name() string {
+	return "github"
+}
+
+func (s gitHubRevisionSource) LatestRevision(ctx context.Context, repo client.GitHubRepository) (Revision, error) {
+	githubClient := s.getGithubClientFunc(ctx, s.accessToken)
+	latestCommit, err := getLatestCommit(ctx, githubClient.Repositories.GetCommit, repo)
+	if err != nil {
+		return Revision{}, err
+	}
+	return Revision{
+		SHA:       latestCommit.GetSHA(),
+		Timestamp: latestCommit.GetCommit().GetAuthor().GetDate().Time,
+	}, nil
+}
+
+// gitLabRevisionSource retrieves the latest revision from the GitLab commits API
+type gitLabRevisionSource struct {
+	baseURL     string
+	accessToken string
+	httpClient  *http.Client
+}
+
+// NewGitLabRevisionSource returns a RevisionSource which retrieves the latest revision from the commits API of the GitLab
+// instance at the given URL (eg: `https://gitlab.com`). The project of a repository is `<Org>/<Name>`.
+// The access token is optional.
+func NewGitLabRevisionSource(baseURL, accessToken string, httpClient *http.Client) RevisionSource {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return gitLabRevisionSource{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		accessToken: accessToken,
+		httpClient:  httpClient,
+	}
+}
+
+func (s gitLabRevisionSource) Name() string {
+	return "gitlab"
+}
+
+func (s gitLabRevisionSource) LatestRevision(ctx context.Context, repo client.GitHubRepository) (Revision, error) {
+	commitURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s", s.baseURL, url.PathEscape(repo.Org+"/"+repo.Name), url.PathEscape(repo.Branch))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, commitURL, nil)
+	if err != nil {
+		return Revision{}, err
+	}
+	if s.accessToken != "" {
+		req.Header.Set("PRIVATE-TOKEN", s.accessToken)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return Revision{}, err
+	}
+	defer closeBody(ctx, resp)
+	if resp.StatusCode != http.StatusOK {
+		return Revision{}, fmt.Errorf("invalid response code from gitlab commits API. resp.Response.StatusCode: %d, repoName: %s, repoBranch: %s", resp.StatusCode, repo.Name, repo.Branch)
+	}
+	commit := struct {
+		ID            string    `json:"id"`
+		CommittedDate time.Time `json:"committed_date"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&commit); err != nil {
+		return Revision{}, errs.Wrapf(err, "unable to read the response of the gitlab commits API. repoName: %s, repoBranch: %s", repo.Name, repo.Branch)
+	}
+	if commit.ID == "" {
+		return Revision{}, fmt.Errorf("no commits returned. repoName: %s, repoBranch: %s", repo.Name, repo.Branch)
+	}
+	return Revision{SHA: commit.ID, Timestamp: commit.CommittedDate}, nil
+}
+
+// gitHTTPRevisionSource retrieves the latest revision from a git server, using the smart HTTP protocol
+type gitHTTPRevisionSource struct {
+	baseURL     string
+	username    string
+	accessToken string
+	httpClient  *http.Client
+}
+
+// NewGitHTTPRevisionSource returns a RevisionSource which retrieves the latest revision from the refs advertised by
+// the git server at the given URL, ie, the URL of a repository is `<baseURL>/<Org>/<Name>.git`.
+// The credentials are optional. Since the refs do not provide the time of the commits, the revisions have no timestamp.
+func NewGitHTTPRevisionSource(baseURL, username, accessToken string, httpClient *http.Client) RevisionSource {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return gitHTTPRevisionSource{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		username:    username,
+		accessToken: accessToken,
+		httpClient:  httpClient,
+	}
+}
+
+func (s gitHTTPRevisionSource) Name() string {
+	return "git"
+}
+
+func (s gitHTTPRevisionSource) LatestRevision(ctx context.Context, repo client.GitHubRepository) (Revision, error) {
+	refsURL := fmt.Sprintf("%s/%s/%s.git/info/refs?service=git-upload-pack", s.baseURL, url.PathEscape(repo.Org), url.PathEscape(repo.Name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, refsURL, nil)
+	if err != nil {
+		return Revision{}, err
+	}
+	if s.accessToken != "" {
+		req.SetBasicAuth(s.username, s.accessToken)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return Revision{}, err
+	}
+	defer closeBody(ctx, resp)
+	if resp.StatusCode != http.StatusOK {
+		return Revision{}, fmt.Errorf("invalid response code from git server. resp.Response.StatusCode: %d, repoName: %s, repoBranch: %s", resp.StatusCode, repo.Name, repo.Branch)
+	}
+	refs, err := parseAdvertisedRefs(resp.Body)
+	if err != nil {
+		return Revision{}, errs.Wrapf(err, "unable to read the refs of the git server. repoName: %s, repoBranch: %s", repo.Name, repo.Branch)
+	}
+	ref := repo.Branch
+	if ref != "HEAD" && !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+	sha, found := refs[ref]
+	if !found {
+		return Revision{}, fmt.Errorf("no commits returned. repoName: %s, repoBranch: %s", repo.Name, repo.Branch)
+	}
+	return Revision{SHA: sha}, nil
+}
+
+// parseAdvertisedRefs parses the refs advertised by a git server, ie, pkt-lines such as `<sha> <ref>[\x00<capabilities>]`,
+// and returns the SHAs indexed by ref
+func parseAdvertisedRefs(r io.Reader) (map[string]string, error) {
+	refs := map[string]string{}
+	reader := bufio.NewReader(r)
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF { //nolint:errorlint
+				return refs, nil
+			}
+			return nil, err
+		}
+		length, err := strconv.ParseUint(string(header), 16, 16)
+		if err != nil {
+			return nil, errs.Wrapf(err, "invalid pkt-line length '%s'", string(header))
+		}
+		if length < 4 {
+			// flush packet
+			continue
+		}
+		payload := make([]byte, length-4)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, err
+		}
+		line := strings.TrimSuffix(string(payload), "\n")
+		if strings.HasPrefix(line, "#") {
+			// service announcement
+			continue
+		}
+		line, _, _ = strings.Cut(line, "\x00")
+		if sha, ref, found := strings.Cut(line, " "); found {
+			refs[ref] = sha
+		}
+	}
+}
+
+const (
+	// RevisionSHAKeySuffix the suffix of the key which contains the SHA of the latest revision of a repository,
+	// in a ConfigMap or a directory (eg: `host-operator.sha`)
+	RevisionSHAKeySuffix = ".sha"
+	// RevisionTimestampKeySuffix the suffix of the optional key which contains the time of the latest revision of a
+	// repository, in the RFC3339 format, in a ConfigMap or a directory (eg: `host-operator.timestamp`)
+	RevisionTimestampKeySuffix = ".timestamp"
+)
+
+// configMapRevisionSource reads the latest revisions from a ConfigMap
+type configMapRevisionSource struct {
+	cl        runtimeclient.Reader
+	namespace string
+	name      string
+}
+
+// NewConfigMapRevisionSource returns a RevisionSource which reads the latest revisions from the ConfigMap with the given
+// namespace and name, eg, for disconnected environments. The SHA of a repository is in the `<Name>.sha` key, and the
+// time of the revision in the optional `<Name>.timestamp` key.
+func NewConfigMapRevisionSource(cl runtimeclient.Reader, namespace, name string) RevisionSource {
+	return configMapRevisionSource{
+		cl:        cl,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s configMapRevisionSource) Name() string {
+	return "configmap"
+}
+
+func (s configMapRevisionSource) LatestRevision(ctx context.Context, repo client.GitHubRepository) (Revision, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := s.cl.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: s.name}, configMap); err != nil {
+		return Revision{}, errs.Wrapf(err, "unable to get the '%s' ConfigMap with the revisions", s.name)
+	}
+	return revisionFromValues(repo, func(key string) (string, bool, error) {
+		value, found := configMap.Data[key]
+		return value, found, nil
+	})
+}
+
+// fileRevisionSource reads the latest revisions from the files of a directory
+type fileRevisionSource struct {
+	dir string
+}
+
+// NewFileRevisionSource returns a RevisionSource which reads the latest revisions from the files of the given directory
+// (eg: a mounted ConfigMap), with the same keys as NewConfigMapRevisionSource
+func NewFileRevisionSource(dir string) RevisionSource {
+	return fileRevisionSource{dir: dir}
+}
+
+func (s fileRevisionSource) Name() string {
+	return "file"
+}
+
+func (s fileRevisionSource) LatestRevision(_ context.Context, repo client.GitHubRepository) (Revision, error) {
+	return revisionFromValues(repo, func(key string) (string, bool, error) {
+		content, err := os.ReadFile(filepath.Join(s.dir, key))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+		return string(content), true, nil
+	})
+}
+
+func revisionFromValues(repo client.GitHubRepository, lookup func(key string) (string, bool, error)) (Revision, error) {
+	sha, found, err := lookup(repo.Name + RevisionSHAKeySuffix)
+	if err != nil {
+		return Revision{}, err
+	}
+	sha = strings.TrimSpace(sha)
+	if !found || sha == "" {
+		return Revision{}, fmt.Errorf("no commits returned. repoName: %s, repoBranch: %s", repo.Name, repo.Branch)
+	}
+	revision := Revision{SHA: sha}
+	timestamp, found, err := lookup(repo.Name + RevisionTimestampKeySuffix)
+	if err != nil {
+		return Revision{}, err
+	}
+	if found {
+		if revision.Timestamp, err = time.Parse(time.RFC3339, strings.TrimSpace(timestamp)); err != nil {
+			return Revision{}, errs.Wrapf(err, "invalid timestamp of the latest revision. repoName: %s", repo.Name)
+		}
+	}
+	return revision, nil
+}
+
+func closeBody(ctx context.Context, resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		logger.FromContext(ctx).Error(err, "unable to close response body")
+	}
+}
@@ -0,0 +1,420 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/client"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var hostOperatorRepo = client.GitHubRepository{
+	Org:               toolchainv1alpha1.ProviderLabelValue,
+	Name:              "host-operator",
+	Branch:            "master",
+	DeployedCommitSHA: "1234abcd",
+}
+
+func TestGitHubRevisionSource(t *testing.T) {
+	// given
+	commitTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	source := NewGitHubRevisionSource(test.MockGitHubClientForRepositoryCommits("1234abcd", commitTime), "githubToken")
+
+	// when
+	revision, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "1234abcd", revision.SHA)
+	assert.True(t, commitTime.Equal(revision.Timestamp))
+}
+
+func TestGitLabRevisionSource(t *testing.T) {
+	commitTime := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/projects/codeready-toolchain%2Fhost-operator/repository/commits/master", r.URL.EscapedPath())
+			assert.Equal(t, "gitlabToken", r.Header.Get("PRIVATE-TOKEN"))
+			_, _ = fmt.Fprintf(w, `{"id": "1234abcd", "committed_date": "%s"}`, commitTime.Format(time.RFC3339))
+		}))
+		defer server.Close()
+		source := NewGitLabRevisionSource(server.URL, "gitlabToken", server.Client())
+
+		// when
+		revision, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Revision{SHA: "1234abcd", Timestamp: commitTime}, revision)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		source := NewGitLabRevisionSource(server.URL, "", server.Client())
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.EqualError(t, err, "invalid response code from gitlab commits API. resp.Response.StatusCode: 404, repoName: host-operator, repoBranch: master")
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`not json`))
+		}))
+		defer server.Close()
+		source := NewGitLabRevisionSource(server.URL, "", server.Client())
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.ErrorContains(t, err, "unable to read the response of the gitlab commits API. repoName: host-operator, repoBranch: master")
+	})
+}
+
+func TestGitHTTPRevisionSource(t *testing.T) {
+	refs := pktLine("# service=git-upload-pack\n") + "0000" +
+		pktLine("1111aaaa HEAD\x00multi_ack symref=HEAD:refs/heads/master\n") +
+		pktLine("1234abcd refs/heads/master\n") +
+		pktLine("5678efgh refs/heads/feature\n") +
+		"0000"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/codeready-toolchain/host-operator.git/info/refs" || r.URL.Query().Get("service") != "git-upload-pack" {
+			http.NotFound(w, r)
+			return
+		}
+		if username, password, ok := r.BasicAuth(); ok && (username != "john" || password != "gitToken") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		_, _ = w.Write([]byte(refs))
+	}))
+	defer server.Close()
+
+	t.Run("branch", func(t *testing.T) {
+		// given
+		source := NewGitHTTPRevisionSource(server.URL, "john", "gitToken", server.Client())
+
+		// when
+		revision, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Revision{SHA: "1234abcd"}, revision)
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		// given
+		source := NewGitHTTPRevisionSource(server.URL, "", "", server.Client())
+		repo := hostOperatorRepo
+		repo.Branch = "HEAD"
+
+		// when
+		revision, err := source.LatestRevision(context.TODO(), repo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Revision{SHA: "1111aaaa"}, revision)
+	})
+
+	t.Run("unknown branch", func(t *testing.T) {
+		// given
+		source := NewGitHTTPRevisionSource(server.URL, "", "", server.Client())
+		repo := hostOperatorRepo
+		repo.Branch = "unknown"
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), repo)
+
+		// then
+		require.EqualError(t, err, "no commits returned. repoName: host-operator, repoBranch: unknown")
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		// given
+		source := NewGitHTTPRevisionSource(server.URL, "john", "wrong", server.Client())
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.EqualError(t, err, "invalid response code from git server. resp.Response.StatusCode: 401, repoName: host-operator, repoBranch: master")
+	})
+
+	t.Run("invalid refs", func(t *testing.T) {
+		// when
+		_, err := parseAdvertisedRefs(strings.NewReader("zzzz"))
+
+		// then
+		require.EqualError(t, err, "invalid pkt-line length 'zzzz': strconv.ParseUint: parsing \"zzzz\": invalid syntax")
+	})
+}
+
+func TestConfigMapRevisionSource(t *testing.T) {
+	commitTime := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "revisions",
+			Namespace: test.HostOperatorNs,
+		},
+		Data: map[string]string{
+			"host-operator.sha":              "1234abcd\n",
+			"host-operator.timestamp":        commitTime.Format(time.RFC3339),
+			"member-operator.sha":            "5678efgh",
+			"registration-service.sha":       "9999ffff",
+			"registration-service.timestamp": "yesterday",
+		},
+	}
+
+	t.Run("with timestamp", func(t *testing.T) {
+		// given
+		source := NewConfigMapRevisionSource(test.NewFakeClient(t, configMap), test.HostOperatorNs, "revisions")
+
+		// when
+		revision, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Revision{SHA: "1234abcd", Timestamp: commitTime}, revision)
+	})
+
+	t.Run("without timestamp", func(t *testing.T) {
+		// given
+		source := NewConfigMapRevisionSource(test.NewFakeClient(t, configMap), test.HostOperatorNs, "revisions")
+		repo := hostOperatorRepo
+		repo.Name = "member-operator"
+
+		// when
+		revision, err := source.LatestRevision(context.TODO(), repo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Revision{SHA: "5678efgh"}, revision)
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		// given
+		source := NewConfigMapRevisionSource(test.NewFakeClient(t, configMap), test.HostOperatorNs, "revisions")
+		repo := hostOperatorRepo
+		repo.Name = "registration-service"
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), repo)
+
+		// then
+		require.ErrorContains(t, err, "invalid timestamp of the latest revision. repoName: registration-service")
+	})
+
+	t.Run("unknown repo", func(t *testing.T) {
+		// given
+		source := NewConfigMapRevisionSource(test.NewFakeClient(t, configMap), test.HostOperatorNs, "revisions")
+		repo := hostOperatorRepo
+		repo.Name = "unknown"
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), repo)
+
+		// then
+		require.EqualError(t, err, "no commits returned. repoName: unknown, repoBranch: master")
+	})
+
+	t.Run("ConfigMap not found", func(t *testing.T) {
+		// given
+		source := NewConfigMapRevisionSource(test.NewFakeClient(t), test.HostOperatorNs, "revisions")
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.ErrorContains(t, err, "unable to get the 'revisions' ConfigMap with the revisions")
+	})
+}
+
+func TestFileRevisionSource(t *testing.T) {
+	// given
+	commitTime := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "host-operator.sha"), []byte("1234abcd\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "host-operator.timestamp"), []byte(commitTime.Format(time.RFC3339)), 0600))
+	source := NewFileRevisionSource(dir)
+
+	t.Run("success", func(t *testing.T) {
+		// when
+		revision, err := source.LatestRevision(context.TODO(), hostOperatorRepo)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Revision{SHA: "1234abcd", Timestamp: commitTime}, revision)
+	})
+
+	t.Run("unknown repo", func(t *testing.T) {
+		// given
+		repo := hostOperatorRepo
+		repo.Name = "unknown"
+
+		// when
+		_, err := source.LatestRevision(context.TODO(), repo)
+
+		// then
+		require.EqualError(t, err, "no commits returned. repoName: unknown, repoBranch: master")
+	})
+}
+
+func TestCheckDeployedVersionWithRevisionSource(t *testing.T) {
+	t.Run("check is enabled without access token", func(t *testing.T) {
+		// given
+		mgr := VersionCheckManager{RevisionSource: fakeRevisionSource{revision: Revision{SHA: "1234abcd", Timestamp: time.Now()}}}
+
+		// when
+		cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{*cond}, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason,
+		})
+	})
+
+	t.Run("error from the source", func(t *testing.T) {
+		// given
+		mgr := VersionCheckManager{RevisionSource: fakeRevisionSource{err: fmt.Errorf("unable to reach the git server")}}
+
+		// when
+		cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, []toolchainv1alpha1.Condition{*cond}, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  DeploymentRevisionCheckSourceErrorReason,
+			Message: "unable to reach the git server",
+		})
+	})
+
+	t.Run("calls to the source are not throttled", func(t *testing.T) {
+		// given
+		mgr := VersionCheckManager{RevisionSource: fakeRevisionSource{revision: Revision{SHA: "1234abcd", Timestamp: time.Now()}}}
+		previous := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+		mgr.RevisionSource = fakeRevisionSource{err: fmt.Errorf("unable to reach the git server")}
+
+		// when
+		cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", []toolchainv1alpha1.Condition{*previous}, hostOperatorRepo)
+
+		// then
+		assert.Equal(t, DeploymentRevisionCheckSourceErrorReason, cond.Reason)
+	})
+
+	t.Run("configured threshold", func(t *testing.T) {
+		// given
+		mgr := VersionCheckManager{
+			RevisionSource: fakeRevisionSource{revision: Revision{SHA: "5678efgh", Timestamp: time.Now().Add(-time.Hour)}},
+			Threshold:      2 * time.Hour,
+		}
+
+		// when
+		cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+	})
+
+	t.Run("revision without timestamp", func(t *testing.T) {
+		// given
+		mgr := VersionCheckManager{RevisionSource: fakeRevisionSource{revision: Revision{SHA: "5678efgh"}}}
+
+		t.Run("first seen within the threshold", func(t *testing.T) {
+			// when
+			cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+
+			// then
+			assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+		})
+
+		t.Run("first seen before the threshold", func(t *testing.T) {
+			// given
+			mgr.firstSeenRevisions["host-operator"] = Revision{SHA: "5678efgh", Timestamp: time.Now().Add(-DeploymentThreshold - time.Minute)}
+
+			// when
+			cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+
+			// then
+			assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, cond.Reason)
+			assert.Contains(t, cond.Message, "deployment version is not up to date with latest fake commit SHA. deployed commit SHA 1234abcd ,fake latest SHA 5678efgh")
+		})
+
+		t.Run("new revision is seen", func(t *testing.T) {
+			// given
+			mgr.RevisionSource = fakeRevisionSource{revision: Revision{SHA: "9999ffff"}}
+
+			// when
+			cond := mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, hostOperatorRepo)
+
+			// then
+			assert.Equal(t, toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason, cond.Reason)
+			assert.Equal(t, "9999ffff", mgr.firstSeenRevisions["host-operator"].SHA)
+		})
+	})
+}
+
+func TestCheckDeployedVersionConcurrently(t *testing.T) {
+	// given
+	mgr := &VersionCheckManager{RevisionSource: fakeRevisionSource{revision: Revision{SHA: "1234abcd"}}}
+	var wg sync.WaitGroup
+
+	// when
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			repo := hostOperatorRepo
+			repo.Name = fmt.Sprintf("repo-%d", i%4)
+			mgr.CheckDeployedVersionIsUpToDate(context.TODO(), true, "", nil, repo)
+		}(i)
+	}
+	wg.Wait()
+
+	// then
+	_, found := mgr.LastGHCall("repo-0")
+	assert.False(t, found) // only the calls to GitHub are throttled
+	assert.Len(t, mgr.firstSeenRevisions, 4)
+}
+
+type fakeRevisionSource struct {
+	revision Revision
+	err      error
+}
+
+func (s fakeRevisionSource) Name() string {
+	return "fake"
+}
+
+func (s fakeRevisionSource) LatestRevision(_ context.Context, _ client.GitHubRepository) (Revision, error) {
+	return s.revision, s.err
+}
+
+func pktLine(payload string) string {
+	return fmt.Sprintf("%04x%s", len(payload)+4, payload)
+}
@@ -15,13 +15,26 @@ import (
 const (
 	ErrMsgClusterConnectionNotFound              = "the cluster connection was not found"
 	ErrMsgClusterConnectionLastProbeTimeExceeded = "exceeded the maximum duration since the last probe"
+	ErrMsgClusterConnectionIntermittent          = "the cluster connection is intermittent"
+	ErrMsgClusterConnectionDown                  = "the cluster connection is down"
 )
 
+// ToolchainClusterConnectionIntermittentReason the reason of the condition when some of the recent probes of the
+// cluster connection failed, but not all of them
+const ToolchainClusterConnectionIntermittentReason = "ToolchainClusterConnectionIntermittent"
+
 // ToolchainClusterAttributes required attributes for obtaining ToolchainCluster status
 type ToolchainClusterAttributes struct {
 	GetClusterFunc func() (*cluster.CachedToolchainCluster, bool)
 	Period         time.Duration
 	Timeout        time.Duration
+	// AvailabilityWindow the window of the probe history used to compute the availability of the cluster connection.
+	// The probe history is not used if not set. The history must be sized for this window (see the AvailabilityWindow
+	// of the ToolchainCluster Reconciler), otherwise the conditions mention that the window is truncated.
+	AvailabilityWindow time.Duration
+	// MinAvailability the minimum ratio (between 0 and 1) of healthy probes within the AvailabilityWindow, under which
+	// the cluster connection is reported as intermittent even if the last probe was healthy
+	MinAvailability float64
 }
 
 // GetToolchainClusterConditions uses the provided ToolchainCluster attributes to determine status conditions
@@ -34,13 +47,14 @@ func GetToolchainClusterConditions(logger logr.Logger, attrs ToolchainClusterAtt
 
 	// check conditions of cluster connection
 	if !cluster.IsReady(toolchainCluster.ClusterStatus) {
+		errMsg := "the cluster connection is not ready"
 		for _, c := range toolchainCluster.ClusterStatus.Conditions {
 			if c.Type == "Ready" && c.Message != "" {
-				return []toolchainv1alpha1.Condition{*NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionNotReadyReason, c.Message)}
+				errMsg = c.Message
+				break
 			}
 		}
-		genericErrMsg := "the cluster connection is not ready"
-		return []toolchainv1alpha1.Condition{*NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionNotReadyReason, genericErrMsg)}
+		return []toolchainv1alpha1.Condition{*clusterNotReadyCondition(toolchainCluster, attrs, errMsg)}
 	}
 
 	var lastUpdatedTime metav1.Time
@@ -64,5 +78,49 @@ func GetToolchainClusterConditions(logger logr.Logger, attrs ToolchainClusterAtt
 		logger.Error(err, fmt.Sprintf("the last probe for %s happened before: %s, see: %+v", toolchainCluster.Name, timeSinceLastProbe.String(), toolchainCluster.ClusterStatus))
 		return []toolchainv1alpha1.Condition{*NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionLastProbeTimeExceededReason, err.Error())}
 	}
-	return []toolchainv1alpha1.Condition{*NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionReadyReason)}
+	readyCondition := NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionReadyReason)
+	if availability, probes, window, ok := clusterAvailability(toolchainCluster, attrs); ok && probes > 0 {
+		if availability < attrs.MinAvailability {
+			err := fmt.Errorf("%s (%.0f%% healthy probes in the last %s, expected at least %.0f%%)", ErrMsgClusterConnectionIntermittent, availability*100, window, attrs.MinAvailability*100)
+			return []toolchainv1alpha1.Condition{*NewComponentErrorCondition(ToolchainClusterConnectionIntermittentReason, err.Error())}
+		}
+		readyCondition.Message = fmt.Sprintf("%.0f%% healthy probes in the last %s", availability*100, window)
+	}
+	return []toolchainv1alpha1.Condition{*readyCondition}
+}
+
+// clusterNotReadyCondition returns the condition of a cluster connection which is not ready. If the probe history is
+// used, the condition distinguishes an intermittent connection (some recent probes were healthy) from a dead one.
+func clusterNotReadyCondition(toolchainCluster *cluster.CachedToolchainCluster, attrs ToolchainClusterAttributes, errMsg string) *toolchainv1alpha1.Condition {
+	availability, probes, window, ok := clusterAvailability(toolchainCluster, attrs)
+	switch {
+	case !ok || probes == 0:
+		return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionNotReadyReason, errMsg)
+	case availability > 0:
+		err := fmt.Errorf("%s (%.0f%% healthy probes in the last %s): %s", ErrMsgClusterConnectionIntermittent, availability*100, window, errMsg)
+		return NewComponentErrorCondition(ToolchainClusterConnectionIntermittentReason, err.Error())
+	default:
+		err := fmt.Errorf("%s (no healthy probes in the last %s): %s", ErrMsgClusterConnectionDown, window, errMsg)
+		return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusClusterConnectionNotReadyReason, err.Error())
+	}
+}
+
+// clusterAvailability returns the availability of the cluster connection within the window (see ProbeHistory.Availability),
+// along with the description of the window, which mentions if the probe history does not cover the whole window (see
+// ProbeHistorySize), and false if the probe history is not used or not available
+func clusterAvailability(toolchainCluster *cluster.CachedToolchainCluster, attrs ToolchainClusterAttributes) (float64, int, string, bool) {
+	if attrs.AvailabilityWindow <= 0 {
+		return 0, 0, "", false
+	}
+	history := toolchainCluster.ProbeHistory()
+	if history == nil {
+		return 0, 0, "", false
+	}
+	since := time.Now().Add(-attrs.AvailabilityWindow)
+	availability, probes := history.Availability(since)
+	window := attrs.AvailabilityWindow.String()
+	if !history.Covers(since) {
+		window = fmt.Sprintf("%s, truncated to the last %d probes", window, history.Size())
+	}
+	return availability, probes, window, true
 }
@@ -175,6 +175,160 @@ func TestGetToolchainClusterConditions(t *testing.T) {
 	})
 }
 
+func TestGetToolchainClusterConditionsWithAvailability(t *testing.T) {
+	now := time.Now()
+	attrs := func(name string, getCluster cluster.GetHostClusterFunc) ToolchainClusterAttributes {
+		return ToolchainClusterAttributes{
+			GetClusterFunc:     withClusterName(name, getCluster),
+			Period:             10 * time.Second,
+			Timeout:            3 * time.Second,
+			AvailabilityWindow: time.Hour,
+			MinAvailability:    0.9,
+		}
+	}
+	recordProbes := func(name string, healthy ...bool) {
+		for i, h := range healthy {
+			cluster.RecordProbeResult(name, 0, cluster.ProbeResult{
+				Timestamp: now.Add(-time.Duration(len(healthy)-i) * time.Minute),
+				Healthy:   h,
+			})
+		}
+		// a probe out of the window
+		cluster.RecordProbeResult(name, 0, cluster.ProbeResult{Timestamp: now.Add(-2 * time.Hour)})
+	}
+
+	t.Run("ready with availability", func(t *testing.T) {
+		// given
+		recordProbes("availability-ready", true, true, true, true, true, true, true, true, true, true, true, true, true, true, true, true, true, true, true, false)
+
+		// when
+		conditions := GetToolchainClusterConditions(log, attrs("availability-ready", newGetHostClusterReady()))
+
+		// then
+		require.NoError(t, ValidateComponentConditionReady(conditions...))
+		test.AssertConditionsMatchAndRecentTimestamps(t, conditions, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionTrue,
+			Reason:  "HostConnectionReady",
+			Message: "95% healthy probes in the last 1h0m0s",
+		})
+	})
+
+	t.Run("ready but availability under the minimum", func(t *testing.T) {
+		// given
+		recordProbes("availability-low", true, false, true, true, true)
+
+		// when
+		conditions := GetToolchainClusterConditions(log, attrs("availability-low", newGetHostClusterReady()))
+
+		// then
+		require.Error(t, ValidateComponentConditionReady(conditions...))
+		test.AssertConditionsMatchAndRecentTimestamps(t, conditions, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  ToolchainClusterConnectionIntermittentReason,
+			Message: "the cluster connection is intermittent (80% healthy probes in the last 1h0m0s, expected at least 90%)",
+		})
+	})
+
+	t.Run("not ready and intermittent", func(t *testing.T) {
+		// given
+		recordProbes("availability-intermittent", true, false, true, false, false)
+
+		// when
+		conditions := GetToolchainClusterConditions(log, attrs("availability-intermittent", newGetHostClusterOkButNotReady(fakeToolchainClusterMsg)))
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, conditions, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  ToolchainClusterConnectionIntermittentReason,
+			Message: "the cluster connection is intermittent (40% healthy probes in the last 1h0m0s): " + fakeToolchainClusterMsg,
+		})
+	})
+
+	t.Run("not ready and down", func(t *testing.T) {
+		// given
+		recordProbes("availability-down", false, false, false)
+
+		// when
+		conditions := GetToolchainClusterConditions(log, attrs("availability-down", newGetHostClusterOkButNotReady(fakeToolchainClusterMsg)))
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, conditions, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "HostConnectionNotReady",
+			Message: "the cluster connection is down (no healthy probes in the last 1h0m0s): " + fakeToolchainClusterMsg,
+		})
+	})
+
+	t.Run("no probe in the window", func(t *testing.T) {
+		// given
+		recordProbes("availability-none")
+
+		// when
+		ready := GetToolchainClusterConditions(log, attrs("availability-none", newGetHostClusterReady()))
+		notReady := GetToolchainClusterConditions(log, attrs("availability-none", newGetHostClusterOkButNotReady(fakeToolchainClusterMsg)))
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, ready, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: "HostConnectionReady",
+		})
+		test.AssertConditionsMatchAndRecentTimestamps(t, notReady, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "HostConnectionNotReady",
+			Message: fakeToolchainClusterMsg,
+		})
+	})
+
+	t.Run("ready with a truncated window", func(t *testing.T) {
+		// given 20 minutes of probes every 10s, which is more than the default history size
+		for i := 120; i >= 0; i-- {
+			cluster.RecordProbeResult("availability-truncated", 0, cluster.ProbeResult{
+				Timestamp: now.Add(-time.Duration(i) * 10 * time.Second),
+				Healthy:   true,
+			})
+		}
+
+		// when
+		conditions := GetToolchainClusterConditions(log, attrs("availability-truncated", newGetHostClusterReady()))
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, conditions, toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionTrue,
+			Reason:  "HostConnectionReady",
+			Message: "100% healthy probes in the last 1h0m0s, truncated to the last 120 probes",
+		})
+	})
+
+	t.Run("no probe history", func(t *testing.T) {
+		// when
+		conditions := GetToolchainClusterConditions(log, attrs("availability-unknown", newGetHostClusterReady()))
+
+		// then
+		test.AssertConditionsMatchAndRecentTimestamps(t, conditions, toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: "HostConnectionReady",
+		})
+	})
+}
+
+func withClusterName(name string, getCluster cluster.GetHostClusterFunc) cluster.GetHostClusterFunc {
+	return func() (*cluster.CachedToolchainCluster, bool) {
+		toolchainCluster, ok := getCluster()
+		if ok {
+			toolchainCluster.Name = name
+		}
+		return toolchainCluster, ok
+	}
+}
+
 func newGetHostClusterReady() cluster.GetHostClusterFunc {
 	return NewFakeGetHostCluster(true, toolchainv1alpha1.ConditionReady, corev1.ConditionTrue, fakeToolchainClusterReason, "", &updatetime)
 }
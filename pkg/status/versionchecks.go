@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
@@ -17,21 +18,62 @@ import (
 )
 
 const (
-	// ErrMsgDeploymentIsNotUpToDate means that deployment version is not aligned with source code version
+	// ErrMsgDeploymentIsNotUpToDate means that deployment version is not aligned with source code version in GitHub
 	ErrMsgDeploymentIsNotUpToDate = "deployment version is not up to date with latest github commit SHA"
 
+	// DeploymentRevisionCheckSourceErrorReason the reason of the condition when the latest revision cannot be
+	// retrieved from a RevisionSource other than GitHub (see ToolchainStatusDeploymentRevisionCheckGitHubErrorReason)
+	DeploymentRevisionCheckSourceErrorReason = "DeploymentRevisionCheckSourceError"
+
 	// DeploymentThreshold is the threshold after which we can be almost sure the deployment was not updated on the cluster with the latest version/commit,
 	// in this case some issue is preventing the new deployment to happen.
 	DeploymentThreshold = 30 * time.Minute
 )
 
+// VersionCheckManager verifies that the deployed versions are up to date with the latest revisions of their repositories.
+// It is safe for concurrent use, through a pointer (a manager must not be copied once used). Using a GetGithubClientFunc
+// returned by client.NewCachingGitHubClientFunc avoids exhausting the rate limit of the GitHub API.
 type VersionCheckManager struct {
 	GetGithubClientFunc client.GetGitHubClientFunc
-	LastGHCallsPerRepo  map[string]time.Time
+	// RevisionSource the source of the latest revisions. If not set, the latest revisions are retrieved from GitHub,
+	// using the GetGithubClientFunc and the given access token.
+	RevisionSource RevisionSource
+	// Threshold the delay after the latest commit during which a deployment which is not up to date is not reported.
+	// DeploymentThreshold if not set.
+	Threshold time.Duration
+
+	// mutex guards the lastGHCallsPerRepo and the firstSeenRevisions
+	mutex sync.Mutex
+	// lastGHCallsPerRepo the time of the last call to GitHub, per repo name.
+	// Formerly the exported LastGHCallsPerRepo field, use LastGHCall and SetLastGHCall instead.
+	lastGHCallsPerRepo map[string]time.Time
+	// firstSeenRevisions the revisions without timestamp, along with the time they were first seen, per repo name
+	firstSeenRevisions map[string]Revision
+}
+
+// LastGHCall returns the time of the last call to GitHub for the given repo, if any.
+// It replaces the read access to the former LastGHCallsPerRepo field, which is not safe while the checks are running.
+func (m *VersionCheckManager) LastGHCall(repoName string) (time.Time, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	lastCall, found := m.lastGHCallsPerRepo[repoName]
+	return lastCall, found
+}
+
+// SetLastGHCall records the time of the last call to GitHub for the given repo.
+// It replaces the write access to the former LastGHCallsPerRepo field, which is not safe while the checks are running.
+func (m *VersionCheckManager) SetLastGHCall(repoName string, lastCall time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.lastGHCallsPerRepo == nil {
+		m.lastGHCallsPerRepo = map[string]time.Time{}
+	}
+	m.lastGHCallsPerRepo[repoName] = lastCall
 }
 
-// CheckDeployedVersionIsUpToDate verifies if there is a match between the latest commit in GitHub for a given repo and branch matches the provided commit SHA.
+// CheckDeployedVersionIsUpToDate verifies if there is a match between the latest commit in GitHub (or in the RevisionSource, if set) for a given repo and branch matches the provided commit SHA.
 // There is some preconfigured delay/threshold that we keep in account before returning an `error condition`.
+// The calls to GitHub are throttled per repo (see client.CanIssueGitHubRequest), but not the calls to the other sources.
 func (m *VersionCheckManager) CheckDeployedVersionIsUpToDate(ctx context.Context, isProd bool, accessTokenKey string, alreadyExistingConditions []toolchainv1alpha1.Condition, githubRepo client.GitHubRepository) *toolchainv1alpha1.Condition {
 	// the first two checks are pretty much the same for all components
 	if !isProd {
@@ -39,17 +81,17 @@ func (m *VersionCheckManager) CheckDeployedVersionIsUpToDate(ctx context.Context
 		cond.Message = "is not running in prod environment"
 		return cond
 	}
-	if accessTokenKey == "" {
-		cond := NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason)
-		cond.Message = "access token key is not provided"
-		return cond
-	}
-	// we can store the last call per repo name, so it will solve the gaps between calls for host & reg-service which is done form the same controller
-	if m.LastGHCallsPerRepo == nil {
-		m.LastGHCallsPerRepo = map[string]time.Time{}
+	revisionSource := m.RevisionSource
+	if revisionSource == nil {
+		if accessTokenKey == "" {
+			cond := NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckDisabledReason)
+			cond.Message = "access token key is not provided"
+			return cond
+		}
+		revisionSource = NewGitHubRevisionSource(m.GetGithubClientFunc, accessTokenKey)
 	}
-	lastCall, present := m.LastGHCallsPerRepo[githubRepo.Name]
-	if present && !client.CanIssueGitHubRequest(lastCall) {
+	_, isGitHub := revisionSource.(gitHubRevisionSource)
+	if isGitHub && !m.canIssueRequest(githubRepo.Name) {
 		// return existing condition when we cannot make a new GitHub api call due to rate limiting issues.
 		previouslySet, found := condition.FindConditionByType(alreadyExistingConditions, toolchainv1alpha1.ConditionReady)
 		if !found {
@@ -58,21 +100,30 @@ func (m *VersionCheckManager) CheckDeployedVersionIsUpToDate(ctx context.Context
 		}
 		return &previouslySet
 	}
-	m.LastGHCallsPerRepo[githubRepo.Name] = time.Now()
-	githubClient := m.GetGithubClientFunc(ctx, accessTokenKey)
 	// get the latest commit from given repository and branch
-	latestCommit, err := getLatestCommit(ctx, githubClient.Repositories.GetCommit, githubRepo)
+	latestRevision, err := revisionSource.LatestRevision(ctx, githubRepo)
 	if err != nil {
-		return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckGitHubErrorReason, err.Error())
+		if isGitHub {
+			return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentRevisionCheckGitHubErrorReason, err.Error())
+		}
+		return NewComponentErrorCondition(DeploymentRevisionCheckSourceErrorReason, err.Error())
 	}
 	// check if there is a mismatch between the commit id of the running version and latest commit id from the source code repo (deployed version according to GitHub actions)
 	// we also consider some delay ( time that usually takes the deployment to happen on all our environments)
-	githubCommitTimestamp := latestCommit.Commit.Author.GetDate()
-	expectedDeploymentTime := githubCommitTimestamp.Add(DeploymentThreshold) // let's consider some threshold for the deployment to happen
-	githubCommitSHA := *latestCommit.SHA
+	githubCommitTimestamp := m.revisionTimestamp(githubRepo.Name, latestRevision)
+	threshold := m.Threshold
+	if threshold == 0 {
+		threshold = DeploymentThreshold
+	}
+	expectedDeploymentTime := githubCommitTimestamp.Add(threshold) // let's consider some threshold for the deployment to happen
+	githubCommitSHA := latestRevision.SHA
 	if githubCommitSHA != githubRepo.DeployedCommitSHA && time.Now().After(expectedDeploymentTime) {
 		// deployed version is not up-to-date after expected threshold
-		err := fmt.Errorf("%s. deployed commit SHA %s ,github latest SHA %s, expected deployment timestamp: %s", ErrMsgDeploymentIsNotUpToDate, githubRepo.DeployedCommitSHA, githubCommitSHA, expectedDeploymentTime.Format(time.RFC3339))
+		errMsg := ErrMsgDeploymentIsNotUpToDate
+		if !isGitHub {
+			errMsg = fmt.Sprintf("deployment version is not up to date with latest %s commit SHA", revisionSource.Name())
+		}
+		err := fmt.Errorf("%s. deployed commit SHA %s ,%s latest SHA %s, expected deployment timestamp: %s", errMsg, githubRepo.DeployedCommitSHA, revisionSource.Name(), githubCommitSHA, expectedDeploymentTime.Format(time.RFC3339))
 		return NewComponentErrorCondition(toolchainv1alpha1.ToolchainStatusDeploymentNotUpToDateReason, err.Error())
 	}
 
@@ -80,6 +131,40 @@ func (m *VersionCheckManager) CheckDeployedVersionIsUpToDate(ctx context.Context
 	return NewComponentReadyCondition(toolchainv1alpha1.ToolchainStatusDeploymentUpToDateReason)
 }
 
+// canIssueRequest returns true if the latest revision of the given repo can be retrieved, and records the time of the call
+func (m *VersionCheckManager) canIssueRequest(repoName string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	// we can store the last call per repo name, so it will solve the gaps between calls for host & reg-service which is done form the same controller
+	if m.lastGHCallsPerRepo == nil {
+		m.lastGHCallsPerRepo = map[string]time.Time{}
+	}
+	lastCall, present := m.lastGHCallsPerRepo[repoName]
+	if present && !client.CanIssueGitHubRequest(lastCall) {
+		return false
+	}
+	m.lastGHCallsPerRepo[repoName] = time.Now()
+	return true
+}
+
+// revisionTimestamp returns the timestamp of the given revision or, if the source does not provide it, the time when
+// the revision was first seen for the given repo
+func (m *VersionCheckManager) revisionTimestamp(repoName string, revision Revision) time.Time {
+	if !revision.Timestamp.IsZero() {
+		return revision.Timestamp
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.firstSeenRevisions == nil {
+		m.firstSeenRevisions = map[string]Revision{}
+	}
+	if firstSeen, found := m.firstSeenRevisions[repoName]; found && firstSeen.SHA == revision.SHA {
+		return firstSeen.Timestamp
+	}
+	m.firstSeenRevisions[repoName] = Revision{SHA: revision.SHA, Timestamp: time.Now()}
+	return m.firstSeenRevisions[repoName].Timestamp
+}
+
 type getCommitFunc func(ctx context.Context, owner string, repo string, sha string, opts *github.ListOptions) (*github.RepositoryCommit, *github.Response, error)
 
 func getLatestCommit(ctx context.Context, GetCommit getCommitFunc, githubRepo client.GitHubRepository) (*github.RepositoryCommit, error) {
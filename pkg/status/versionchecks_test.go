@@ -19,9 +19,9 @@ import (
 )
 
 func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
-	versionCheckMgr := VersionCheckManager{
-		GetGithubClientFunc: test.MockGitHubClientForRepositoryCommits("1234abcd", time.Now().Add(-time.Hour*1)),
-		LastGHCallsPerRepo:  nil,
+	getGithubClientFunc := test.MockGitHubClientForRepositoryCommits("1234abcd", time.Now().Add(-time.Hour*1))
+	versionCheckMgr := &VersionCheckManager{
+		GetGithubClientFunc: getGithubClientFunc,
 	}
 	githubRepo := client.GitHubRepository{
 		Org:               toolchainv1alpha1.ProviderLabelValue,
@@ -64,11 +64,11 @@ func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
 
 		t.Run("we cannot issue a github api call but we return existing revision check condition", func(t *testing.T) {
 			// given
-			versionCheckMgrLastCAll := versionCheckMgr
-			// let's set last call for this repository to now, so that we make sure it cannot make another call immediately.
-			versionCheckMgrLastCAll.LastGHCallsPerRepo = map[string]time.Time{
-				"host-operator": time.Now(),
+			versionCheckMgrLastCAll := &VersionCheckManager{
+				GetGithubClientFunc: getGithubClientFunc,
 			}
+			// let's set last call for this repository to now, so that we make sure it cannot make another call immediately.
+			versionCheckMgrLastCAll.SetLastGHCall("host-operator", time.Now())
 			expected := toolchainv1alpha1.Condition{
 				Type:               toolchainv1alpha1.ConditionReady,
 				Status:             corev1.ConditionTrue,
@@ -109,7 +109,6 @@ func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
 				latestCommitTimestamp := time.Now().Add(-time.Minute * 29)
 				versionCheckMgrThreshold := VersionCheckManager{
 					GetGithubClientFunc: test.MockGitHubClientForRepositoryCommits("1234abcd", latestCommitTimestamp),
-					LastGHCallsPerRepo:  nil,
 				}
 				expected := toolchainv1alpha1.Condition{
 					Type:    toolchainv1alpha1.ConditionReady,
@@ -131,7 +130,6 @@ func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
 				latestCommitTimestamp := time.Now().Add(-time.Minute * 31)
 				versionCheckMgrThresholdExpired := VersionCheckManager{
 					GetGithubClientFunc: test.MockGitHubClientForRepositoryCommits("1234abcd", latestCommitTimestamp),
-					LastGHCallsPerRepo:  nil,
 				}
 				expected := toolchainv1alpha1.Condition{
 					Type:    toolchainv1alpha1.ConditionReady,
@@ -171,7 +169,6 @@ func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
 					)
 					return github.NewClient(mockedHTTPClient)
 				},
-				LastGHCallsPerRepo: nil,
 			}
 			expected := toolchainv1alpha1.Condition{
 				Type:    toolchainv1alpha1.ConditionReady,
@@ -194,7 +191,6 @@ func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
 					mockedHTTPClient := test.MockGithubRepositoryCommit(nil)
 					return github.NewClient(mockedHTTPClient)
 				},
-				LastGHCallsPerRepo: nil,
 			}
 			expected := toolchainv1alpha1.Condition{
 				Type:    toolchainv1alpha1.ConditionReady,
@@ -213,10 +209,10 @@ func TestCheckDeployedVersionIsUpToDate(t *testing.T) {
 		t.Run("we cannot issue a github api call and there are no conditions set yet", func(t *testing.T) {
 			// given
 
-			versionCheckMgrNoCond := versionCheckMgr
-			versionCheckMgrNoCond.LastGHCallsPerRepo = map[string]time.Time{
-				"host-operator": time.Now(), // let's set last call for this repository to now, so that we make sure it cannot make another call immediately.
+			versionCheckMgrNoCond := &VersionCheckManager{
+				GetGithubClientFunc: getGithubClientFunc,
 			}
+			versionCheckMgrNoCond.SetLastGHCall("host-operator", time.Now()) // let's set last call for this repository to now, so that we make sure it cannot make another call immediately.
 			expected := toolchainv1alpha1.Condition{
 				Type:    toolchainv1alpha1.ConditionReady,
 				Status:  corev1.ConditionFalse,
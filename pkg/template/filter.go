@@ -1,6 +1,13 @@
 package template
 
-import "k8s.io/apimachinery/pkg/runtime"
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
 
 var (
 	// RetainNamespaces a func to retain only namespaces
@@ -16,6 +23,54 @@ var (
 	}
 )
 
+// RetainGVKs returns a func to retain only the objects of the given GroupVersionKinds
+func RetainGVKs(gvks ...schema.GroupVersionKind) FilterFunc {
+	return func(obj runtime.RawExtension) bool {
+		return slices.Contains(gvks, obj.Object.GetObjectKind().GroupVersionKind())
+	}
+}
+
+// RetainMatchingLabels returns a func to retain only the objects whose labels match the given selector
+func RetainMatchingLabels(selector labels.Selector) FilterFunc {
+	return func(obj runtime.RawExtension) bool {
+		m, err := meta.Accessor(obj.Object)
+		if err != nil {
+			return false
+		}
+		return selector.Matches(labels.Set(m.GetLabels()))
+	}
+}
+
+// RetainInNamespaces returns a func to retain only the objects in one of the given namespaces
+func RetainInNamespaces(namespaces ...string) FilterFunc {
+	return func(obj runtime.RawExtension) bool {
+		m, err := meta.Accessor(obj.Object)
+		if err != nil {
+			return false
+		}
+		return slices.Contains(namespaces, m.GetNamespace())
+	}
+}
+
+// Not returns a func to retain the objects which are not retained by the given filter
+func Not(filter FilterFunc) FilterFunc {
+	return func(obj runtime.RawExtension) bool {
+		return !filter(obj)
+	}
+}
+
+// Any returns a func to retain the objects which are retained by at least one of the given filters
+func Any(filters ...FilterFunc) FilterFunc {
+	return func(obj runtime.RawExtension) bool {
+		for _, filter := range filters {
+			if filter(obj) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // FilterFunc a function to retain an object or not
 type FilterFunc func(runtime.RawExtension) bool
 
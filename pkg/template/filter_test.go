@@ -8,7 +8,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestFilter(t *testing.T) {
@@ -216,3 +218,84 @@ func TestFilter(t *testing.T) {
 		})
 	})
 }
+
+func TestBuiltinFilters(t *testing.T) {
+
+	ns := newUnstructured(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"john","labels":{"type":"dev"}}}`)
+	cm := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"john","labels":{"type":"dev"}}}`)
+	rb := newUnstructured(t, `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"rb","namespace":"john-stage"}}`)
+	objs := []runtime.RawExtension{{Object: ns}, {Object: cm}, {Object: rb}}
+
+	t.Run("retain GVKs", func(t *testing.T) {
+		// when
+		result := template.Filter(objs, template.RetainGVKs(
+			schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+			schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "Namespace"}))
+
+		// then
+		require.Len(t, result, 2)
+		assert.Equal(t, cm, result[0].Object)
+		assert.Equal(t, rb, result[1].Object)
+	})
+
+	t.Run("retain matching labels", func(t *testing.T) {
+		// given
+		selector, err := labels.Parse("type=dev")
+		require.NoError(t, err)
+
+		// when
+		result := template.Filter(objs, template.RetainMatchingLabels(selector))
+
+		// then
+		require.Len(t, result, 2)
+		assert.Equal(t, ns, result[0].Object)
+		assert.Equal(t, cm, result[1].Object)
+	})
+
+	t.Run("retain in namespaces", func(t *testing.T) {
+		// when
+		result := template.Filter(objs, template.RetainInNamespaces("john-stage", "john-prod"))
+
+		// then
+		require.Len(t, result, 1)
+		assert.Equal(t, rb, result[0].Object)
+	})
+
+	t.Run("retain cluster-scoped objects", func(t *testing.T) {
+		// when
+		result := template.Filter(objs, template.RetainInNamespaces(""))
+
+		// then
+		require.Len(t, result, 1)
+		assert.Equal(t, ns, result[0].Object)
+	})
+
+	t.Run("not", func(t *testing.T) {
+		// when
+		result := template.Filter(objs, template.Not(template.RetainInNamespaces("john")))
+
+		// then
+		require.Len(t, result, 2)
+		assert.Equal(t, ns, result[0].Object)
+		assert.Equal(t, rb, result[1].Object)
+	})
+
+	t.Run("any", func(t *testing.T) {
+		// when
+		result := template.Filter(objs, template.Any(template.RetainNamespaces, template.RetainInNamespaces("john-stage")))
+
+		// then
+		require.Len(t, result, 2)
+		assert.Equal(t, ns, result[0].Object)
+		assert.Equal(t, rb, result[1].Object)
+	})
+
+	t.Run("any without filter", func(t *testing.T) {
+		// when
+		result := template.Filter(objs, template.Any())
+
+		// then
+		require.Empty(t, result)
+	})
+}
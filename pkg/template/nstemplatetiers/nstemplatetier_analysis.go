@@ -0,0 +1,282 @@
+package nstemplatetiers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	commonTemplate "github.com/codeready-toolchain/toolchain-common/pkg/template"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// TierTemplateSizeLimit the maximum size (in bytes) of a serialized TierTemplate, ie, the default
+	// maximum size of a request to etcd (see the `--max-request-bytes` etcd flag)
+	TierTemplateSizeLimit = 1572864
+	// TierTemplateSizeWarningThreshold the size (in bytes) of a serialized TierTemplate above which a warning is reported (80% of the limit)
+	TierTemplateSizeWarningThreshold = TierTemplateSizeLimit * 8 / 10
+
+	// sampleParamValue the value of the required template parameters when processing the templates for the analysis
+	sampleParamValue = "sample"
+)
+
+// TierAnalysis the analysis of a tier
+type TierAnalysis struct {
+	Name          string
+	TierTemplates []TierTemplateAnalysis
+	// ResourceQuota the sum of the hard limits of all the ResourceQuotas in the namespace templates of the tier,
+	// ie, the maximum amount of resources that a space in this tier can use
+	ResourceQuota corev1.ResourceList
+	// LimitRanges the most permissive values of all the LimitRanges in the namespace templates of the tier, indexed by type
+	// (ie, the highest default, default request and max values and the lowest min values)
+	LimitRanges map[corev1.LimitType]corev1.LimitRangeItem
+	// Warnings the TierTemplates whose size is close to the TierTemplateSizeLimit
+	Warnings []string
+}
+
+// TierTemplateAnalysis the analysis of a TierTemplate
+type TierTemplateAnalysis struct {
+	Name string
+	Type string
+	// Objects the number of objects in the template
+	Objects int
+	// Size the size (in bytes) of the serialized TierTemplate
+	Size int
+}
+
+// Objects returns the total number of objects in all the TierTemplates of the tier
+func (a TierAnalysis) Objects() int {
+	count := 0
+	for _, tmpl := range a.TierTemplates {
+		count += tmpl.Objects
+	}
+	return count
+}
+
+// Size returns the total size (in bytes) of all the TierTemplates of the tier
+func (a TierAnalysis) Size() int {
+	size := 0
+	for _, tmpl := range a.TierTemplates {
+		size += tmpl.Size
+	}
+	return size
+}
+
+// AnalyzeTiers processes the given metadata and files and returns the analysis of each tier, sorted by tier name.
+// Returns an error if a TierTemplate exceeds the TierTemplateSizeLimit.
+func AnalyzeTiers(s *runtime.Scheme, namespace string, metadata map[string]string, files map[string][]byte) ([]TierAnalysis, error) {
+	generator, err := newNSTemplateTierGenerator(s, nil, namespace, metadata, files)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to init NSTemplateTier generator")
+	}
+	return generator.analyzeTiers()
+}
+
+// analyzeTiers returns the analysis of each tier, sorted by tier name
+func (t *TierGenerator) analyzeTiers() ([]TierAnalysis, error) {
+	tiers := make([]string, 0, len(t.templatesByTier))
+	for tier := range t.templatesByTier {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+	analyses := make([]TierAnalysis, 0, len(tiers))
+	for _, tier := range tiers {
+		analysis, err := t.analyzeTier(tier)
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, analysis)
+	}
+	return analyses, nil
+}
+
+// analyzeTier returns the analysis of the given tier
+func (t *TierGenerator) analyzeTier(tier string) (TierAnalysis, error) {
+	tierData := t.templatesByTier[tier]
+	// the namespace templates are the ones of the "source" tier
+	sourceData := tierData
+	if tierData.basedOnTier != nil {
+		sourceData = t.templatesByTier[tierData.basedOnTier.From]
+	}
+	analysis := TierAnalysis{
+		Name:          tier,
+		ResourceQuota: corev1.ResourceList{},
+		LimitRanges:   map[corev1.LimitType]corev1.LimitRangeItem{},
+	}
+	for _, tierTmpl := range tierData.tierTemplates {
+		size, warning, err := verifyTierTemplateSize(tierTmpl)
+		if err != nil {
+			return analysis, err
+		}
+		if warning != "" {
+			analysis.Warnings = append(analysis.Warnings, warning)
+		}
+		analysis.TierTemplates = append(analysis.TierTemplates, TierTemplateAnalysis{
+			Name:    tierTmpl.Name,
+			Type:    tierTmpl.Spec.Type,
+			Objects: len(tierTmpl.Spec.Template.Objects),
+			Size:    size,
+		})
+		if _, isNamespaceTemplate := sourceData.rawTemplates.namespaceTemplates[tierTmpl.Spec.Type]; !isNamespaceTemplate {
+			continue
+		}
+		if err := t.aggregateLimits(&analysis, tierTmpl); err != nil {
+			return analysis, err
+		}
+	}
+	return analysis, nil
+}
+
+// verifyTierTemplateSizes verifies that none of the TierTemplates exceeds the TierTemplateSizeLimit, and logs a warning
+// for each TierTemplate whose size is close to the limit
+func (t *TierGenerator) verifyTierTemplateSizes() error {
+	for _, tierData := range t.templatesByTier {
+		for _, tierTmpl := range tierData.tierTemplates {
+			_, warning, err := verifyTierTemplateSize(tierTmpl)
+			if err != nil {
+				return err
+			}
+			if warning != "" {
+				log.Info(warning)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyTierTemplateSize returns the size of the given TierTemplate once serialized, along with a warning if the size
+// is close to the TierTemplateSizeLimit, or an error if the size exceeds the limit
+func verifyTierTemplateSize(tierTmpl *toolchainv1alpha1.TierTemplate) (int, string, error) {
+	raw, err := json.Marshal(tierTmpl)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "unable to serialize the '%s' TierTemplate", tierTmpl.Name)
+	}
+	if len(raw) > TierTemplateSizeLimit {
+		return len(raw), "", fmt.Errorf("the '%s' TierTemplate exceeds the size limit: %d bytes > %d bytes", tierTmpl.Name, len(raw), TierTemplateSizeLimit)
+	}
+	if len(raw) > TierTemplateSizeWarningThreshold {
+		return len(raw), fmt.Sprintf("the '%s' TierTemplate is close to the size limit: %d bytes (limit is %d bytes)", tierTmpl.Name, len(raw), TierTemplateSizeLimit), nil
+	}
+	return len(raw), "", nil
+}
+
+// aggregateLimits adds the values of the ResourceQuotas and LimitRanges of the given TierTemplate to the given analysis
+func (t *TierGenerator) aggregateLimits(analysis *TierAnalysis, tierTmpl *toolchainv1alpha1.TierTemplate) error {
+	tmpl := tierTmpl.Spec.Template.DeepCopy()
+	values := map[string]string{}
+	for _, param := range tmpl.Parameters {
+		if param.Required && param.Value == "" && param.Generate == "" {
+			values[param.Name] = sampleParamValue
+		}
+	}
+	objs, err := commonTemplate.NewProcessor(t.scheme).Process(tmpl, values)
+	if err != nil {
+		return errors.Wrapf(err, "unable to process the '%s' TierTemplate", tierTmpl.Name)
+	}
+	for _, obj := range objs {
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "ResourceQuota":
+			quota := &corev1.ResourceQuota{}
+			if err := t.scheme.Convert(obj, quota, nil); err != nil {
+				return errors.Wrapf(err, "unable to read the '%s' ResourceQuota in the '%s' TierTemplate", obj.GetName(), tierTmpl.Name)
+			}
+			for name, value := range quota.Spec.Hard {
+				total := analysis.ResourceQuota[name]
+				total.Add(value)
+				analysis.ResourceQuota[name] = total
+			}
+		case "LimitRange":
+			limitRange := &corev1.LimitRange{}
+			if err := t.scheme.Convert(obj, limitRange, nil); err != nil {
+				return errors.Wrapf(err, "unable to read the '%s' LimitRange in the '%s' TierTemplate", obj.GetName(), tierTmpl.Name)
+			}
+			for _, item := range limitRange.Spec.Limits {
+				aggregated := analysis.LimitRanges[item.Type]
+				aggregated.Type = item.Type
+				aggregated.Max = mergeResourceList(aggregated.Max, item.Max, true)
+				aggregated.Min = mergeResourceList(aggregated.Min, item.Min, false)
+				aggregated.Default = mergeResourceList(aggregated.Default, item.Default, true)
+				aggregated.DefaultRequest = mergeResourceList(aggregated.DefaultRequest, item.DefaultRequest, true)
+				aggregated.MaxLimitRequestRatio = mergeResourceList(aggregated.MaxLimitRequestRatio, item.MaxLimitRequestRatio, true)
+				analysis.LimitRanges[item.Type] = aggregated
+			}
+		}
+	}
+	return nil
+}
+
+// mergeResourceList merges the given values into the given list, retaining the highest (or lowest) value for each resource
+func mergeResourceList(list, values corev1.ResourceList, highest bool) corev1.ResourceList {
+	if len(values) == 0 {
+		return list
+	}
+	if list == nil {
+		list = corev1.ResourceList{}
+	}
+	for name, value := range values {
+		existing, found := list[name]
+		if !found || (highest && value.Cmp(existing) > 0) || (!highest && value.Cmp(existing) < 0) {
+			list[name] = value.DeepCopy()
+		}
+	}
+	return list
+}
+
+// PrintSummary writes a table summarizing the given analyses, with one row per tier
+func PrintSummary(out io.Writer, analyses []TierAnalysis) error {
+	// collect the names of all the resources with a quota, to have one column per resource
+	resourceNames := map[corev1.ResourceName]bool{}
+	for _, analysis := range analyses {
+		for name := range analysis.ResourceQuota {
+			resourceNames[name] = true
+		}
+	}
+	columns := make([]string, 0, len(resourceNames))
+	for name := range resourceNames {
+		columns = append(columns, string(name))
+	}
+	sort.Strings(columns)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := append([]string{"TIER", "TEMPLATES", "OBJECTS", "SIZE", "LARGEST TEMPLATE"}, columns...)
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+	for _, analysis := range analyses {
+		largest := TierTemplateAnalysis{}
+		for _, tmpl := range analysis.TierTemplates {
+			if tmpl.Size > largest.Size {
+				largest = tmpl
+			}
+		}
+		row := []string{
+			analysis.Name,
+			fmt.Sprint(len(analysis.TierTemplates)),
+			fmt.Sprint(analysis.Objects()),
+			fmt.Sprint(analysis.Size()),
+			fmt.Sprintf("%s (%d)", largest.Type, largest.Size),
+		}
+		for _, name := range columns {
+			value, found := analysis.ResourceQuota[corev1.ResourceName(name)]
+			row = append(row, quantityOrNone(value, found))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func quantityOrNone(value resource.Quantity, found bool) string {
+	if !found {
+		return "-"
+	}
+	return value.String()
+}
@@ -0,0 +1,229 @@
+package nstemplatetiers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestAnalyzeTiers(t *testing.T) {
+	s := addToScheme(t)
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		files := getTestTemplates(t)
+		files["base/ns_dev.yaml"] = []byte(nsTemplateWithLimits("dev", "2", "7Gi", "1", "1000m"))
+		files["base/ns_stage.yaml"] = []byte(nsTemplateWithLimits("stage", "1", "3Gi", "2", "500m"))
+
+		// when
+		analyses, err := AnalyzeTiers(s, test.HostOperatorNs, getTestMetadata(), files)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, analyses, 4)
+		for i, name := range []string{"advanced", "appstudio", "base", "nocluster"} {
+			assert.Equal(t, name, analyses[i].Name)
+			assert.Empty(t, analyses[i].Warnings)
+		}
+
+		t.Run("tier templates", func(t *testing.T) {
+			base := analyses[2]
+			require.Len(t, base.TierTemplates, 4)
+			assert.Equal(t, "base-dev-123456b-123456b", base.TierTemplates[0].Name)
+			assert.Equal(t, "dev", base.TierTemplates[0].Type)
+			assert.Equal(t, 3, base.TierTemplates[0].Objects)
+			assert.Positive(t, base.TierTemplates[0].Size)
+			assert.Equal(t, "stage", base.TierTemplates[1].Type)
+			assert.Equal(t, "admin", base.TierTemplates[2].Type)
+			assert.Equal(t, "clusterresources", base.TierTemplates[3].Type)
+			size := 0
+			objects := 0
+			for _, tmpl := range base.TierTemplates {
+				size += tmpl.Size
+				objects += tmpl.Objects
+			}
+			assert.Equal(t, size, base.Size())
+			assert.Equal(t, objects, base.Objects())
+		})
+
+		t.Run("quotas and limits", func(t *testing.T) {
+			for _, analysis := range []TierAnalysis{analyses[0], analyses[2]} { // advanced is based on base
+				assertResourceList(t, map[corev1.ResourceName]string{
+					corev1.ResourceLimitsCPU:    "3",
+					corev1.ResourceLimitsMemory: "10Gi",
+				}, analysis.ResourceQuota)
+				require.Len(t, analysis.LimitRanges, 1)
+				container := analysis.LimitRanges[corev1.LimitTypeContainer]
+				assert.Equal(t, corev1.LimitTypeContainer, container.Type)
+				assertResourceList(t, map[corev1.ResourceName]string{corev1.ResourceCPU: "2"}, container.Default)
+				assertResourceList(t, map[corev1.ResourceName]string{corev1.ResourceCPU: "1"}, container.DefaultRequest)
+				assertResourceList(t, map[corev1.ResourceName]string{corev1.ResourceCPU: "10m"}, container.Min)
+				assert.Empty(t, container.Max)
+			}
+			assert.Empty(t, analyses[3].ResourceQuota)
+			assert.Empty(t, analyses[3].LimitRanges)
+		})
+
+		t.Run("summary", func(t *testing.T) {
+			// given
+			out := &bytes.Buffer{}
+
+			// when
+			err := PrintSummary(out, analyses)
+
+			// then
+			require.NoError(t, err)
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			require.Len(t, lines, 5)
+			assert.Equal(t, []string{"TIER", "TEMPLATES", "OBJECTS", "SIZE", "LARGEST", "TEMPLATE", "limits.cpu", "limits.memory"}, strings.Fields(lines[0]))
+			base := strings.Fields(lines[3])
+			assert.Equal(t, []string{"base", "4", fmt.Sprint(analyses[2].Objects()), fmt.Sprint(analyses[2].Size())}, base[:4])
+			assert.Equal(t, []string{"3", "10Gi"}, base[6:])
+			assert.Equal(t, []string{"-", "-"}, strings.Fields(lines[4])[6:])
+		})
+	})
+
+	t.Run("warning when close to the size limit", func(t *testing.T) {
+		// given
+		files := getTestTemplates(t)
+		files["base/ns_dev.yaml"] = []byte(nsTemplateWithData("dev", TierTemplateSizeWarningThreshold))
+
+		// when
+		analyses, err := AnalyzeTiers(s, test.HostOperatorNs, getTestMetadata(), files)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, analyses[2].Warnings, 1)
+		assert.Contains(t, analyses[2].Warnings[0], "the 'base-dev-123456b-123456b' TierTemplate is close to the size limit")
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("size limit exceeded", func(t *testing.T) {
+			// given
+			files := getTestTemplates(t)
+			files["base/ns_dev.yaml"] = []byte(nsTemplateWithData("dev", TierTemplateSizeLimit))
+
+			// when
+			_, err := AnalyzeTiers(s, test.HostOperatorNs, getTestMetadata(), files)
+
+			// then
+			require.ErrorContains(t, err, "the 'advanced-dev-abcd123-123456b' TierTemplate exceeds the size limit")
+		})
+
+		t.Run("size limit exceeded when generating tiers", func(t *testing.T) {
+			// given
+			files := getTestTemplates(t)
+			files["base/ns_dev.yaml"] = []byte(nsTemplateWithData("dev", TierTemplateSizeLimit))
+			clt := test.NewFakeClient(t)
+
+			// when
+			err := GenerateTiers(s, ensureObjectFuncForClient(clt), test.HostOperatorNs, getTestMetadata(), files)
+
+			// then
+			require.ErrorContains(t, err, "invalid TierTemplates: the")
+			require.ErrorContains(t, err, "TierTemplate exceeds the size limit")
+		})
+
+		t.Run("invalid quota", func(t *testing.T) {
+			// given
+			files := getTestTemplates(t)
+			files["base/ns_dev.yaml"] = []byte(nsTemplateWithLimits("dev", "2", "invalid", "1", "1000m"))
+
+			// when
+			_, err := AnalyzeTiers(s, test.HostOperatorNs, getTestMetadata(), files)
+
+			// then
+			require.ErrorContains(t, err, "unable to read the 'compute' ResourceQuota in the 'advanced-dev-abcd123-123456b' TierTemplate")
+		})
+
+		t.Run("invalid template", func(t *testing.T) {
+			// given
+			files := getTestTemplates(t)
+			files["base/ns_dev.yaml"] = []byte("invalid")
+
+			// when
+			_, err := AnalyzeTiers(s, test.HostOperatorNs, getTestMetadata(), files)
+
+			// then
+			require.ErrorContains(t, err, "unable to init NSTemplateTier generator")
+		})
+	})
+}
+
+func assertResourceList(t *testing.T, expected map[corev1.ResourceName]string, actual corev1.ResourceList) {
+	require.Len(t, actual, len(expected))
+	for name, value := range expected {
+		actualValue, found := actual[name]
+		require.True(t, found, "missing value for '%s'", name)
+		expectedValue := resource.MustParse(value)
+		assert.Zero(t, expectedValue.Cmp(actualValue), "unexpected value for '%s': %s", name, actualValue.String())
+	}
+}
+
+func nsTemplateWithLimits(nsType, cpuLimit, memoryLimit, defaultCPU, defaultCPURequest string) string {
+	return fmt.Sprintf(`apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: base-%[1]s
+objects:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: ${SPACE_NAME}-%[1]s
+- apiVersion: v1
+  kind: ResourceQuota
+  metadata:
+    name: compute
+    namespace: ${SPACE_NAME}-%[1]s
+  spec:
+    hard:
+      limits.cpu: "%[2]s"
+      limits.memory: ${MEMORY_LIMIT}
+- apiVersion: v1
+  kind: LimitRange
+  metadata:
+    name: resource-limits
+    namespace: ${SPACE_NAME}-%[1]s
+  spec:
+    limits:
+    - type: "Container"
+      default:
+        cpu: "%[4]s"
+      defaultRequest:
+        cpu: "%[5]s"
+      min:
+        cpu: 10m
+parameters:
+- name: SPACE_NAME
+  required: true
+- name: MEMORY_LIMIT
+  value: "%[3]s"
+`, nsType, cpuLimit, memoryLimit, defaultCPU, defaultCPURequest)
+}
+
+func nsTemplateWithData(nsType string, size int) string {
+	return fmt.Sprintf(`apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: base-%[1]s
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: data
+    namespace: ${SPACE_NAME}-%[1]s
+  data:
+    content: "%[2]s"
+parameters:
+- name: SPACE_NAME
+  required: true
+`, nsType, strings.Repeat("a", size))
+}
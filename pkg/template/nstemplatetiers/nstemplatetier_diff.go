@@ -0,0 +1,273 @@
+package nstemplatetiers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	commonTemplate "github.com/codeready-toolchain/toolchain-common/pkg/template"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ChangeType the type of change of a tier, a template or an object between two revisions
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// TierDiff the changes of a tier between two revisions
+type TierDiff struct {
+	Name      string
+	Change    ChangeType
+	Templates []TemplateDiff
+}
+
+// TemplateDiff the changes of a template of a tier between two revisions. The type of the template is
+// the namespace type (eg: `dev`), the space role (eg: `admin`) or `clusterresources`
+type TemplateDiff struct {
+	Type    string
+	Change  ChangeType
+	Objects []ObjectDiff
+}
+
+// ObjectDiff the changes of an object of a template between two revisions.
+// The required parameters of the template (eg: `SPACE_NAME`) are not replaced in the name and namespace
+// of the object, nor in its content.
+type ObjectDiff struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Change     ChangeType
+	// Diff the unified diff between the YAML content of the object in the two revisions (only for modified objects).
+	// The keys are sorted in the YAML content, so that the diff is stable.
+	Diff string
+}
+
+// DiffTiers processes the two given sets of metadata and files (eg: from the main branch and from a pull request)
+// and returns the changes of each tier, template and object, sorted by tier name, template type and object key.
+// Tiers, templates and objects without any change are not included in the result.
+func DiffTiers(s *runtime.Scheme, namespace string, oldMetadata map[string]string, oldFiles map[string][]byte, newMetadata map[string]string, newFiles map[string][]byte) ([]TierDiff, error) {
+	oldGenerator, err := newNSTemplateTierGenerator(s, nil, namespace, oldMetadata, oldFiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to init NSTemplateTier generator for the old revision")
+	}
+	newGenerator, err := newNSTemplateTierGenerator(s, nil, namespace, newMetadata, newFiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to init NSTemplateTier generator for the new revision")
+	}
+	oldObjects, err := oldGenerator.objectsByTier()
+	if err != nil {
+		return nil, err
+	}
+	newObjects, err := newGenerator.objectsByTier()
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []TierDiff
+	for _, tier := range sortedKeys(oldObjects, newObjects) {
+		oldTemplates, inOld := oldObjects[tier]
+		newTemplates, inNew := newObjects[tier]
+		tierDiff := TierDiff{
+			Name:      tier,
+			Change:    changeType(inOld, inNew),
+			Templates: diffTemplates(oldTemplates, newTemplates),
+		}
+		if len(tierDiff.Templates) > 0 {
+			diffs = append(diffs, tierDiff)
+		}
+	}
+	return diffs, nil
+}
+
+// objectsByKey the objects of a template, indexed by key (see objectKey)
+type objectsByKey map[string]runtimeclient.Object
+
+// objectsByTier returns the objects of all the templates of all the tiers, indexed by tier name and by template type
+func (t *TierGenerator) objectsByTier() (map[string]map[string]objectsByKey, error) {
+	result := make(map[string]map[string]objectsByKey, len(t.templatesByTier))
+	for tier, tierData := range t.templatesByTier {
+		result[tier] = make(map[string]objectsByKey, len(tierData.tierTemplates))
+		for _, tierTmpl := range tierData.tierTemplates {
+			objs, err := t.processWithPlaceholders(tierTmpl)
+			if err != nil {
+				return nil, err
+			}
+			byKey := make(objectsByKey, len(objs))
+			for _, obj := range objs {
+				byKey[objectKey(obj)] = obj
+			}
+			result[tier][tierTmpl.Spec.Type] = byKey
+		}
+	}
+	return result, nil
+}
+
+// processWithPlaceholders processes the template of the given TierTemplate, using `${<name>}` as the value of the
+// required parameters without a value, and seeding the generated values with the name of the tier and type of the
+// template, so that the two revisions of the same template can be compared
+func (t *TierGenerator) processWithPlaceholders(tierTmpl *toolchainv1alpha1.TierTemplate) ([]runtimeclient.Object, error) {
+	tmpl := tierTmpl.Spec.Template.DeepCopy()
+	values := map[string]string{}
+	for _, param := range tmpl.Parameters {
+		if param.Required && param.Value == "" && param.Generate == "" {
+			values[param.Name] = fmt.Sprintf("${%s}", param.Name)
+		}
+	}
+	processor := commonTemplate.NewProcessor(t.scheme, commonTemplate.WithSeedKey(tierTmpl.Spec.TierName, tierTmpl.Spec.Type))
+	objs, err := processor.Process(tmpl, values)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to process the '%s' TierTemplate", tierTmpl.Name)
+	}
+	return objs, nil
+}
+
+// objectKey returns the key of the given object, in the `<apiVersion>/<kind>/<namespace>/<name>` form
+func objectKey(obj runtimeclient.Object) string {
+	apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	return fmt.Sprintf("%s/%s/%s/%s", apiVersion, kind, obj.GetNamespace(), obj.GetName())
+}
+
+// diffTemplates returns the changes between the given templates, indexed by type
+func diffTemplates(oldTemplates, newTemplates map[string]objectsByKey) []TemplateDiff {
+	var diffs []TemplateDiff
+	for _, tmplType := range sortedKeys(oldTemplates, newTemplates) {
+		oldObjs, inOld := oldTemplates[tmplType]
+		newObjs, inNew := newTemplates[tmplType]
+		tmplDiff := TemplateDiff{
+			Type:    tmplType,
+			Change:  changeType(inOld, inNew),
+			Objects: diffObjects(oldObjs, newObjs),
+		}
+		if len(tmplDiff.Objects) > 0 {
+			diffs = append(diffs, tmplDiff)
+		}
+	}
+	return diffs
+}
+
+// diffObjects returns the changes between the given objects, indexed by key
+func diffObjects(oldObjs, newObjs objectsByKey) []ObjectDiff {
+	var diffs []ObjectDiff
+	for _, key := range sortedKeys(oldObjs, newObjs) {
+		oldObj, inOld := oldObjs[key]
+		newObj, inNew := newObjs[key]
+		obj := newObj
+		if !inNew {
+			obj = oldObj
+		}
+		objDiff := ObjectDiff{
+			Kind:      obj.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Change:    changeType(inOld, inNew),
+		}
+		objDiff.APIVersion, _ = obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+		if inOld && inNew {
+			objDiff.Diff = diff(oldObj, newObj)
+			if objDiff.Diff == "" {
+				continue
+			}
+		}
+		diffs = append(diffs, objDiff)
+	}
+	return diffs
+}
+
+// diff returns the unified diff between the YAML content of the given objects, or an empty string if they are equal
+func diff(oldObj, newObj runtimeclient.Object) string {
+	oldContent, newContent := content(oldObj), content(newObj)
+	if oldContent == newContent {
+		return ""
+	}
+	d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldContent),
+		B:        difflib.SplitLines(newContent),
+		FromFile: "old",
+		ToFile:   "new",
+		Context:  3,
+	})
+	if err != nil {
+		// should not happen, since the diff is written in memory
+		return err.Error()
+	}
+	return d
+}
+
+// content returns the content of the given object as YAML (with sorted keys), for comparison purpose
+func content(obj runtimeclient.Object) string {
+	c, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		// should not happen with the objects of the templates, which are Unstructured
+		return err.Error()
+	}
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+func changeType(inOld, inNew bool) ChangeType {
+	switch {
+	case !inOld:
+		return Added
+	case !inNew:
+		return Removed
+	default:
+		return Modified
+	}
+}
+
+// sortedKeys returns the sorted union of the keys of the given maps
+func sortedKeys[V any](m1, m2 map[string]V) []string {
+	keys := make([]string, 0, len(m1)+len(m2))
+	for k := range m1 {
+		keys = append(keys, k)
+	}
+	for k := range m2 {
+		if _, found := m1[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PrintDiff writes the given changes in a human-readable form
+func PrintDiff(out io.Writer, diffs []TierDiff) error {
+	for _, tierDiff := range diffs {
+		if _, err := fmt.Fprintf(out, "tier '%s' (%s)\n", tierDiff.Name, tierDiff.Change); err != nil {
+			return err
+		}
+		for _, tmplDiff := range tierDiff.Templates {
+			if _, err := fmt.Fprintf(out, "  template '%s' (%s)\n", tmplDiff.Type, tmplDiff.Change); err != nil {
+				return err
+			}
+			for _, objDiff := range tmplDiff.Objects {
+				name := objDiff.Name
+				if objDiff.Namespace != "" {
+					name = objDiff.Namespace + "/" + objDiff.Name
+				}
+				if _, err := fmt.Fprintf(out, "    %s '%s' (%s)\n", objDiff.Kind, name, objDiff.Change); err != nil {
+					return err
+				}
+				if objDiff.Diff != "" {
+					if _, err := fmt.Fprint(out, objDiff.Diff); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package nstemplatetiers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	s := addToScheme(t)
+
+	t.Run("no change", func(t *testing.T) {
+		// when
+		diffs, err := DiffTiers(s, test.HostOperatorNs, getTestMetadata(), getTestTemplates(t), getTestMetadata(), getTestTemplates(t))
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("no change with new revisions", func(t *testing.T) {
+		// given
+		newMetadata := getTestMetadata()
+		for k := range newMetadata {
+			newMetadata[k] = "fedcba9"
+		}
+
+		// when
+		diffs, err := DiffTiers(s, test.HostOperatorNs, getTestMetadata(), getTestTemplates(t), newMetadata, getTestTemplates(t))
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		// given
+		newFiles := getTestTemplates(t)
+		newMetadata := getTestMetadata()
+		// modify the dev namespace and add a ConfigMap
+		newFiles["base/ns_dev.yaml"] = []byte(`apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: base-dev
+objects:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    annotations:
+      openshift.io/description: ${SPACE_NAME}-dev
+      openshift.io/display-name: ${SPACE_NAME}-dev
+      openshift.io/requester: ${SPACE_NAME}
+    labels:
+      toolchain.dev.openshift.com/provider: codeready-toolchain
+      name: ${SPACE_NAME}-dev
+      tier: base
+    name: ${SPACE_NAME}-dev
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config
+    namespace: ${SPACE_NAME}-dev
+parameters:
+- name: SPACE_NAME
+  required: true`)
+		newMetadata["base/ns_dev"] = "fedcba9"
+		// remove the stage namespace
+		delete(newFiles, "nocluster/ns_stage.yaml")
+		delete(newMetadata, "nocluster/ns_stage")
+		// add a new tier
+		newFiles["newtier/based_on_tier.yaml"] = []byte("from: nocluster")
+		newMetadata["newtier/based_on_tier"] = "fedcba9"
+
+		// when
+		diffs, err := DiffTiers(s, test.HostOperatorNs, getTestMetadata(), getTestTemplates(t), newMetadata, newFiles)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, diffs, 4)
+
+		t.Run("modified tiers", func(t *testing.T) {
+			for i, tier := range []string{"advanced", "base"} { // advanced is based on base
+				assert.Equal(t, tier, diffs[i].Name)
+				assert.Equal(t, Modified, diffs[i].Change)
+				require.Len(t, diffs[i].Templates, 1)
+				dev := diffs[i].Templates[0]
+				assert.Equal(t, "dev", dev.Type)
+				assert.Equal(t, Modified, dev.Change)
+				require.Len(t, dev.Objects, 2)
+				assert.Equal(t, ObjectDiff{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+					Namespace:  "${SPACE_NAME}-dev",
+					Name:       "config",
+					Change:     Added,
+				}, dev.Objects[0])
+				assert.Equal(t, "v1", dev.Objects[1].APIVersion)
+				assert.Equal(t, "Namespace", dev.Objects[1].Kind)
+				assert.Equal(t, "${SPACE_NAME}-dev", dev.Objects[1].Name)
+				assert.Equal(t, Modified, dev.Objects[1].Change)
+				assert.Contains(t, dev.Objects[1].Diff, "--- old\n+++ new\n")
+				assert.Contains(t, dev.Objects[1].Diff, "\n+    tier: base\n")
+			}
+		})
+
+		t.Run("added tier", func(t *testing.T) {
+			newtier := diffs[2]
+			assert.Equal(t, "newtier", newtier.Name)
+			assert.Equal(t, Added, newtier.Change)
+			require.Len(t, newtier.Templates, 2)
+			assert.Equal(t, "admin", newtier.Templates[0].Type)
+			assert.Equal(t, Added, newtier.Templates[0].Change)
+			assert.Equal(t, "dev", newtier.Templates[1].Type)
+			assert.Equal(t, Added, newtier.Templates[1].Change)
+			require.Len(t, newtier.Templates[1].Objects, 1)
+			assert.Equal(t, Added, newtier.Templates[1].Objects[0].Change)
+			assert.Empty(t, newtier.Templates[1].Objects[0].Diff)
+		})
+
+		t.Run("removed template", func(t *testing.T) {
+			nocluster := diffs[3]
+			assert.Equal(t, "nocluster", nocluster.Name)
+			assert.Equal(t, Modified, nocluster.Change)
+			require.Len(t, nocluster.Templates, 1)
+			assert.Equal(t, "stage", nocluster.Templates[0].Type)
+			assert.Equal(t, Removed, nocluster.Templates[0].Change)
+			require.Len(t, nocluster.Templates[0].Objects, 1)
+			assert.Equal(t, ObjectDiff{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+				Name:       "${SPACE_NAME}-stage",
+				Change:     Removed,
+			}, nocluster.Templates[0].Objects[0])
+		})
+
+		t.Run("print", func(t *testing.T) {
+			// given
+			out := &bytes.Buffer{}
+
+			// when
+			err := PrintDiff(out, diffs)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(out.String(), `tier 'advanced' (modified)
+  template 'dev' (modified)
+    ConfigMap '${SPACE_NAME}-dev/config' (added)
+    Namespace '${SPACE_NAME}-dev' (modified)
+`), out.String())
+			assert.True(t, strings.HasSuffix(out.String(), `tier 'nocluster' (modified)
+  template 'stage' (removed)
+    Namespace '${SPACE_NAME}-stage' (removed)
+`), out.String())
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("invalid old revision", func(t *testing.T) {
+			// given
+			oldFiles := getTestTemplates(t)
+			oldFiles["base/ns_dev.yaml"] = []byte("invalid")
+
+			// when
+			_, err := DiffTiers(s, test.HostOperatorNs, getTestMetadata(), oldFiles, getTestMetadata(), getTestTemplates(t))
+
+			// then
+			require.ErrorContains(t, err, "unable to init NSTemplateTier generator for the old revision")
+		})
+
+		t.Run("invalid new revision", func(t *testing.T) {
+			// given
+			newFiles := getTestTemplates(t)
+			newFiles["base/ns_dev.yaml"] = []byte("invalid")
+
+			// when
+			_, err := DiffTiers(s, test.HostOperatorNs, getTestMetadata(), getTestTemplates(t), getTestMetadata(), newFiles)
+
+			// then
+			require.ErrorContains(t, err, "unable to init NSTemplateTier generator for the new revision")
+		})
+	})
+}
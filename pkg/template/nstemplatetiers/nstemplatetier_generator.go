@@ -60,6 +60,11 @@ func GenerateTiers(s *runtime.Scheme, ensureObject EnsureObject, namespace strin
 		return errors.Wrap(err, "unable to init NSTemplateTier generator")
 	}
 
+	// verify that the TierTemplate resources can be stored
+	if err := generator.verifyTierTemplateSizes(); err != nil {
+		return errors.Wrap(err, "invalid TierTemplates")
+	}
+
 	// create the TierTemplate resources
 	err = generator.createTierTemplates()
 	if err != nil {
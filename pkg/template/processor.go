@@ -2,7 +2,9 @@ package template
 
 import (
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"strings"
 	"time"
 
 	templatev1 "github.com/openshift/api/template/v1"
@@ -15,18 +17,53 @@ import (
 
 // Processor the tool that will process and apply a template with variables
 type Processor struct {
-	scheme *runtime.Scheme
+	scheme       *runtime.Scheme
+	transformers []TransformerFunc
+	validator    Validator
+	seedKey      string
+}
+
+// ProcessorOption an option to configure the Processor
+type ProcessorOption func(*Processor)
+
+// WithTransformers configures the Processor to apply the given transformers (in order) on all the objects it returns.
+// The transformers are applied after the filters, and before the validation.
+func WithTransformers(transformers ...TransformerFunc) ProcessorOption {
+	return func(p *Processor) {
+		p.transformers = append(p.transformers, transformers...)
+	}
+}
+
+// WithValidator configures the Processor to validate all the objects it returns with the given validator.
+// The Process func then fails with an error listing all the invalid objects.
+func WithValidator(validator Validator) ProcessorOption {
+	return func(p *Processor) {
+		p.validator = validator
+	}
+}
+
+// WithSeedKey configures the Processor to seed the generator of the parameter values from the given keys
+// (eg: the space name and the template name) instead of the current time, so that the generated values
+// are the same every time the template is processed with the same keys.
+func WithSeedKey(keys ...string) ProcessorOption {
+	return func(p *Processor) {
+		p.seedKey = strings.Join(keys, "/")
+	}
 }
 
 // NewProcessor returns a new Processor
-func NewProcessor(scheme *runtime.Scheme) Processor {
-	return Processor{
+func NewProcessor(scheme *runtime.Scheme, opts ...ProcessorOption) Processor {
+	p := Processor{
 		scheme: scheme,
 	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return p
 }
 
 // Process processes the template (ie, replaces the variables with their actual values) and optionally filters the result
-// to return a subset of the template objects
+// to return a subset of the template objects, which are then transformed and validated if the Processor was configured to
 func (p Processor) Process(tmpl *templatev1.Template, values map[string]string, filters ...FilterFunc) ([]runtimeclient.Object, error) {
 	// inject variables in the twmplate
 	for param, val := range values {
@@ -39,7 +76,7 @@ func (p Processor) Process(tmpl *templatev1.Template, values map[string]string,
 
 	// convert the template into a set of objects
 	tmplProcessor := templateprocessing.NewProcessor(map[string]generator.Generator{
-		"expression": generator.NewExpressionValueGenerator(rand.New(rand.NewSource(time.Now().UnixNano()))), //nolint:gosec
+		"expression": generator.NewExpressionValueGenerator(rand.New(rand.NewSource(p.seed()))), //nolint:gosec
 	})
 	if err := tmplProcessor.Process(tmpl); len(err) > 0 {
 		return nil, errors.Wrap(err.ToAggregate(), "unable to process template")
@@ -57,5 +94,25 @@ func (p Processor) Process(tmpl *templatev1.Template, values map[string]string,
 		}
 		objects[i] = clientObj
 	}
+	if err := Transform(objects, p.transformers...); err != nil {
+		return nil, errors.Wrap(err, "failed to transform template objects")
+	}
+	if p.validator != nil {
+		if err := ValidateObjects(p.validator, objects); err != nil {
+			return nil, errors.Wrap(err, "invalid template objects")
+		}
+	}
 	return objects, nil
 }
+
+// seed returns the seed of the generator of the parameter values: derived from the seed key if one was configured,
+// from the current time otherwise
+func (p Processor) seed() int64 {
+	if p.seedKey == "" {
+		return time.Now().UnixNano()
+	}
+	h := fnv.New64a()
+	// Ignore the error, as this implementation cannot return one
+	_, _ = h.Write([]byte(p.seedKey))
+	return int64(h.Sum64()) //nolint:gosec
+}
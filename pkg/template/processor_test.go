@@ -218,6 +218,69 @@ func TestProcess(t *testing.T) {
 	})
 }
 
+func TestProcessWithSeedKey(t *testing.T) {
+
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": "john",
+	}
+	process := func(t *testing.T, p template.Processor) string {
+		tmpl, err := DecodeTemplate(decoder,
+			CreateTemplate(WithObjects(Namespace), WithParams(UsernameParam, generatedCommitParam)))
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		require.Len(t, objs, 1)
+		return objs[0].GetLabels()["version"]
+	}
+
+	t.Run("same value with same seed key", func(t *testing.T) {
+		// when
+		first := process(t, template.NewProcessor(s, template.WithSeedKey("john", "base-dev")))
+		second := process(t, template.NewProcessor(s, template.WithSeedKey("john", "base-dev")))
+
+		// then
+		assert.Regexp(t, "^[a-f0-9]{12}$", first)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("different values with different seed keys", func(t *testing.T) {
+		// when
+		first := process(t, template.NewProcessor(s, template.WithSeedKey("john", "base-dev")))
+		second := process(t, template.NewProcessor(s, template.WithSeedKey("john", "base-stage")))
+
+		// then
+		assert.Regexp(t, "^[a-f0-9]{12}$", first)
+		assert.Regexp(t, "^[a-f0-9]{12}$", second)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("provided value takes precedence over generated value", func(t *testing.T) {
+		// given
+		values := map[string]string{
+			"USERNAME": "john",
+			"COMMIT":   "123abc",
+		}
+		tmpl, err := DecodeTemplate(decoder,
+			CreateTemplate(WithObjects(Namespace), WithParams(UsernameParam, generatedCommitParam)))
+		require.NoError(t, err)
+
+		// when
+		objs, err := template.NewProcessor(s, template.WithSeedKey("john", "base-dev")).Process(tmpl, values)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 1)
+		assert.Equal(t, "123abc", objs[0].GetLabels()["version"])
+	})
+}
+
+const generatedCommitParam TemplateParam = `
+- name: COMMIT
+  generate: expression
+  from: "[a-f0-9]{12}"`
+
 func addToScheme(t *testing.T) *runtime.Scheme {
 	s := scheme.Scheme
 	err := authv1.Install(s)
@@ -0,0 +1,146 @@
+package template
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// TransformerFunc a function to mutate an object returned by the Processor
+type TransformerFunc func(obj runtimeclient.Object) error
+
+// Transform applies all the given transformers (in order) on each of the given objects
+func Transform(objs []runtimeclient.Object, transformers ...TransformerFunc) error {
+	for _, obj := range objs {
+		for _, transform := range transformers {
+			if err := transform(obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddLabels returns a func which adds the given labels to the objects, overriding the existing labels with the same keys
+func AddLabels(labels map[string]string) TransformerFunc {
+	return func(obj runtimeclient.Object) error {
+		obj.SetLabels(merge(obj.GetLabels(), labels))
+		return nil
+	}
+}
+
+// AddAnnotations returns a func which adds the given annotations to the objects, overriding the existing annotations with the same keys
+func AddAnnotations(annotations map[string]string) TransformerFunc {
+	return func(obj runtimeclient.Object) error {
+		obj.SetAnnotations(merge(obj.GetAnnotations(), annotations))
+		return nil
+	}
+}
+
+func merge(existing, added map[string]string) map[string]string {
+	if existing == nil {
+		existing = make(map[string]string, len(added))
+	}
+	for k, v := range added {
+		existing[k] = v
+	}
+	return existing
+}
+
+// SetNamespace returns a func which sets the given namespace on all the namespaced objects. The scope of the objects
+// is determined by the given RESTMapper, and the cluster-scoped objects (Namespaces, ClusterRoles, etc.) are left untouched.
+func SetNamespace(mapper meta.RESTMapper, namespace string) TransformerFunc {
+	return func(obj runtimeclient.Object) error {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return errors.Wrapf(err, "unable to determine the scope of the '%s' object", gvk)
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return nil
+		}
+		obj.SetNamespace(namespace)
+		return nil
+	}
+}
+
+// SetControllerReference returns a func which sets the given owner as the controller of the objects
+func SetControllerReference(owner runtimeclient.Object, scheme *runtime.Scheme) TransformerFunc {
+	return func(obj runtimeclient.Object) error {
+		return controllerutil.SetControllerReference(owner, obj, scheme)
+	}
+}
+
+// ReplaceImages returns a func which replaces the images of the containers (including the init and ephemeral containers)
+// of all the objects containing a pod spec (Pods, Deployments, Jobs, CronJobs, etc.).
+// The keys of the mapping are either complete image references (eg: `quay.io/org/app:v1`), in which case the whole
+// reference is replaced, or image names without tag nor digest (eg: `quay.io/org/app`), in which case only the name is
+// replaced and the tag or digest is retained.
+func ReplaceImages(mapping map[string]string) TransformerFunc {
+	return func(obj runtimeclient.Object) error {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			replaceImages(u.Object, mapping)
+			return nil
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		replaceImages(content, mapping)
+		return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+	}
+}
+
+var containerFields = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// replaceImages walks through the given content and replaces the images of all the containers it finds
+func replaceImages(content map[string]interface{}, mapping map[string]string) {
+	for k, v := range content {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			replaceImages(v, mapping)
+		case []interface{}:
+			isContainers := slices.Contains(containerFields, k)
+			for _, item := range v {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if image, ok := m["image"].(string); ok && isContainers {
+					m["image"] = replaceImage(image, mapping)
+					continue
+				}
+				replaceImages(m, mapping)
+			}
+		}
+	}
+}
+
+func replaceImage(image string, mapping map[string]string) string {
+	if replacement, found := mapping[image]; found {
+		return replacement
+	}
+	name, suffix := splitImage(image)
+	if replacement, found := mapping[name]; found {
+		return replacement + suffix
+	}
+	return image
+}
+
+// splitImage splits the given image reference into its name and its tag or digest suffix (including the `:` or `@` separator)
+func splitImage(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i:]
+	}
+	// a `:` after the last `/` is the tag separator (the other ones are port separators)
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i:]
+	}
+	return image, ""
+}
@@ -0,0 +1,259 @@
+package template_test
+
+import (
+	"fmt"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/template"
+	. "github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestTransformers(t *testing.T) {
+
+	s := addToScheme(t)
+
+	t.Run("add labels", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","labels":{"foo":"bar","type":"dev"}}}`)
+		noLabels := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`)
+
+		// when
+		err := template.Transform([]runtimeclient.Object{obj, noLabels}, template.AddLabels(map[string]string{"type": "stage", "tier": "base"}))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"foo": "bar", "type": "stage", "tier": "base"}, obj.GetLabels())
+		assert.Equal(t, map[string]string{"type": "stage", "tier": "base"}, noLabels.GetLabels())
+	})
+
+	t.Run("add annotations", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","annotations":{"foo":"bar"}}}`)
+
+		// when
+		err := template.Transform([]runtimeclient.Object{obj}, template.AddAnnotations(map[string]string{"owner": "john"}))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"foo": "bar", "owner": "john"}, obj.GetAnnotations())
+	})
+
+	t.Run("set namespace", func(t *testing.T) {
+		// given
+		ns := newUnstructured(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"john"}}`)
+		clusterRole := newUnstructured(t, `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"cr"}}`)
+		cm := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"other"}}`)
+		// a namespaced kind named `Namespace` in another group
+		otherNs := newUnstructured(t, `{"apiVersion":"example.com/v1","kind":"Namespace","metadata":{"name":"other"}}`)
+
+		// when
+		err := template.Transform([]runtimeclient.Object{ns, clusterRole, cm, otherNs}, template.SetNamespace(newRESTMapper(), "john"))
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, ns.GetNamespace())
+		assert.Empty(t, clusterRole.GetNamespace())
+		assert.Equal(t, "john", cm.GetNamespace())
+		assert.Equal(t, "john", otherNs.GetNamespace())
+	})
+
+	t.Run("set namespace fails for an unknown kind", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"unknown"}}`)
+
+		// when
+		err := template.Transform([]runtimeclient.Object{obj}, template.SetNamespace(newRESTMapper(), "john"))
+
+		// then
+		require.ErrorContains(t, err, "unable to determine the scope of the 'example.com/v1, Kind=Unknown' object")
+		assert.Empty(t, obj.GetNamespace())
+	})
+
+	t.Run("set controller reference", func(t *testing.T) {
+		// given
+		owner := &toolchainv1alpha1.NSTemplateSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "john",
+				Namespace: HostOperatorNs,
+				UID:       "123",
+			},
+		}
+		cm := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"toolchain-host-operator"}}`)
+
+		// when
+		err := template.Transform([]runtimeclient.Object{cm}, template.SetControllerReference(owner, s))
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, cm.GetOwnerReferences(), 1)
+		assert.Equal(t, "NSTemplateSet", cm.GetOwnerReferences()[0].Kind)
+		assert.Equal(t, "john", cm.GetOwnerReferences()[0].Name)
+		assert.True(t, *cm.GetOwnerReferences()[0].Controller)
+	})
+
+	t.Run("set controller reference fails", func(t *testing.T) {
+		// given
+		owner := &toolchainv1alpha1.NSTemplateSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "john",
+				Namespace: HostOperatorNs,
+			},
+		}
+		cm := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"john"}}`)
+
+		// when
+		err := template.Transform([]runtimeclient.Object{cm}, template.SetControllerReference(owner, s))
+
+		// then
+		require.Error(t, err) // cross-namespace owner references are not allowed
+	})
+
+	t.Run("replace images", func(t *testing.T) {
+		// given
+		mapping := map[string]string{
+			"quay.io/org/app:v1":  "mirror.io/org/app:v2",
+			"quay.io/org/sidecar": "mirror.io/org/sidecar",
+			"localhost:5000/init": "mirror.io/org/init",
+		}
+
+		t.Run("unstructured objects", func(t *testing.T) {
+			// given
+			pod := newUnstructured(t, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod"},"spec":{
+				"initContainers":[{"name":"init","image":"localhost:5000/init:latest"}],
+				"containers":[{"name":"app","image":"quay.io/org/app:v1"},{"name":"sidecar","image":"quay.io/org/sidecar@sha256:abc"},{"name":"other","image":"quay.io/org/other:v1"}]}}`)
+			cronjob := newUnstructured(t, `{"apiVersion":"batch/v1","kind":"CronJob","metadata":{"name":"cronjob"},"spec":{
+				"jobTemplate":{"spec":{"template":{"spec":{"containers":[{"name":"sidecar","image":"quay.io/org/sidecar"}]}}}}}}`)
+			cm := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"data":{"image":"quay.io/org/app:v1"}}`)
+
+			// when
+			err := template.Transform([]runtimeclient.Object{pod, cronjob, cm}, template.ReplaceImages(mapping))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, []string{"mirror.io/org/init:latest"}, images(t, pod, "spec", "initContainers"))
+			assert.Equal(t, []string{"mirror.io/org/app:v2", "mirror.io/org/sidecar@sha256:abc", "quay.io/org/other:v1"}, images(t, pod, "spec", "containers"))
+			assert.Equal(t, []string{"mirror.io/org/sidecar"}, images(t, cronjob, "spec", "jobTemplate", "spec", "template", "spec", "containers"))
+			assert.Equal(t, map[string]interface{}{"image": "quay.io/org/app:v1"}, cm.Object["data"])
+		})
+
+		t.Run("typed objects", func(t *testing.T) {
+			// given
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "deployment",
+				},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "app",
+									Image: "quay.io/org/app:v1",
+								},
+							},
+						},
+					},
+				},
+			}
+
+			// when
+			err := template.Transform([]runtimeclient.Object{deployment}, template.ReplaceImages(mapping))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "mirror.io/org/app:v2", deployment.Spec.Template.Spec.Containers[0].Image)
+		})
+	})
+
+	t.Run("transformation fails", func(t *testing.T) {
+		// given
+		cm := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`)
+		failing := func(runtimeclient.Object) error {
+			return fmt.Errorf("mock error")
+		}
+
+		// when
+		err := template.Transform([]runtimeclient.Object{cm}, failing, template.AddLabels(map[string]string{"foo": "bar"}))
+
+		// then
+		require.EqualError(t, err, "mock error")
+		assert.Empty(t, cm.GetLabels())
+	})
+}
+
+func TestProcessWithTransformers(t *testing.T) {
+	// given
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME":  "john",
+		"NAMESPACE": "john-dev",
+	}
+	tmpl, err := DecodeTemplate(decoder,
+		CreateTemplate(WithObjects(Namespace, ServiceAccount, ConfigMap), WithParams(UsernameParam, CommitParam, NamespaceParam, ServSelectorParam)))
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(s, template.WithTransformers(template.SetNamespace(newRESTMapper(), "john-stage")),
+			template.WithTransformers(template.AddLabels(map[string]string{"tier": "base"})))
+
+		// when
+		objs, err := p.Process(tmpl.DeepCopy(), values, template.RetainAllButNamespaces)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 2)
+		for _, obj := range objs {
+			assert.Equal(t, "john-stage", obj.GetNamespace())
+			assert.Equal(t, "base", obj.GetLabels()["tier"])
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(s, template.WithTransformers(func(runtimeclient.Object) error {
+			return fmt.Errorf("mock error")
+		}))
+
+		// when
+		objs, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.EqualError(t, err, "failed to transform template objects: mock error")
+		assert.Nil(t, objs)
+	})
+}
+
+func newRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Namespace"}, meta.RESTScopeNamespace)
+	return mapper
+}
+
+func images(t *testing.T, obj *unstructured.Unstructured, fields ...string) []string {
+	containers, found, err := unstructured.NestedSlice(obj.Object, fields...)
+	require.NoError(t, err)
+	require.True(t, found)
+	var result []string
+	for _, c := range containers {
+		result = append(result, c.(map[string]interface{})["image"].(string))
+	}
+	return result
+}
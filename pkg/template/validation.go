@@ -0,0 +1,352 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/openapi"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	schemaRefPrefix           = "#/components/schemas/"
+	gvkExtension              = "x-kubernetes-group-version-kind"
+	preserveUnknownExtension  = "x-kubernetes-preserve-unknown-fields"
+	intOrStringExtension      = "x-kubernetes-int-or-string"
+	embeddedResourceExtension = "x-kubernetes-embedded-resource"
+)
+
+// Validator validates the objects produced by the Processor
+type Validator interface {
+	// Validate returns the list of errors found in the given object, or an empty list if the object is valid
+	Validate(obj runtimeclient.Object) field.ErrorList
+}
+
+// SchemaValidator a Validator which checks the objects against the OpenAPI v3 schemas served by an API server
+// (or bundled in files, see NewOpenAPIFileClient). It reports unknown fields and values whose type does not match
+// the schema.
+type SchemaValidator struct {
+	client openapi.Client
+	mu     sync.Mutex
+	paths  map[string]openapi.GroupVersion
+	specs  map[string]*spec3.OpenAPI
+}
+
+var _ Validator = &SchemaValidator{}
+
+// NewSchemaValidator returns a new SchemaValidator which retrieves the schemas from the given client.
+// The client can be obtained with `discoveryClient.OpenAPIV3()` to use the schemas of a live cluster,
+// or with NewOpenAPIFileClient to use schemas bundled in files.
+func NewSchemaValidator(client openapi.Client) *SchemaValidator {
+	return &SchemaValidator{
+		client: client,
+		specs:  map[string]*spec3.OpenAPI{},
+	}
+}
+
+// Validate validates the given object against the schema of its GroupVersionKind
+func (v *SchemaValidator) Validate(obj runtimeclient.Object) field.ErrorList {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return field.ErrorList{field.InternalError(nil, err)}
+	}
+	doc, err := v.specFor(gvk.GroupVersion())
+	if err != nil {
+		return field.ErrorList{field.InternalError(nil, err)}
+	}
+	s := findSchema(doc, gvk)
+	if s == nil {
+		return field.ErrorList{field.NotFound(field.NewPath("kind"), gvk.String())}
+	}
+	return validateValue(doc, nil, content, s)
+}
+
+// specFor returns the (cached) OpenAPI document for the given GroupVersion
+func (v *SchemaValidator) specFor(gv schema.GroupVersion) (*spec3.OpenAPI, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	path := openAPIPath(gv)
+	if doc, ok := v.specs[path]; ok {
+		return doc, nil
+	}
+	if v.paths == nil {
+		paths, err := v.client.Paths()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list the OpenAPI v3 paths")
+		}
+		v.paths = paths
+	}
+	groupVersion, ok := v.paths[path]
+	if !ok {
+		return nil, fmt.Errorf("no OpenAPI v3 schema found for '%s'", gv.String())
+	}
+	raw, err := groupVersion.Schema(runtime.ContentTypeJSON)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to retrieve the OpenAPI v3 schema for '%s'", gv.String())
+	}
+	doc := &spec3.OpenAPI{}
+	if err := json.Unmarshal(raw, doc); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode the OpenAPI v3 schema for '%s'", gv.String())
+	}
+	v.specs[path] = doc
+	return doc, nil
+}
+
+// openAPIPath returns the path of the OpenAPI v3 document for the given GroupVersion, eg: `api/v1` or `apis/apps/v1`
+func openAPIPath(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return "api/" + gv.Version
+	}
+	return "apis/" + gv.Group + "/" + gv.Version
+}
+
+// findSchema looks-up the schema declaring the given GroupVersionKind in the given document
+func findSchema(doc *spec3.OpenAPI, gvk schema.GroupVersionKind) *spec.Schema {
+	if doc.Components == nil {
+		return nil
+	}
+	for _, s := range doc.Components.Schemas {
+		gvks, ok := s.Extensions[gvkExtension].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, e := range gvks {
+			m, ok := e.(map[string]interface{})
+			if ok && m["group"] == gvk.Group && m["version"] == gvk.Version && m["kind"] == gvk.Kind {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// resolve follows the `$ref` of the given schema (if any)
+func resolve(doc *spec3.OpenAPI, s *spec.Schema) (*spec.Schema, error) {
+	for s.Ref.String() != "" {
+		ref := s.Ref.String()
+		if !strings.HasPrefix(ref, schemaRefPrefix) || doc.Components == nil {
+			return nil, fmt.Errorf("unsupported schema reference '%s'", ref)
+		}
+		resolved, ok := doc.Components.Schemas[strings.TrimPrefix(ref, schemaRefPrefix)]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema reference '%s'", ref)
+		}
+		s = resolved
+	}
+	return s, nil
+}
+
+// validateValue validates the given value against the given schema
+func validateValue(doc *spec3.OpenAPI, path *field.Path, value interface{}, s *spec.Schema) field.ErrorList {
+	s, err := resolve(doc, s)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	if value == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	for i := range s.AllOf {
+		errs = append(errs, validateValue(doc, path, value, &s.AllOf[i])...)
+	}
+	if len(s.OneOf) > 0 {
+		errs = append(errs, validateAlternatives(doc, path, value, s.OneOf)...)
+	}
+	if len(s.AnyOf) > 0 {
+		errs = append(errs, validateAlternatives(doc, path, value, s.AnyOf)...)
+	}
+	if s.Extensions[intOrStringExtension] == true {
+		switch value.(type) {
+		case string, int64, float64:
+			return errs
+		default:
+			return append(errs, field.TypeInvalid(path, value, "must be an integer or a string"))
+		}
+	}
+	if len(s.Type) == 0 {
+		if len(s.Properties) > 0 {
+			return append(errs, validateObject(doc, path, value, s)...)
+		}
+		return errs
+	}
+	switch s.Type[0] {
+	case "object":
+		errs = append(errs, validateObject(doc, path, value, s)...)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(errs, field.TypeInvalid(path, value, "must be an array"))
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			for i, item := range items {
+				errs = append(errs, validateValue(doc, path.Index(i), item, s.Items.Schema)...)
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(errs, field.TypeInvalid(path, value, "must be a string"))
+		}
+	case "integer":
+		switch v := value.(type) {
+		case int64:
+		case float64:
+			if v != float64(int64(v)) {
+				errs = append(errs, field.TypeInvalid(path, value, "must be an integer"))
+			}
+		default:
+			errs = append(errs, field.TypeInvalid(path, value, "must be an integer"))
+		}
+	case "number":
+		switch value.(type) {
+		case int64, float64:
+		default:
+			errs = append(errs, field.TypeInvalid(path, value, "must be a number"))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, field.TypeInvalid(path, value, "must be a boolean"))
+		}
+	}
+	return errs
+}
+
+// validateAlternatives validates the given value against the `oneOf`/`anyOf` schemas: the value is valid if at least
+// one of the schemas accepts it
+func validateAlternatives(doc *spec3.OpenAPI, path *field.Path, value interface{}, alternatives []spec.Schema) field.ErrorList {
+	var firstErrs field.ErrorList
+	for i := range alternatives {
+		errs := validateValue(doc, path, value, &alternatives[i])
+		if len(errs) == 0 {
+			return nil
+		}
+		if firstErrs == nil {
+			firstErrs = errs
+		}
+	}
+	return firstErrs
+}
+
+// validateObject validates the fields of the given value against the properties of the given schema
+func validateObject(doc *spec3.OpenAPI, path *field.Path, value interface{}, s *spec.Schema) field.ErrorList {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return field.ErrorList{field.TypeInvalid(path, value, "must be an object")}
+	}
+	preserveUnknownFields := s.Extensions[preserveUnknownExtension] == true || s.Extensions[embeddedResourceExtension] == true
+	// a schema without any properties is free-form, eg, RawExtension or FieldsV1
+	if len(s.Properties) == 0 && s.AdditionalProperties == nil {
+		return nil
+	}
+	// iterate over the sorted keys so that the errors are reported in a predictable order
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs field.ErrorList
+	for _, k := range keys {
+		if p, found := s.Properties[k]; found {
+			errs = append(errs, validateValue(doc, path.Child(k), obj[k], &p)...)
+			continue
+		}
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Allows {
+			if s.AdditionalProperties.Schema != nil {
+				errs = append(errs, validateValue(doc, path.Key(k), obj[k], s.AdditionalProperties.Schema)...)
+			}
+			continue
+		}
+		if !preserveUnknownFields {
+			errs = append(errs, field.Forbidden(path.Child(k), "unknown field"))
+		}
+	}
+	return errs
+}
+
+// ValidateObjects validates all the given objects with the given validator and returns an aggregate
+// error with all the invalid objects and their errors, or nil if all objects are valid
+func ValidateObjects(validator Validator, objs []runtimeclient.Object) error {
+	var errs []error
+	for _, obj := range objs {
+		if objErrs := validator.Validate(obj); len(objErrs) > 0 {
+			errs = append(errs, &InvalidObjectError{
+				GVK:       obj.GetObjectKind().GroupVersionKind(),
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Errors:    objErrs,
+			})
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// InvalidObjectError the error reported for an object which did not pass the validation
+type InvalidObjectError struct {
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+	Errors    field.ErrorList
+}
+
+func (e *InvalidObjectError) Error() string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+	return fmt.Sprintf("invalid %s '%s': %s", e.GVK.Kind, name, e.Errors.ToAggregate().Error())
+}
+
+// NewOpenAPIFileClient returns an openapi.Client which serves the OpenAPI v3 documents stored in the given filesystem,
+// eg. to validate the templates offline. The files must be at the root of the filesystem and named after their path
+// on the API server, with `/` replaced by `__` and the `_openapi.json` suffix, eg: `apis__apps__v1_openapi.json`.
+// Such files can be obtained with `kubectl get --raw /openapi/v3/apis/apps/v1 > apis__apps__v1_openapi.json`
+func NewOpenAPIFileClient(fsys fs.FS) openapi.Client {
+	return fileClient{fsys: fsys}
+}
+
+type fileClient struct {
+	fsys fs.FS
+}
+
+func (c fileClient) Paths() (map[string]openapi.GroupVersion, error) {
+	entries, err := fs.ReadDir(c.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]openapi.GroupVersion{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), "_openapi.json") {
+			continue
+		}
+		path := strings.ReplaceAll(strings.TrimSuffix(e.Name(), "_openapi.json"), "__", "/")
+		paths[path] = fileGroupVersion{fsys: c.fsys, filename: e.Name()}
+	}
+	return paths, nil
+}
+
+type fileGroupVersion struct {
+	fsys     fs.FS
+	filename string
+}
+
+func (g fileGroupVersion) Schema(contentType string) ([]byte, error) {
+	if contentType != runtime.ContentTypeJSON {
+		return nil, fmt.Errorf("unsupported content type '%s'", contentType)
+	}
+	return fs.ReadFile(g.fsys, g.filename)
+}
+
+func (g fileGroupVersion) ServerRelativeURL() string {
+	return g.filename
+}
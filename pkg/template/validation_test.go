@@ -0,0 +1,197 @@
+package template_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/template"
+	. "github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/openapi/openapitest"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSchemaValidator(t *testing.T) {
+
+	validator := template.NewSchemaValidator(openapitest.NewEmbeddedFileClient())
+
+	t.Run("valid objects", func(t *testing.T) {
+		for name, obj := range map[string]runtimeclient.Object{
+			"namespace":      newUnstructured(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"john","labels":{"foo":"bar"}}}`),
+			"configmap":      newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"john"},"data":{"foo":"bar"}}`),
+			"null value":     newUnstructured(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"john","creationTimestamp":null}}`),
+			"quantities":     newUnstructured(t, `{"apiVersion":"v1","kind":"ResourceQuota","metadata":{"name":"rq"},"spec":{"hard":{"cpu":"2","memory":1000}}}`),
+			"int-or-str":     newUnstructured(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"svc"},"spec":{"ports":[{"port":80,"targetPort":"http"},{"port":81,"targetPort":8081}]}}`),
+			"deployment":     newUnstructured(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"deploy"},"spec":{"replicas":1,"template":{"spec":{"containers":[{"name":"c","image":"busybox"}]}}}}`),
+			"raw extension":  newUnstructured(t, `{"apiVersion":"apps/v1","kind":"ControllerRevision","metadata":{"name":"rev"},"revision":1,"data":{"foo":"bar","nested":{"key":1}}}`),
+			"managed fields": newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","managedFields":[{"manager":"kubectl","operation":"Apply","fieldsType":"FieldsV1","fieldsV1":{"f:data":{"f:foo":{}}}}]}}`),
+		} {
+			t.Run(name, func(t *testing.T) {
+				// when
+				errs := validator.Validate(obj)
+
+				// then
+				assert.Empty(t, errs)
+			})
+		}
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namspace":"john"},"type":"Opaque","data":{"foo":"bar"}}`)
+
+		// when
+		errs := validator.Validate(obj)
+
+		// then
+		require.Len(t, errs, 2)
+		assert.Equal(t, field.ErrorTypeForbidden, errs[0].Type)
+		assert.Equal(t, "metadata.namspace", errs[0].Field)
+		assert.Equal(t, field.ErrorTypeForbidden, errs[1].Type)
+		assert.Equal(t, "type", errs[1].Field)
+	})
+
+	t.Run("invalid types", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"deploy","labels":{"foo":1}},"spec":{"replicas":"one","template":{"spec":{"containers":{"name":"c"}}}}}`)
+
+		// when
+		errs := validator.Validate(obj)
+
+		// then
+		require.Len(t, errs, 3)
+		assert.Equal(t, field.ErrorTypeTypeInvalid, errs[0].Type)
+		assert.Equal(t, "metadata.labels[foo]", errs[0].Field)
+		assert.Equal(t, field.ErrorTypeTypeInvalid, errs[1].Type)
+		assert.Equal(t, "spec.replicas", errs[1].Field)
+		assert.Equal(t, field.ErrorTypeTypeInvalid, errs[2].Type)
+		assert.Equal(t, "spec.template.spec.containers", errs[2].Field)
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"v1","kind":"Unknown","metadata":{"name":"unknown"}}`)
+
+		// when
+		errs := validator.Validate(obj)
+
+		// then
+		require.Len(t, errs, 1)
+		assert.Equal(t, field.ErrorTypeNotFound, errs[0].Type)
+	})
+
+	t.Run("unknown group version", func(t *testing.T) {
+		// given
+		obj := newUnstructured(t, `{"apiVersion":"unknown.io/v1","kind":"Unknown","metadata":{"name":"unknown"}}`)
+
+		// when
+		errs := validator.Validate(obj)
+
+		// then
+		require.Len(t, errs, 1)
+		assert.Equal(t, field.ErrorTypeInternal, errs[0].Type)
+		assert.Contains(t, errs[0].Error(), "no OpenAPI v3 schema found for 'unknown.io/v1'")
+	})
+}
+
+func TestOpenAPIFileClient(t *testing.T) {
+	// given
+	fsys := bundleSchemas(t, openapitest.NewEmbeddedFileClient())
+	fsys["README.md"] = &fstest.MapFile{Data: []byte("not a schema")}
+
+	// when
+	client := template.NewOpenAPIFileClient(fsys)
+
+	// then
+	paths, err := client.Paths()
+	require.NoError(t, err)
+	require.Contains(t, paths, "api/v1")
+	require.Contains(t, paths, "apis/apps/v1")
+	assert.Len(t, paths, len(fsys)-1)
+	_, err = paths["api/v1"].Schema("application/com.github.proto-openapi.spec.v3@v1.0+protobuf")
+	require.Error(t, err)
+	errs := template.NewSchemaValidator(client).Validate(newUnstructured(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"type":"Opaque"}`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "type", errs[0].Field)
+}
+
+func TestProcessWithValidator(t *testing.T) {
+	// given
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	p := template.NewProcessor(s, template.WithValidator(template.NewSchemaValidator(openapitest.NewEmbeddedFileClient())))
+	values := map[string]string{
+		"USERNAME":  "john",
+		"NAMESPACE": "john-dev",
+	}
+
+	t.Run("valid objects", func(t *testing.T) {
+		// given
+		tmpl, err := DecodeTemplate(decoder,
+			CreateTemplate(WithObjects(Namespace, ServiceAccount, Service), WithParams(UsernameParam, CommitParam, NamespaceParam, ServSelectorParam)))
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, values)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 3)
+	})
+
+	t.Run("invalid objects", func(t *testing.T) {
+		// given
+		tmpl, err := DecodeTemplate(decoder,
+			CreateTemplate(WithObjects(Namespace, ConfigMap), WithParams(UsernameParam, CommitParam, NamespaceParam, ServSelectorParam)))
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, values)
+
+		// then
+		require.EqualError(t, err, "invalid template objects: invalid ConfigMap 'john-dev/registration-service': type: Forbidden: unknown field")
+		assert.Nil(t, objs)
+	})
+
+	t.Run("filtered out objects are not validated", func(t *testing.T) {
+		// given
+		tmpl, err := DecodeTemplate(decoder,
+			CreateTemplate(WithObjects(Namespace, ConfigMap), WithParams(UsernameParam, CommitParam, NamespaceParam, ServSelectorParam)))
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, values, template.RetainNamespaces)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 1)
+	})
+}
+
+func newUnstructured(t *testing.T, content string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	err := obj.UnmarshalJSON([]byte(content))
+	require.NoError(t, err)
+	return obj
+}
+
+// bundleSchemas copies all the schemas served by the given client in an in-memory filesystem
+func bundleSchemas(t *testing.T, client openapi.Client) fstest.MapFS {
+	paths, err := client.Paths()
+	require.NoError(t, err)
+	fsys := fstest.MapFS{}
+	for path, gv := range paths {
+		data, err := gv.Schema("application/json")
+		require.NoError(t, err)
+		fsys[fmt.Sprintf("%s_openapi.json", strings.ReplaceAll(path, "/", "__"))] = &fstest.MapFile{Data: data}
+	}
+	return fsys
+}